@@ -2,23 +2,33 @@ package v1
 
 import (
 	"mime/multipart"
+	"net/http"
 
 	"github.com/labstack/echo"
 	"github.com/mises-id/sns/app/apis/rest"
+	"github.com/mises-id/sns/app/models"
 	svc "github.com/mises-id/sns/app/services/attachment"
 	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/storage"
 	"github.com/sirupsen/logrus"
 )
 
 type UploadParams struct {
 	FileType string `form:"file_type"`
+	PosterID uint64 `form:"poster_id"`
 }
 
 type AttachmentResp struct {
-	ID       uint64 `json:"id"`
-	Filename string `json:"filename"`
-	FileType string `json:"file_type"`
-	Url      string `json:"url"`
+	ID        uint64  `json:"id"`
+	Filename  string  `json:"filename"`
+	FileType  string  `json:"file_type"`
+	MimeType  string  `json:"mime_type"`
+	Size      int64   `json:"size"`
+	Width     int     `json:"width,omitempty"`
+	Height    int     `json:"height,omitempty"`
+	Duration  float64 `json:"duration,omitempty"`
+	Url       string  `json:"url"`
+	PosterUrl string  `json:"poster_url,omitempty"`
 }
 
 func Upload(c echo.Context) error {
@@ -35,16 +45,55 @@ func Upload(c echo.Context) error {
 		return err
 	}
 	defer src.Close()
-	attachment, err := svc.CreateAttachment(c.Request().Context(), params.FileType, file.Filename, src)
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	createParams := &svc.CreateAttachmentParams{
+		UID:      currentUID,
+		FileType: params.FileType,
+		Filename: file.Filename,
+		File:     src,
+		PosterID: params.PosterID,
+	}
+	if poster, err := c.FormFile("poster"); err == nil {
+		posterSrc, err := poster.Open()
+		if err != nil {
+			return err
+		}
+		defer posterSrc.Close()
+		createParams.PosterFilename, createParams.PosterFile = poster.Filename, posterSrc
+	}
+	attachment, err := svc.CreateAttachment(c.Request().Context(), createParams)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, buildAttachmentResp(attachment))
+}
+
+func buildAttachmentResp(attachment *models.Attachment) *AttachmentResp {
+	return &AttachmentResp{
+		ID:        attachment.ID,
+		Filename:  attachment.Filename,
+		FileType:  attachment.FileType.String(),
+		MimeType:  attachment.MimeType,
+		Size:      attachment.Size,
+		Width:     attachment.Width,
+		Height:    attachment.Height,
+		Duration:  attachment.Duration,
+		Url:       attachment.FileUrl(),
+		PosterUrl: attachment.PosterUrl(),
+	}
+}
+
+// ServeFile serves the files of the signed urls of the local file store
+func ServeFile(c echo.Context) error {
+	file, info, err := storage.OpenSignedFile(c.Request().Context(), c.Param("*"), c.QueryParam("expires"), c.QueryParam("signature"))
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessResp(c, &AttachmentResp{
-		ID:       attachment.ID,
-		Filename: attachment.Filename,
-		FileType: attachment.FileType.String(),
-		Url:      attachment.FileUrl(),
-	})
+	defer file.Close()
+	return c.Stream(http.StatusOK, info.ContentType, file)
 }
 
 func receiveUploadFile(c echo.Context) (*multipart.FileHeader, error) {
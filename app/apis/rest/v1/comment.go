@@ -4,7 +4,6 @@ import (
 	"github.com/labstack/echo"
 	"github.com/mises-id/sns/app/apis/rest"
 	"github.com/mises-id/sns/app/models"
-	"github.com/mises-id/sns/app/models/enum"
 	svc "github.com/mises-id/sns/app/services/status"
 	"github.com/mises-id/sns/lib/codes"
 	"github.com/mises-id/sns/lib/pagination"
@@ -13,6 +12,7 @@ import (
 
 type CreateCommentParams struct {
 	CommentableID primitive.ObjectID `json:"status_id"`
+	CommentID     primitive.ObjectID `json:"comment_id"`
 	Content       string             `json:"content"`
 }
 
@@ -21,6 +21,13 @@ type ListCommentParams struct {
 	CommentableID string `query:"status_id"`
 }
 
+type CommentResp struct {
+	*StatusResp
+	ReplyToUser  *UserResp      `json:"reply_to_user"`
+	RepliesCount uint64         `json:"replies_count"`
+	Replies      []*CommentResp `json:"replies,omitempty"`
+}
+
 func ListComment(c echo.Context) error {
 	params := &ListCommentParams{}
 	if err := c.Bind(params); err != nil {
@@ -34,16 +41,35 @@ func ListComment(c echo.Context) error {
 	if c.Get("CurrentUID") != nil {
 		currentUID = c.Get("CurrentUID").(uint64)
 	}
-	statuses, page, err := svc.ListStatus(c.Request().Context(), &svc.ListStatusParams{
-		PageQuickParams: &params.PageQuickParams,
-		ParentID:        statusID,
-		CurrentUID:      currentUID,
-		FromTypes:       []enum.FromType{enum.FromComment},
-	})
+	comments, page, err := svc.ListComment(c.Request().Context(), currentUID, statusID, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp, err := batchBuildCommentResp(comments)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
+func ListCommentReplies(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid comment id")
+	}
+	params := &pagination.PageQuickParams{}
+	if err = c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	replies, page, err := svc.ListCommentReplies(c.Request().Context(), currentUID, id, params)
 	if err != nil {
 		return err
 	}
-	resp, err := batchBuildStatusResp(statuses)
+	resp, err := batchBuildCommentResp(replies)
 	if err != nil {
 		return err
 	}
@@ -56,18 +82,72 @@ func CreateComment(c echo.Context) error {
 		return codes.ErrInvalidArgument.New("invalid comment params")
 	}
 	uid := c.Get("CurrentUser").(*models.User).UID
-	status, err := svc.CreateStatus(c.Request().Context(), uid, &svc.CreateStatusParams{
-		StatusType: enum.TextStatus.String(),
-		Content:    params.Content,
-		ParentID:   params.CommentableID,
-		FromType:   enum.FromComment,
+	comment, err := svc.CreateComment(c.Request().Context(), uid, &svc.CreateCommentParams{
+		StatusID:  params.CommentableID,
+		CommentID: params.CommentID,
+		Content:   params.Content,
 	})
 	if err != nil {
 		return err
 	}
-	resp, err := buildStatusResp(status)
+	resp, err := buildCommentResp(comment)
 	if err != nil {
 		return err
 	}
 	return rest.BuildSuccessResp(c, resp)
 }
+
+func LikeComment(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid comment id")
+	}
+	uid := c.Get("CurrentUser").(*models.User).UID
+	_, err = svc.LikeComment(c.Request().Context(), uid, id)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func UnlikeComment(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid comment id")
+	}
+	uid := c.Get("CurrentUser").(*models.User).UID
+	err = svc.UnlikeComment(c.Request().Context(), uid, id)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func batchBuildCommentResp(comments []*models.Status) ([]*CommentResp, error) {
+	result := make([]*CommentResp, len(comments))
+	var err error
+	for i, comment := range comments {
+		result[i], err = buildCommentResp(comment)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func buildCommentResp(comment *models.Status) (*CommentResp, error) {
+	statusResp, err := buildStatusResp(comment)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := batchBuildCommentResp(comment.Replies)
+	if err != nil {
+		return nil, err
+	}
+	return &CommentResp{
+		StatusResp:   statusResp,
+		ReplyToUser:  buildUserResp(comment.ReplyToUser),
+		RepliesCount: comment.CommentsCount,
+		Replies:      replies,
+	}, nil
+}
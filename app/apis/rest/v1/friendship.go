@@ -22,6 +22,19 @@ type FollowParams struct {
 	ToUserID uint64 `json:"to_user_id" query:"to_user_id"`
 }
 
+type FollowRequestParams struct {
+	FromUserID uint64 `json:"from_user_id" query:"from_user_id"`
+}
+
+type ListFollowRequestParams struct {
+	pagination.PageQuickParams
+}
+
+type FollowRequestResp struct {
+	User      *UserResp `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type FriendshipResp struct {
 	User         *UserResp `json:"user"`
 	RelationType string    `json:"relation_type"`
@@ -42,7 +55,11 @@ func ListFriendship(c echo.Context) error {
 	if err != nil {
 		relationType = enum.Fan
 	}
-	follows, page, err := followSVC.ListFriendship(c.Request().Context(), uid, relationType, &params.QuickPagination)
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	follows, page, err := followSVC.ListFriendship(c.Request().Context(), currentUID, uid, relationType, &params.QuickPagination)
 	if err != nil {
 		return err
 	}
@@ -56,11 +73,13 @@ func Follow(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return codes.ErrInvalidArgument
 	}
-	_, err := followSVC.Follow(c.Request().Context(), uid, params.ToUserID)
+	_, request, err := followSVC.Follow(c.Request().Context(), uid, params.ToUserID)
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessResp(c, nil)
+	return rest.BuildSuccessResp(c, echo.Map{
+		"pending": request != nil,
+	})
 }
 
 func Unfollow(c echo.Context) error {
@@ -76,6 +95,63 @@ func Unfollow(c echo.Context) error {
 	return rest.BuildSuccessResp(c, nil)
 }
 
+func ListFollowRequest(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &ListFollowRequestParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	requests, page, err := followSVC.ListFollowRequest(c.Request().Context(), uid, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp := make([]*FollowRequestResp, len(requests))
+	for i, request := range requests {
+		resp[i] = &FollowRequestResp{
+			User:      buildUserResp(request.FromUser),
+			CreatedAt: request.CreatedAt,
+		}
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
+func ApproveFollowRequest(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowRequestParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	_, err := followSVC.ApproveFollowRequest(c.Request().Context(), uid, params.FromUserID)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func RejectFollowRequest(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowRequestParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	if err := followSVC.RejectFollowRequest(c.Request().Context(), uid, params.FromUserID); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func CancelFollowRequest(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	if err := followSVC.CancelFollowRequest(c.Request().Context(), uid, params.ToUserID); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
 func batchBuildFriendshipResp(relationType enum.RelationType, friendships []*models.Follow) []*FriendshipResp {
 	resp := make([]*FriendshipResp, len(friendships))
 	for i, friendship := range friendships {
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	"github.com/mises-id/sns/app/models"
+	svc "github.com/mises-id/sns/app/services/notification"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ListNotificationParams struct {
+	pagination.PageQuickParams
+}
+
+type ReadNotificationParams struct {
+	IDs []string `json:"ids"`
+}
+
+type NotificationResp struct {
+	ID          string      `json:"id"`
+	NotifyType  string      `json:"notify_type"`
+	Actors      []*UserResp `json:"actors"`
+	ActorsCount uint64      `json:"actors_count"`
+	Status      *StatusResp `json:"status"`
+	Source      *StatusResp `json:"source"`
+	IsRead      bool        `json:"is_read"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func ListNotification(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &ListNotificationParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	notifications, page, err := svc.ListNotification(c.Request().Context(), uid, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp, err := batchBuildNotificationResp(notifications)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
+func ReadNotification(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &ReadNotificationParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid read params")
+	}
+	ids := make([]primitive.ObjectID, len(params.IDs))
+	for i, hex := range params.IDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return codes.ErrInvalidArgument.Newf("invalid notification id %s", hex)
+		}
+		ids[i] = id
+	}
+	if err := svc.ReadNotifications(c.Request().Context(), uid, ids); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func UnreadNotificationCount(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	count, err := svc.CountUnread(c.Request().Context(), uid)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, echo.Map{
+		"count": count,
+	})
+}
+
+func batchBuildNotificationResp(notifications []*models.Notification) ([]*NotificationResp, error) {
+	result := make([]*NotificationResp, len(notifications))
+	for i, notification := range notifications {
+		statusResp, err := buildStatusResp(notification.Status)
+		if err != nil {
+			return nil, err
+		}
+		sourceResp, err := buildStatusResp(notification.Source)
+		if err != nil {
+			return nil, err
+		}
+		actors := make([]*UserResp, len(notification.Actors))
+		for j, actor := range notification.Actors {
+			actors[j] = buildUserResp(actor)
+		}
+		result[i] = &NotificationResp{
+			ID:          notification.ID.Hex(),
+			NotifyType:  notification.NotifyType.String(),
+			Actors:      actors,
+			ActorsCount: notification.ActorsCount,
+			Status:      statusResp,
+			Source:      sourceResp,
+			IsRead:      notification.IsRead(),
+			CreatedAt:   notification.CreatedAt,
+			UpdatedAt:   notification.UpdatedAt,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	relationSVC "github.com/mises-id/sns/app/services/relation"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+)
+
+type ListRelationParams struct {
+	pagination.PageQuickParams
+}
+
+type RelationResp struct {
+	User         *UserResp `json:"user"`
+	RelationType string    `json:"relation_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func ListBlock(c echo.Context) error {
+	return listRelation(c, enum.Block)
+}
+
+func Block(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	_, err := relationSVC.Block(c.Request().Context(), uid, params.ToUserID)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func Unblock(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	if err := relationSVC.Unblock(c.Request().Context(), uid, params.ToUserID); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func ListMute(c echo.Context) error {
+	return listRelation(c, enum.Mute)
+}
+
+func Mute(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	_, err := relationSVC.Mute(c.Request().Context(), uid, params.ToUserID)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func Unmute(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &FollowParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	if err := relationSVC.Unmute(c.Request().Context(), uid, params.ToUserID); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+func listRelation(c echo.Context, relationType enum.RelationType) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &ListRelationParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	relations, page, err := relationSVC.ListRelation(c.Request().Context(), uid, relationType, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp := make([]*RelationResp, len(relations))
+	for i, relation := range relations {
+		resp[i] = &RelationResp{
+			User:         buildUserResp(relation.ToUser),
+			RelationType: relation.RelationType.String(),
+			CreatedAt:    relation.CreatedAt,
+		}
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	searchSVC "github.com/mises-id/sns/app/services/search"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+)
+
+type SearchParams struct {
+	pagination.TraditionalParams
+	Type  string `query:"type"`
+	Query string `query:"q"`
+}
+
+func Search(c echo.Context) error {
+	params := &SearchParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	switch params.Type {
+	case "", "status":
+		statuses, page, err := searchSVC.SearchStatus(c.Request().Context(), currentUID, params.Query, &params.TraditionalParams)
+		if err != nil {
+			return err
+		}
+		resp, err := batchBuildStatusResp(statuses)
+		if err != nil {
+			return err
+		}
+		return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+	case "user":
+		users, page, err := searchSVC.SearchUser(c.Request().Context(), currentUID, params.Query, &params.TraditionalParams)
+		if err != nil {
+			return err
+		}
+		resp := make([]*UserResp, len(users))
+		for i, user := range users {
+			resp[i] = buildUserResp(user)
+		}
+		return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+	default:
+		return codes.ErrInvalidArgument.Newf("invalid search type %s", params.Type)
+	}
+}
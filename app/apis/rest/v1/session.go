@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	"github.com/mises-id/sns/app/models"
+	sessionSVC "github.com/mises-id/sns/app/services/session"
+	"github.com/mises-id/sns/lib/codes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefreshTokenParams struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type DeleteSessionParams struct {
+	ID string `json:"id" query:"id"`
+}
+
+type TokenResp struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type SessionResp struct {
+	ID         string    `json:"id"`
+	DeviceID   string    `json:"device_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func RefreshToken(c echo.Context) error {
+	params := &RefreshTokenParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	token, err := sessionSVC.Refresh(c.Request().Context(), params.RefreshToken, requestDevice(c))
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, buildTokenResp(token))
+}
+
+func ListSession(c echo.Context) error {
+	uid := c.Get("CurrentUID").(uint64)
+	sessions, err := sessionSVC.ListSessions(c.Request().Context(), uid)
+	if err != nil {
+		return err
+	}
+	// the session is nil for a legacy token
+	current, _ := c.Get("CurrentSession").(*models.Session)
+	resp := make([]*SessionResp, len(sessions))
+	for i, session := range sessions {
+		resp[i] = &SessionResp{
+			ID:         session.ID.Hex(),
+			DeviceID:   session.DeviceID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    current != nil && session.ID == current.ID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+		}
+	}
+	return rest.BuildSuccessResp(c, resp)
+}
+
+// DeleteSession signs out the session of the id, or the current session without an id
+func DeleteSession(c echo.Context) error {
+	params := &DeleteSessionParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	var id primitive.ObjectID
+	if current, _ := c.Get("CurrentSession").(*models.Session); current != nil {
+		id = current.ID
+	}
+	if params.ID != "" {
+		var err error
+		if id, err = primitive.ObjectIDFromHex(params.ID); err != nil {
+			return codes.ErrNotFound.New("session not found")
+		}
+	}
+	if id.IsZero() {
+		return codes.ErrInvalidArgument.New("legacy token has no session to sign out")
+	}
+	if err := sessionSVC.RevokeSession(c.Request().Context(), uid, id); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, nil)
+}
+
+// JWKS publishes the public keys of the access tokens for other services
+func JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, sessionSVC.KeySet().JWKS())
+}
+
+func requestDevice(c echo.Context) *models.Device {
+	return &models.Device{
+		DeviceID:  c.Request().Header.Get("x-device-id"),
+		UserAgent: c.Request().UserAgent(),
+		IP:        c.RealIP(),
+	}
+}
+
+func buildTokenResp(token *sessionSVC.Token) *TokenResp {
+	return &TokenResp{
+		Token:        token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(token.ExpiresAt).Seconds()),
+	}
+}
@@ -20,42 +20,119 @@ type ListUserStatusParams struct {
 	pagination.PageQuickParams
 }
 
+type ListLikeParams struct {
+	pagination.PageQuickParams
+}
+
+type LikeResp struct {
+	User      *UserResp `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type LinkMeta struct {
-	Title        string `json:"title"`
-	Host         string `json:"host"`
-	Link         string `json:"link"`
+	Link string `json:"link"`
+}
+
+type MediaItem struct {
 	AttachmentID uint64 `json:"attachment_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Alt          string `json:"alt"`
+}
+
+type ImageMeta struct {
+	Images []*MediaItem `json:"images"`
+}
+
+type VideoMeta struct {
+	Videos []*MediaItem `json:"videos"`
 }
 
 type CreateStatusParams struct {
 	StatusType string             `json:"status_type"`
 	ParentID   primitive.ObjectID `json:"parent_id"`
 	Content    string             `json:"content"`
+	Visibility string             `json:"visibility"`
 	LinkMeta   *LinkMeta          `json:"link_meta"`
+	ImageMeta  *ImageMeta         `json:"image_meta"`
+	VideoMeta  *VideoMeta         `json:"video_meta"`
+}
+
+type EditStatusParams struct {
+	Content   string     `json:"content"`
+	LinkMeta  *LinkMeta  `json:"link_meta"`
+	ImageMeta *ImageMeta `json:"image_meta"`
+	VideoMeta *VideoMeta `json:"video_meta"`
 }
 
 type LinkMetaResp struct {
 	Title         string `json:"title"`
+	Description   string `json:"description"`
 	Host          string `json:"host"`
 	Link          string `json:"link"`
 	AttachmentID  uint64 `json:"attachment_id"`
 	AttachmentURL string `json:"attachment_url"`
 }
 
+type MediaItemResp struct {
+	AttachmentID  uint64  `json:"attachment_id"`
+	AttachmentURL string  `json:"attachment_url"`
+	SmallURL      string  `json:"small_url,omitempty"`
+	MediumURL     string  `json:"medium_url,omitempty"`
+	LargeURL      string  `json:"large_url,omitempty"`
+	PosterURL     string  `json:"poster_url,omitempty"`
+	Duration      float64 `json:"duration,omitempty"`
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	Alt           string  `json:"alt"`
+}
+
+type ImageMetaResp struct {
+	Images []*MediaItemResp `json:"images"`
+}
+
+type VideoMetaResp struct {
+	Videos []*MediaItemResp `json:"videos"`
+}
+
+type MentionResp struct {
+	UID      uint64 `json:"uid"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
 type StatusResp struct {
-	ID            string        `json:"id"`
-	User          *UserResp     `json:"user"`
-	Content       string        `json:"content"`
-	FromType      string        `json:"from_type"`
-	StatusType    string        `json:"status_type"`
-	ParentStatus  *StatusResp   `json:"parent_status"`
-	OriginStatus  *StatusResp   `json:"origin_status"`
-	CommentsCount uint64        `json:"comments_count"`
-	LikesCount    uint64        `json:"likes_count"`
-	ForwardsCount uint64        `json:"forwards_count"`
-	IsLiked       bool          `json:"is_liked"`
-	LinkMeta      *LinkMetaResp `json:"link_meta"`
-	CreatedAt     time.Time     `json:"created_at"`
+	ID            string         `json:"id"`
+	User          *UserResp      `json:"user"`
+	Content       string         `json:"content"`
+	FromType      string         `json:"from_type"`
+	StatusType    string         `json:"status_type"`
+	Visibility    string         `json:"visibility"`
+	ParentStatus  *StatusResp    `json:"parent_status"`
+	OriginStatus  *StatusResp    `json:"origin_status"`
+	CommentsCount uint64         `json:"comments_count"`
+	LikesCount    uint64         `json:"likes_count"`
+	ForwardsCount uint64         `json:"forwards_count"`
+	IsLiked       bool           `json:"is_liked"`
+	IsDeleted     bool           `json:"is_deleted"`
+	LinkMeta      *LinkMetaResp  `json:"link_meta"`
+	ImageMeta     *ImageMetaResp `json:"image_meta,omitempty"`
+	VideoMeta     *VideoMetaResp `json:"video_meta,omitempty"`
+	Mentions      []*MentionResp `json:"mentions"`
+	EditedAt      *time.Time     `json:"edited_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+type StatusRevisionResp struct {
+	ID         string         `json:"id"`
+	Content    string         `json:"content"`
+	StatusType string         `json:"status_type"`
+	LinkMeta   *LinkMetaResp  `json:"link_meta"`
+	ImageMeta  *ImageMetaResp `json:"image_meta,omitempty"`
+	VideoMeta  *VideoMetaResp `json:"video_meta,omitempty"`
+	Mentions   []*MentionResp `json:"mentions"`
+	CreatedAt  time.Time      `json:"created_at"`
 }
 
 func GetStatus(c echo.Context) error {
@@ -126,6 +203,23 @@ func Timeline(c echo.Context) error {
 	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
 }
 
+func ListMyMentions(c echo.Context) error {
+	uid := c.Get("CurrentUser").(*models.User).UID
+	params := &ListUserStatusParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	statuses, page, err := svc.ListMentionStatus(c.Request().Context(), uid, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp, err := batchBuildStatusResp(statuses)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
 func RecommendStatus(c echo.Context) error {
 	var currentUID uint64
 	if c.Get("CurrentUID") != nil {
@@ -157,19 +251,33 @@ func CreateStatus(c echo.Context) error {
 	if !params.ParentID.IsZero() {
 		fromType = enum.FromForward
 	}
-	var meta json.RawMessage
-	var err error
-	if params.LinkMeta != nil {
-		if meta, err = json.Marshal(params.LinkMeta); err != nil {
+	visibility := enum.VisibilityPublic
+	if params.Visibility != "" {
+		var err error
+		if visibility, err = enum.VisibilityFromString(params.Visibility); err != nil {
 			return err
 		}
 	}
+	var meta json.RawMessage
+	var err error
+	switch {
+	case params.LinkMeta != nil:
+		meta, err = json.Marshal(params.LinkMeta)
+	case params.ImageMeta != nil:
+		meta, err = json.Marshal(params.ImageMeta)
+	case params.VideoMeta != nil:
+		meta, err = json.Marshal(params.VideoMeta)
+	}
+	if err != nil {
+		return err
+	}
 	status, err := svc.CreateStatus(c.Request().Context(), uid, &svc.CreateStatusParams{
 		StatusType: params.StatusType,
 		Content:    params.Content,
 		ParentID:   params.ParentID,
 		Meta:       meta,
 		FromType:   fromType,
+		Visibility: visibility,
 	})
 	if err != nil {
 		return err
@@ -181,6 +289,79 @@ func CreateStatus(c echo.Context) error {
 	return rest.BuildSuccessResp(c, resp)
 }
 
+func EditStatus(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid status id")
+	}
+	params := &EditStatusParams{}
+	if err = c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid status params")
+	}
+	uid := c.Get("CurrentUser").(*models.User).UID
+	var meta json.RawMessage
+	switch {
+	case params.LinkMeta != nil:
+		meta, err = json.Marshal(params.LinkMeta)
+	case params.ImageMeta != nil:
+		meta, err = json.Marshal(params.ImageMeta)
+	case params.VideoMeta != nil:
+		meta, err = json.Marshal(params.VideoMeta)
+	}
+	if err != nil {
+		return err
+	}
+	status, err := svc.EditStatus(c.Request().Context(), uid, id, &svc.EditStatusParams{
+		Content: params.Content,
+		Meta:    meta,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := buildStatusResp(status)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, resp)
+}
+
+func ListStatusRevision(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid status id")
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	params := &ListUserStatusParams{}
+	if err = c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	revisions, page, err := svc.ListStatusRevision(c.Request().Context(), currentUID, id, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp := make([]*StatusRevisionResp, len(revisions))
+	for i, revision := range revisions {
+		statusResp, err := buildStatusResp(revision.Status)
+		if err != nil {
+			return err
+		}
+		resp[i] = &StatusRevisionResp{
+			ID:         revision.ID.Hex(),
+			Content:    statusResp.Content,
+			StatusType: statusResp.StatusType,
+			LinkMeta:   statusResp.LinkMeta,
+			ImageMeta:  statusResp.ImageMeta,
+			VideoMeta:  statusResp.VideoMeta,
+			Mentions:   statusResp.Mentions,
+			CreatedAt:  revision.CreatedAt,
+		}
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
 func DeleteStatus(c echo.Context) error {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
@@ -220,6 +401,63 @@ func UnlikeStatus(c echo.Context) error {
 	return rest.BuildSuccessResp(c, nil)
 }
 
+func ListStatusLike(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid status id")
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	params := &ListLikeParams{}
+	if err = c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	likes, page, err := svc.ListStatusLike(c.Request().Context(), currentUID, id, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, batchBuildLikeResp(likes), page.BuildJSONResult())
+}
+
+// list statuses liked by user
+func ListUserLikedStatus(c echo.Context) error {
+	uidParam := c.Param("uid")
+	uid, err := strconv.ParseUint(uidParam, 10, 64)
+	if err != nil {
+		return codes.ErrInvalidArgument.Newf("invalid uid %s", uidParam)
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	params := &ListLikeParams{}
+	if err = c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	statuses, page, err := svc.ListUserLikedStatus(c.Request().Context(), currentUID, uid, &params.PageQuickParams)
+	if err != nil {
+		return err
+	}
+	resp, err := batchBuildStatusResp(statuses)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
+func batchBuildLikeResp(likes []*models.Like) []*LikeResp {
+	resp := make([]*LikeResp, len(likes))
+	for i, like := range likes {
+		resp[i] = &LikeResp{
+			User:      buildUserResp(like.User),
+			CreatedAt: like.CreatedAt,
+		}
+	}
+	return resp
+}
+
 func batchBuildStatusResp(statuses []*models.Status) ([]*StatusResp, error) {
 	result := make([]*StatusResp, len(statuses))
 	var err error
@@ -236,6 +474,9 @@ func buildStatusResp(status *models.Status) (*StatusResp, error) {
 	if status == nil {
 		return nil, nil
 	}
+	if status.IsDeleted() {
+		return buildTombstoneResp(status), nil
+	}
 	parentResp, err := buildStatusResp(status.ParentStatus)
 	if err != nil {
 		return nil, err
@@ -250,12 +491,15 @@ func buildStatusResp(status *models.Status) (*StatusResp, error) {
 		Content:       status.Content,
 		FromType:      status.FromType.String(),
 		StatusType:    status.StatusType.String(),
+		Visibility:    status.Visibility.String(),
 		ParentStatus:  parentResp,
 		OriginStatus:  originResp,
 		CommentsCount: status.CommentsCount,
 		LikesCount:    status.LikesCount,
 		ForwardsCount: status.ForwardsCount,
 		IsLiked:       status.IsLiked,
+		Mentions:      buildMentions(status.Mentions),
+		EditedAt:      status.EditedAt,
 		CreatedAt:     status.CreatedAt,
 	}
 	metaData, err := status.GetMetaData()
@@ -266,16 +510,67 @@ func buildStatusResp(status *models.Status) (*StatusResp, error) {
 	case enum.LinkStatus:
 		linkMeta := metaData.(*meta.LinkMeta)
 		resp.LinkMeta = buildLinkMeta(linkMeta)
+	case enum.ImageStatus:
+		if imageMeta, ok := metaData.(*meta.ImageMeta); ok {
+			resp.ImageMeta = &ImageMetaResp{Images: buildMediaItems(imageMeta.Images)}
+		}
+	case enum.VideoStatus:
+		if videoMeta, ok := metaData.(*meta.VideoMeta); ok {
+			resp.VideoMeta = &VideoMetaResp{Videos: buildMediaItems(videoMeta.Videos)}
+		}
 	}
 	return resp, nil
 }
 
+func buildMentions(mentions []*models.Mention) []*MentionResp {
+	resp := make([]*MentionResp, len(mentions))
+	for i, mention := range mentions {
+		resp[i] = &MentionResp{
+			UID:      mention.UID,
+			Username: mention.Username,
+			Start:    mention.Start,
+			End:      mention.End,
+		}
+	}
+	return resp
+}
+
+func buildMediaItems(items []*meta.MediaItem) []*MediaItemResp {
+	resp := make([]*MediaItemResp, len(items))
+	for i, item := range items {
+		resp[i] = &MediaItemResp{
+			AttachmentID:  item.AttachmentID,
+			AttachmentURL: item.AttachmentURL,
+			SmallURL:      item.SmallURL,
+			MediumURL:     item.MediumURL,
+			LargeURL:      item.LargeURL,
+			PosterURL:     item.PosterURL,
+			Duration:      item.Duration,
+			Width:         item.Width,
+			Height:        item.Height,
+			Alt:           item.Alt,
+		}
+	}
+	return resp
+}
+
+func buildTombstoneResp(status *models.Status) *StatusResp {
+	return &StatusResp{
+		ID:         status.ID.Hex(),
+		FromType:   status.FromType.String(),
+		StatusType: status.StatusType.String(),
+		IsDeleted:  true,
+		CreatedAt:  status.CreatedAt,
+	}
+}
+
 func buildLinkMeta(meta *meta.LinkMeta) *LinkMetaResp {
 	if meta == nil {
 		return nil
 	}
 	return &LinkMetaResp{
 		Title:         meta.Title,
+		Description:   meta.Description,
 		Host:          meta.Host,
 		Link:          meta.Link,
 		AttachmentID:  meta.AttachmentID,
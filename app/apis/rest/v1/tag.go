@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"net/url"
+
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	statusSVC "github.com/mises-id/sns/app/services/status"
+	tagSVC "github.com/mises-id/sns/app/services/tag"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+)
+
+type TrendingTagParams struct {
+	Window string `query:"window"`
+	Limit  int64  `query:"limit"`
+}
+
+type TagResp struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+func ListTagStatus(c echo.Context) error {
+	params := &pagination.PageQuickParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	var currentUID uint64
+	if c.Get("CurrentUID") != nil {
+		currentUID = c.Get("CurrentUID").(uint64)
+	}
+	name, err := url.PathUnescape(c.Param("name"))
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid tag")
+	}
+	statuses, page, err := statusSVC.ListTagStatus(c.Request().Context(), currentUID, name, params)
+	if err != nil {
+		return err
+	}
+	resp, err := batchBuildStatusResp(statuses)
+	if err != nil {
+		return err
+	}
+	return rest.BuildSuccessRespWithPagination(c, resp, page.BuildJSONResult())
+}
+
+func TrendingTags(c echo.Context) error {
+	params := &TrendingTagParams{}
+	if err := c.Bind(params); err != nil {
+		return codes.ErrInvalidArgument.New("invalid query params")
+	}
+	tags, err := tagSVC.ListTrendingTags(c.Request().Context(), params.Window, params.Limit)
+	if err != nil {
+		return err
+	}
+	resp := make([]*TagResp, len(tags))
+	for i, tag := range tags {
+		resp[i] = &TagResp{
+			Tag:   tag.Tag,
+			Count: tag.Count,
+		}
+	}
+	return rest.BuildSuccessResp(c, resp)
+}
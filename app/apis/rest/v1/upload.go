@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/mises-id/sns/app/apis/rest"
+	"github.com/mises-id/sns/app/models"
+	uploadSVC "github.com/mises-id/sns/app/services/upload"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the upload session api follows the core protocol of tus 1.0.0 with the creation, expiration and termination extensions
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+func TusOptions(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set("Tus-Resumable", tusVersion)
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", tusExtensions)
+	maxSize := env.Envs.VideoMaxSize
+	if env.Envs.ImageMaxSize > maxSize {
+		maxSize = env.Envs.ImageMaxSize
+	}
+	header.Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func CreateUploadSession(c echo.Context) error {
+	if !tusResumable(c) {
+		return c.NoContent(http.StatusPreconditionFailed)
+	}
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid upload length")
+	}
+	metadata, err := parseUploadMetadata(c.Request().Header.Get("Upload-Metadata"))
+	if err != nil {
+		return err
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	session, err := uploadSVC.CreateSession(c.Request().Context(), uid, length, metadata)
+	if err != nil {
+		return err
+	}
+	setUploadHeaders(c, session)
+	c.Response().Header().Set(echo.HeaderLocation, path.Join(c.Request().URL.Path, session.ID.Hex()))
+	return c.NoContent(http.StatusCreated)
+}
+
+func UploadSessionStatus(c echo.Context) error {
+	if !tusResumable(c) {
+		return c.NoContent(http.StatusPreconditionFailed)
+	}
+	id, err := uploadSessionID(c)
+	if err != nil {
+		return err
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	session, err := uploadSVC.FindSession(c.Request().Context(), uid, id)
+	if err != nil {
+		return err
+	}
+	setUploadHeaders(c, session)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.NoContent(http.StatusOK)
+}
+
+// UploadChunk appends the body at the Upload-Offset, the attachment is created when the last chunk is received
+func UploadChunk(c echo.Context) error {
+	if !tusResumable(c) {
+		return c.NoContent(http.StatusPreconditionFailed)
+	}
+	if c.Request().Header.Get(echo.HeaderContentType) != tusContentType {
+		return codes.ErrUnsupportedMedia.Newf("content type must be %s", tusContentType)
+	}
+	id, err := uploadSessionID(c)
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return codes.ErrInvalidArgument.New("invalid upload offset")
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	session, err := uploadSVC.AppendChunk(c.Request().Context(), uid, id, offset, c.Request().Body)
+	if err != nil {
+		return err
+	}
+	setUploadHeaders(c, session)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CompleteUploadSession returns the attachment of a complete upload
+func CompleteUploadSession(c echo.Context) error {
+	id, err := uploadSessionID(c)
+	if err != nil {
+		return err
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	attachment, err := uploadSVC.Complete(c.Request().Context(), uid, id)
+	if err != nil {
+		return err
+	}
+	if err = models.PreloadPosters(c.Request().Context(), attachment); err != nil {
+		return err
+	}
+	return rest.BuildSuccessResp(c, buildAttachmentResp(attachment))
+}
+
+func TerminateUploadSession(c echo.Context) error {
+	if !tusResumable(c) {
+		return c.NoContent(http.StatusPreconditionFailed)
+	}
+	id, err := uploadSessionID(c)
+	if err != nil {
+		return err
+	}
+	uid := c.Get("CurrentUID").(uint64)
+	if err = uploadSVC.Terminate(c.Request().Context(), uid, id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tusResumable sets the protocol version of the response, it is false for a client of another version
+func tusResumable(c echo.Context) bool {
+	header := c.Response().Header()
+	header.Set("Tus-Resumable", tusVersion)
+	if c.Request().Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	header.Set("Tus-Version", tusVersion)
+	return false
+}
+
+func setUploadHeaders(c echo.Context, session *models.UploadSession) {
+	header := c.Response().Header()
+	header.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(session.Length, 10))
+	header.Set("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+func uploadSessionID(c echo.Context) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return id, codes.ErrNotFound.New("upload session not found")
+	}
+	return id, nil
+}
+
+// parseUploadMetadata decodes the comma separated pairs of key and base64 value, the value may be absent
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			continue
+		}
+		if len(fields) == 1 {
+			metadata[fields[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, codes.ErrInvalidArgument.Newf("invalid upload metadata %s", fields[0])
+		}
+		metadata[fields[0]] = string(value)
+	}
+	return metadata, nil
+}
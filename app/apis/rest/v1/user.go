@@ -35,6 +35,7 @@ type UserResp struct {
 	Address    string      `json:"address"`
 	Avatar     *AvatarResp `json:"avatar"`
 	IsFollowed bool        `json:"is_followed"`
+	IsPrivate  bool        `json:"is_private"`
 }
 
 func SignIn(c echo.Context) error {
@@ -42,13 +43,14 @@ func SignIn(c echo.Context) error {
 	if err := c.Bind(params); err != nil {
 		return err
 	}
-	token, err := sessionSVC.SignIn(c.Request().Context(), params.UserAuthz.Auth)
+	if params.UserAuthz == nil {
+		return codes.ErrInvalidArgument
+	}
+	token, err := sessionSVC.SignIn(c.Request().Context(), params.UserAuthz.Auth, requestDevice(c))
 	if err != nil {
 		return err
 	}
-	return rest.BuildSuccessResp(c, echo.Map{
-		"token": token,
-	})
+	return rest.BuildSuccessResp(c, buildTokenResp(token))
 }
 
 func MyProfile(c echo.Context) error {
@@ -88,11 +90,16 @@ type UserAvatarParams struct {
 	AttachmentID uint64 `json:"attachment_id"`
 }
 
+type UserPrivacyParams struct {
+	IsPrivate bool `json:"is_private"`
+}
+
 type UserUpdateParams struct {
 	By       string             `json:"by"`
 	Profile  *UserProfileParams `json:"profile"`
 	Username *UserNameParams    `json:"username"`
 	Avatar   *UserAvatarParams  `json:"avatar"`
+	Privacy  *UserPrivacyParams `json:"privacy"`
 }
 
 func UpdateUser(c echo.Context) error {
@@ -121,6 +128,11 @@ func UpdateUser(c echo.Context) error {
 		user, err = svc.UpdateUserAvatar(c.Request().Context(), uid, params.Avatar.AttachmentID)
 	case "username":
 		user, err = svc.UpdateUsername(c.Request().Context(), uid, params.Username.Username)
+	case "privacy":
+		if params.Privacy == nil {
+			return codes.ErrInvalidArgument
+		}
+		user, err = svc.UpdateUserPrivacy(c.Request().Context(), uid, params.Privacy.IsPrivate)
 	}
 	if err != nil {
 		return err
@@ -141,13 +153,13 @@ func buildUserResp(user *models.User) *UserResp {
 		Email:      user.Email,
 		Address:    user.Address,
 		IsFollowed: user.IsFollowed,
+		IsPrivate:  user.IsPrivate,
 	}
 	if user.Avatar != nil {
 		resp.Avatar = &AvatarResp{
-			// TODO support multiple sizes avatar
-			Small:  user.Avatar.FileUrl(),
-			Medium: user.Avatar.FileUrl(),
-			Large:  user.Avatar.FileUrl(),
+			Small:  user.Avatar.VariantUrl("small"),
+			Medium: user.Avatar.VariantUrl("medium"),
+			Large:  user.Avatar.VariantUrl("large"),
 		}
 	}
 	return resp
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/queue"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	jobQueue *queue.Queue
+	once     sync.Once
+)
+
+// Queue is the queue of the jobs collection, it is available after the mongo setup
+func Queue() *queue.Queue {
+	once.Do(func() {
+		jobQueue = queue.New(db.DB().Collection("jobs"))
+	})
+	return jobQueue
+}
+
+func EnsureIndex() {
+	if err := Queue().EnsureIndex(context.Background()); err != nil {
+		logrus.Debug(err)
+	}
+}
+
+// NewWorker returns a worker running the handlers of all the jobs
+func NewWorker() *queue.Worker {
+	worker := queue.NewWorker(Queue())
+	worker.Concurrency = env.Envs.WorkerConcurrency
+	worker.Register(StatusFanOut, fanOutStatus)
+	worker.Register(StatusUnfurlLink, unfurlStatusLink)
+	return worker
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
+	linkpreviewSVC "github.com/mises-id/sns/app/services/linkpreview"
+	"github.com/mises-id/sns/lib/queue"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const StatusUnfurlLink = "status.unfurl_link"
+
+type unfurlLinkPayload struct {
+	StatusID primitive.ObjectID `bson:"status_id"`
+}
+
+// EnqueueUnfurlLink schedules fetching the preview of the link of the status
+func EnqueueUnfurlLink(ctx context.Context, statusID primitive.ObjectID) error {
+	_, err := Queue().Enqueue(ctx, StatusUnfurlLink, &unfurlLinkPayload{StatusID: statusID})
+	return err
+}
+
+// a failed fetch is cached as a preview with the host only, so the job is not retried for it.
+// The status edited during the fetch fails with conflict, it is loaded again by the retry.
+func unfurlStatusLink(ctx context.Context, job *queue.Job) error {
+	payload := &unfurlLinkPayload{}
+	if err := job.Decode(payload); err != nil {
+		return err
+	}
+	status, err := models.FindStatusIncludeDeleted(ctx, payload.StatusID)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if status.IsDeleted() || status.StatusType != enum.LinkStatus {
+		return nil
+	}
+	metaData, err := status.GetMetaData()
+	if err != nil {
+		return err
+	}
+	linkMeta, ok := metaData.(*meta.LinkMeta)
+	if !ok || linkMeta.Link == "" {
+		return nil
+	}
+	unfurled, err := linkpreviewSVC.Unfurl(ctx, linkMeta.Link)
+	if err != nil {
+		return err
+	}
+	return models.UpdateStatusLinkMeta(ctx, status, unfurled)
+}
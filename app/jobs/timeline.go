@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/lib/queue"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const StatusFanOut = "status.fan_out"
+
+type fanOutPayload struct {
+	StatusID primitive.ObjectID `bson:"status_id"`
+}
+
+// EnqueueFanOut schedules pushing the status into the timelines of the fans of its author
+func EnqueueFanOut(ctx context.Context, statusID primitive.ObjectID) error {
+	_, err := Queue().Enqueue(ctx, StatusFanOut, &fanOutPayload{StatusID: statusID})
+	return err
+}
+
+// a status deleted before the fan out is skipped, the timeline entries are idempotent so a retry is safe
+func fanOutStatus(ctx context.Context, job *queue.Job) error {
+	payload := &fanOutPayload{}
+	if err := job.Decode(payload); err != nil {
+		return err
+	}
+	status, err := models.FindStatus(ctx, payload.StatusID)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return models.FanOutStatus(ctx, status)
+}
@@ -24,12 +24,13 @@ var SetCurrentUserMiddleware = func(next echo.HandlerFunc) echo.HandlerFunc {
 				return err
 			}
 
-			user, err := session.Auth(c.Request().Context(), strs[1])
+			user, currentSession, err := session.Auth(c.Request().Context(), strs[1])
 			if err != nil {
 				return err
 			}
 			c.Set("CurrentUser", user)
 			c.Set("CurrentUID", user.UID)
+			c.Set("CurrentSession", currentSession)
 		}
 
 		return next(c)
@@ -1,25 +1,53 @@
 package models
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mises-id/sns/app/models/enum"
 	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
 	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/imaging"
 	"github.com/mises-id/sns/lib/storage"
+	"github.com/mises-id/sns/lib/video"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 type Attachment struct {
-	ID        uint64        `bson:"_id"`
-	Filename  string        `bson:"filename,omitempty"`
-	FileType  enum.FileType `bson:"file_type"`
-	CreatedAt time.Time     `bson:"created_at,omitempty"`
-	UpdatedAt time.Time     `bson:"updated_at,omitempty"`
+	ID       uint64          `bson:"_id"`
+	UID      uint64          `bson:"uid,omitempty"`
+	Filename string          `bson:"filename,omitempty"`
+	FileType enum.FileType   `bson:"file_type"`
+	Size     int64           `bson:"size,omitempty"`
+	MimeType string          `bson:"mime_type,omitempty"`
+	Sha256   string          `bson:"sha256,omitempty"`
+	Width    int             `bson:"width,omitempty"`
+	Height   int             `bson:"height,omitempty"`
+	Variants []*ImageVariant `bson:"variants,omitempty"`
+	// the duration in seconds, codec and poster image of a video
+	Duration  float64     `bson:"duration,omitempty"`
+	Codec     string      `bson:"codec,omitempty"`
+	PosterID  uint64      `bson:"poster_id,omitempty"`
+	Poster    *Attachment `bson:"-"`
+	CreatedAt time.Time   `bson:"created_at,omitempty"`
+	UpdatedAt time.Time   `bson:"updated_at,omitempty"`
 	file      storage.File
+	variants  map[string][]byte
+}
+
+// ImageVariant is a resized copy of an image attachment, stored in the folder of the original
+type ImageVariant struct {
+	Name     string `bson:"name"`
+	Filename string `bson:"filename"`
+	Width    int    `bson:"width"`
+	Height   int    `bson:"height"`
 }
 
 func (a *Attachment) BeforeCreate(ctx context.Context) error {
@@ -34,7 +62,22 @@ func (a *Attachment) BeforeCreate(ctx context.Context) error {
 }
 
 func (a *Attachment) FileUrl() string {
-	return env.Envs.AssetHost + path.Join(storage.Prefix, a.fileFolder(), a.Filename)
+	return storage.FileURL(a.fileFolder(), a.Filename)
+}
+
+// SignedFileUrl is an url of the file which expires
+func (a *Attachment) SignedFileUrl(ctx context.Context, expires time.Duration) (string, error) {
+	return storage.SignedFileURL(ctx, a.fileFolder(), a.Filename, expires)
+}
+
+// VariantUrl returns the url of the sized variant, or the url of the original if the variant does not exist
+func (a *Attachment) VariantUrl(name string) string {
+	for _, variant := range a.Variants {
+		if variant.Name == name {
+			return storage.FileURL(a.fileFolder(), variant.Filename)
+		}
+	}
+	return a.FileUrl()
 }
 
 func (a *Attachment) fileFolder() string {
@@ -46,23 +89,172 @@ func (a *Attachment) fileFolder() string {
 }
 
 func (a *Attachment) UploadFile(ctx context.Context) error {
-	return storage.UploadFile(ctx, a.fileFolder(), a.Filename, a.file)
+	if err := storage.UploadFile(ctx, a.fileFolder(), a.Filename, a.file); err != nil {
+		return err
+	}
+	for _, variant := range a.Variants {
+		if err := storage.UploadFile(ctx, a.fileFolder(), variant.Filename, bytes.NewReader(a.variants[variant.Name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteFiles deletes the stored file and its variants
+func (a *Attachment) DeleteFiles(ctx context.Context) error {
+	if err := storage.DeleteFile(ctx, a.fileFolder(), a.Filename); err != nil {
+		return err
+	}
+	for _, variant := range a.Variants {
+		if err := storage.DeleteFile(ctx, a.fileFolder(), variant.Filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probeVideo reads the duration, size and codec from the container, a webm video is stored without them
+func (a *Attachment) probeVideo() error {
+	if a.MimeType == "video/webm" {
+		return nil
+	}
+	readerAt, ok := a.file.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(a.file)
+		if err != nil {
+			return err
+		}
+		buffered := bytes.NewReader(data)
+		a.file, readerAt = buffered, buffered
+	}
+	info, err := video.Probe(readerAt, a.Size)
+	switch err {
+	case nil:
+	case video.ErrUnsupported:
+		return codes.ErrUnsupportedMedia.New("unsupported video container")
+	case video.ErrInvalid:
+		return codes.ErrInvalidArgument.New("invalid video file")
+	default:
+		return err
+	}
+	if maxDuration := env.Envs.VideoMaxDuration; maxDuration > 0 && info.Duration > maxDuration {
+		return codes.ErrPayloadTooLarge.Newf("video is longer than %s", maxDuration)
+	}
+	a.Width, a.Height, a.Codec = info.Width, info.Height, info.VideoCodec
+	a.Duration = info.Duration.Seconds()
+	return nil
+}
+
+// SetPoster links the image attachment as the poster of the video
+func (a *Attachment) SetPoster(ctx context.Context, poster *Attachment) error {
+	_, err := db.DB().Collection("attachments").UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$set": bson.M{"poster_id": poster.ID}})
+	if err != nil {
+		return err
+	}
+	a.PosterID, a.Poster = poster.ID, poster
+	return nil
+}
+
+// touch marks the reused attachment as updated, the sweeper counts the grace period from the last upload
+func (a *Attachment) touch(ctx context.Context) error {
+	now := time.Now()
+	_, err := db.DB().Collection("attachments").UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$set": bson.M{"updated_at": now}})
+	if err != nil {
+		return err
+	}
+	a.UpdatedAt = now
+	return nil
+}
+
+// PosterUrl is the url of the poster image, it is empty if the poster is not linked or preloaded
+func (a *Attachment) PosterUrl() string {
+	if a.Poster == nil {
+		return ""
+	}
+	return a.Poster.VariantUrl("large")
 }
 
-func CreateAttachment(ctx context.Context, tp enum.FileType, filename string, file storage.File) (*Attachment, error) {
+// processImage replaces the file with the upright original without metadata and builds the sized variants,
+// an image format which can not be decoded is stored as is
+func (a *Attachment) processImage() error {
+	data, err := io.ReadAll(a.file)
+	if err != nil {
+		return err
+	}
+	a.file = bytes.NewReader(data)
+	result, err := imaging.Process(data, imaging.DefaultSizes)
+	if err == imaging.ErrTooLarge {
+		return codes.ErrInvalidArgument.New("image is too large")
+	}
+	if err != nil {
+		logrus.Warnf("process image %s error: %v", a.Filename, err)
+		return nil
+	}
+	a.file = bytes.NewReader(result.Original)
+	a.Width, a.Height = result.Width, result.Height
+	a.variants = make(map[string][]byte)
+	base := strings.TrimSuffix(a.Filename, path.Ext(a.Filename))
+	for _, variant := range result.Variants {
+		a.Variants = append(a.Variants, &ImageVariant{
+			Name:     variant.Name,
+			Filename: base + "_" + variant.Name + variant.Ext,
+			Width:    variant.Width,
+			Height:   variant.Height,
+		})
+		a.variants[variant.Name] = variant.Data
+	}
+	return nil
+}
+
+// CreateAttachment validates and stores the file, the attachment of the same content uploaded by the user before is reused
+func CreateAttachment(ctx context.Context, uid uint64, tp enum.FileType, filename string, file storage.File) (*Attachment, error) {
 	attachment := &Attachment{
+		UID:      uid,
 		Filename: filename,
 		FileType: tp,
 		file:     file,
 	}
-	if err := attachment.BeforeCreate(ctx); err != nil {
+	if err := attachment.inspectFile(); err != nil {
+		return nil, err
+	}
+	if tp == enum.VideoFile {
+		if err := attachment.probeVideo(); err != nil {
+			return nil, err
+		}
+	}
+	duplicate, err := findDuplicateAttachment(ctx, uid, tp, attachment.Sha256)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate != nil {
+		return duplicate, duplicate.touch(ctx)
+	}
+	if err = reserveStorage(ctx, uid, attachment.Size); err != nil {
 		return nil, err
 	}
-	if err := attachment.UploadFile(ctx); err != nil {
+	if err = attachment.store(ctx); err != nil {
+		if releaseErr := releaseStorage(ctx, uid, attachment.Size); releaseErr != nil {
+			logrus.Errorf("release storage of user %d error: %v", uid, releaseErr)
+		}
 		return nil, err
 	}
-	_, err := db.DB().Collection("attachments").InsertOne(ctx, attachment)
-	return attachment, err
+	return attachment, nil
+}
+
+func (a *Attachment) store(ctx context.Context) error {
+	if err := a.BeforeCreate(ctx); err != nil {
+		return err
+	}
+	if a.FileType == enum.ImageFile {
+		if err := a.processImage(); err != nil {
+			return err
+		}
+	}
+	if err := a.UploadFile(ctx); err != nil {
+		return err
+	}
+	_, err := db.DB().Collection("attachments").InsertOne(ctx, a)
+	return err
 }
 
 func FindAttachmentMap(ctx context.Context, ids []uint64) (map[uint64]*Attachment, error) {
@@ -83,3 +275,24 @@ func FindAttachmentMap(ctx context.Context, ids []uint64) (map[uint64]*Attachmen
 	}
 	return result, nil
 }
+
+// PreloadPosters loads the posters of the video attachments
+func PreloadPosters(ctx context.Context, attachments ...*Attachment) error {
+	ids := make([]uint64, 0)
+	for _, attachment := range attachments {
+		if attachment.PosterID != 0 {
+			ids = append(ids, attachment.PosterID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	posterMap, err := FindAttachmentMap(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		attachment.Poster = posterMap[attachment.PosterID]
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var attachmentStatusTypes = []enum.StatusType{enum.LinkStatus, enum.ImageStatus, enum.VideoStatus}
+
+// SweepOrphanAttachments deletes the attachments uploaded before the grace period which are not referenced
+// by an avatar, a status, a revision of a status, a link preview or as the poster of a referenced video.
+// With dryRun the orphans are only returned. The grace period leaves time for an upload to be referenced,
+// it starts from updated_at which is touched when the same content is uploaded again.
+func SweepOrphanAttachments(ctx context.Context, grace time.Duration, dryRun bool) ([]*Attachment, error) {
+	referenced, err := referencedAttachmentIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-grace)
+	cursor, err := db.DB().Collection("attachments").Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"updated_at": bson.M{"$lt": cutoff}},
+		bson.M{"updated_at": nil, "created_at": bson.M{"$lt": cutoff}},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	orphans := make([]*Attachment, 0)
+	for cursor.Next(ctx) {
+		attachment := &Attachment{}
+		if err = cursor.Decode(attachment); err != nil {
+			return nil, err
+		}
+		if referenced[attachment.ID] {
+			continue
+		}
+		if !dryRun {
+			// a failed deletion is retried by the next sweep
+			if err = attachment.DeleteFiles(ctx); err != nil {
+				logrus.Warnf("delete files of attachment %d error: %v", attachment.ID, err)
+				continue
+			}
+			if _, err = db.DB().Collection("attachments").DeleteOne(ctx, bson.M{"_id": attachment.ID}); err != nil {
+				return nil, err
+			}
+			if err = releaseStorage(ctx, attachment.UID, attachment.Size); err != nil {
+				return nil, err
+			}
+		}
+		orphans = append(orphans, attachment)
+	}
+	return orphans, cursor.Err()
+}
+
+func referencedAttachmentIDs(ctx context.Context) (map[uint64]bool, error) {
+	referenced := make(map[uint64]bool)
+	err := eachDocument(ctx, "users", bson.M{"avatar_id": bson.M{"$gt": 0}}, bson.M{"avatar_id": 1}, func(raw bson.Raw) error {
+		user := &User{}
+		if err := bson.Unmarshal(raw, user); err != nil {
+			return err
+		}
+		referenced[user.AvatarID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = eachDocument(ctx, "linkpreviews", bson.M{"attachment_id": bson.M{"$gt": 0}}, bson.M{"attachment_id": 1}, func(raw bson.Raw) error {
+		preview := &LinkPreview{}
+		if err := bson.Unmarshal(raw, preview); err != nil {
+			return err
+		}
+		referenced[preview.AttachmentID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[primitive.ObjectID]bool)
+	err = eachDocument(ctx, "statuses", bson.M{"deleted_at": bson.M{"$ne": nil}}, bson.M{"_id": 1}, func(raw bson.Raw) error {
+		status := &Status{}
+		if err := bson.Unmarshal(raw, status); err != nil {
+			return err
+		}
+		deleted[status.ID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the meta is stored as json, so the statuses with attachments are decoded one by one
+	statusFilter := bson.M{"status_type": bson.M{"$in": attachmentStatusTypes}}
+	liveFilter := bson.M{"status_type": bson.M{"$in": attachmentStatusTypes}, "deleted_at": nil}
+	err = eachDocument(ctx, "statuses", liveFilter, bson.M{"status_type": 1, "meta": 1}, func(raw bson.Raw) error {
+		status := &Status{}
+		if err := bson.Unmarshal(raw, status); err != nil {
+			return err
+		}
+		return markMetaAttachments(referenced, status.StatusType, status.Meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = eachDocument(ctx, "status_revisions", statusFilter, bson.M{"status_id": 1, "status_type": 1, "meta": 1}, func(raw bson.Raw) error {
+		revision := &StatusRevision{}
+		if err := bson.Unmarshal(raw, revision); err != nil {
+			return err
+		}
+		if deleted[revision.StatusID] {
+			return nil
+		}
+		return markMetaAttachments(referenced, revision.StatusType, revision.Meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	posters := make(map[uint64]uint64)
+	err = eachDocument(ctx, "attachments", bson.M{"poster_id": bson.M{"$gt": 0}}, bson.M{"poster_id": 1}, func(raw bson.Raw) error {
+		attachment := &Attachment{}
+		if err := bson.Unmarshal(raw, attachment); err != nil {
+			return err
+		}
+		posters[attachment.ID] = attachment.PosterID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for id, posterID := range posters {
+		if referenced[id] {
+			referenced[posterID] = true
+		}
+	}
+	return referenced, nil
+}
+
+func markMetaAttachments(referenced map[uint64]bool, statusType enum.StatusType, metaJSON []byte) error {
+	if len(metaJSON) == 0 {
+		return nil
+	}
+	metaData, err := meta.BuildStatusMeta(statusType, metaJSON)
+	if err != nil {
+		return err
+	}
+	switch data := metaData.(type) {
+	case *meta.LinkMeta:
+		referenced[data.AttachmentID] = true
+	case *meta.ImageMeta:
+		for _, item := range data.Images {
+			referenced[item.AttachmentID] = true
+		}
+	case *meta.VideoMeta:
+		for _, item := range data.Videos {
+			referenced[item.AttachmentID] = true
+		}
+	}
+	return nil
+}
+
+func eachDocument(ctx context.Context, collection string, filter, projection bson.M, fn func(raw bson.Raw) error) error {
+	cursor, err := db.DB().Collection(collection).Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		if err = fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/video"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxFilenameLength = 64
+
+var (
+	// the accepted content types of the file types, and the extensions of the stored files
+	fileMimeTypes = map[enum.FileType]map[string]string{
+		enum.ImageFile: {
+			"image/jpeg": ".jpg",
+			"image/png":  ".png",
+			"image/gif":  ".gif",
+			"image/webp": ".webp",
+		},
+		enum.VideoFile: {
+			"video/mp4":       ".mp4",
+			"video/quicktime": ".mov",
+			"video/webm":      ".webm",
+		},
+	}
+	unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+func maxFileSize(tp enum.FileType) int64 {
+	if tp == enum.VideoFile {
+		return env.Envs.VideoMaxSize
+	}
+	return env.Envs.ImageMaxSize
+}
+
+// inspectFile reads the file once to check the size, hash the content and sniff the mime type.
+// A seekable file is rewound after, other files are buffered in memory.
+func (a *Attachment) inspectFile() error {
+	limit := maxFileSize(a.FileType)
+	reader := a.file
+	seeker, seekable := a.file.(io.Seeker)
+	buf := &bytes.Buffer{}
+	if !seekable {
+		reader = io.TeeReader(a.file, buf)
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:n]
+	hash := sha256.New()
+	hash.Write(head)
+	rest, err := io.Copy(hash, io.LimitReader(reader, limit-int64(n)+1))
+	if err != nil {
+		return err
+	}
+	a.Size = int64(n) + rest
+	if a.Size == 0 {
+		return codes.ErrInvalidArgument.New("empty file")
+	}
+	if a.Size > limit {
+		return codes.ErrPayloadTooLarge.Newf("%s is larger than %d bytes", a.FileType, limit)
+	}
+	a.Sha256 = hex.EncodeToString(hash.Sum(nil))
+	a.MimeType = http.DetectContentType(head)
+	if contentType := video.ContentType(head); a.FileType == enum.VideoFile && contentType != "" {
+		a.MimeType = contentType
+	}
+	ext, ok := fileMimeTypes[a.FileType][a.MimeType]
+	if !ok {
+		return codes.ErrUnsupportedMedia.Newf("%s is not a valid %s", a.MimeType, a.FileType)
+	}
+	a.Filename = sanitizeFilename(a.Filename, ext)
+	if seekable {
+		_, err = seeker.Seek(0, io.SeekStart)
+		return err
+	}
+	a.file = bytes.NewReader(buf.Bytes())
+	return nil
+}
+
+// sanitizeFilename keeps the safe characters of the base name and replaces the extension with the one of the content,
+// a random name is used when nothing is left
+func sanitizeFilename(filename, ext string) string {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	base = strings.Trim(unsafeFilenameChars.ReplaceAllString(base, "_"), "._")
+	if len(base) > maxFilenameLength {
+		base = base[:maxFilenameLength]
+	}
+	if base == "" {
+		random := make([]byte, 8)
+		_, _ = rand.Read(random)
+		base = hex.EncodeToString(random)
+	}
+	return base + ext
+}
+
+// findDuplicateAttachment finds the attachment of the same content uploaded by the user before
+func findDuplicateAttachment(ctx context.Context, uid uint64, tp enum.FileType, hash string) (*Attachment, error) {
+	attachment := &Attachment{}
+	err := db.ODM(ctx).Where(bson.M{"uid": uid, "file_type": tp, "sha256": hash}).First(attachment).Error
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// checkStorageQuota checks the quota before the upload is received, the storage is reserved when the file is stored
+func checkStorageQuota(ctx context.Context, uid uint64, size int64) error {
+	quota := env.Envs.UserStorageQuota
+	if uid == 0 || quota <= 0 {
+		return nil
+	}
+	user, err := FindUser(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if user.StorageUsed+size > quota {
+		return codes.ErrQuotaExceeded.Newf("storage quota of %d bytes exceeded", quota)
+	}
+	return nil
+}
+
+// reserveStorage adds the size to storage_used of the user only if it stays within the quota,
+// so concurrent uploads can not exceed the quota together. The counter is repaired by reconcile.
+func reserveStorage(ctx context.Context, uid uint64, size int64) error {
+	if uid == 0 {
+		return nil
+	}
+	quota := env.Envs.UserStorageQuota
+	filter := bson.M{"_id": uid}
+	if quota > 0 {
+		if size > quota {
+			return codes.ErrQuotaExceeded.Newf("storage quota of %d bytes exceeded", quota)
+		}
+		filter["storage_used"] = bson.M{"$not": bson.M{"$gt": quota - size}}
+	}
+	result, err := db.DB().Collection("users").UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"storage_used": size}})
+	if err != nil {
+		return err
+	}
+	if quota > 0 && result.MatchedCount == 0 {
+		return codes.ErrQuotaExceeded.Newf("storage quota of %d bytes exceeded", quota)
+	}
+	return nil
+}
+
+// releaseStorage takes the size back from the storage used by the user
+func releaseStorage(ctx context.Context, uid uint64, size int64) error {
+	if uid == 0 || size == 0 {
+		return nil
+	}
+	_, err := db.DB().Collection("users").UpdateOne(ctx, bson.M{"_id": uid}, bson.M{"$inc": bson.M{"storage_used": -size}})
+	return err
+}
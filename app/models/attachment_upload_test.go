@@ -0,0 +1,65 @@
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/codes"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":             "photo.jpg",
+		"../../etc/passwd":      "passwd.png",
+		"C:\\Users\\a\\cat.PHP": "cat.png",
+		"my photo (1).png":      "my_photo_1.png",
+	}
+	for filename, expected := range tests {
+		ext := ".png"
+		if strings.HasSuffix(expected, ".jpg") {
+			ext = ".jpg"
+		}
+		if sanitized := sanitizeFilename(filename, ext); sanitized != expected {
+			t.Errorf("sanitizeFilename(%q) = %q; expected %q", filename, sanitized, expected)
+		}
+	}
+	for _, filename := range []string{"???", ".htaccess", ""} {
+		if sanitized := sanitizeFilename(filename, ".gif"); len(sanitized) != 20 {
+			t.Errorf("sanitizeFilename(%q) = %q; expected a random filename", filename, sanitized)
+		}
+	}
+}
+
+func TestInspectFile(t *testing.T) {
+	data, err := ioutil.ReadFile("../../tests/test.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a reader which is not seekable is buffered
+	attachment := &Attachment{FileType: enum.ImageFile, Filename: "test.jpeg", file: ioutil.NopCloser(bytes.NewReader(data))}
+	if err = attachment.inspectFile(); err != nil {
+		t.Fatal(err)
+	}
+	if attachment.Size != int64(len(data)) || attachment.MimeType != "image/jpeg" || len(attachment.Sha256) != 64 {
+		t.Errorf("inspected attachment = %+v", attachment)
+	}
+	if attachment.Filename != "test.jpg" {
+		t.Errorf("filename = %q; expected %q", attachment.Filename, "test.jpg")
+	}
+	read, _ := ioutil.ReadAll(attachment.file)
+	if !bytes.Equal(read, data) {
+		t.Errorf("file is not rewound")
+	}
+
+	attachment = &Attachment{FileType: enum.VideoFile, Filename: "test.mp4", file: bytes.NewReader(data)}
+	if err = attachment.inspectFile(); !codes.ErrUnsupportedMedia.Equal(err) {
+		t.Errorf("image as video: err = %v; expected %v", err, codes.ErrUnsupportedMedia)
+	}
+	attachment = &Attachment{FileType: enum.ImageFile, Filename: "empty.jpg", file: bytes.NewReader(nil)}
+	if err = attachment.inspectFile(); !codes.ErrInvalidArgument.Equal(err) {
+		t.Errorf("empty file: err = %v; expected %v", err, codes.ErrInvalidArgument)
+	}
+}
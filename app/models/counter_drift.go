@@ -0,0 +1,182 @@
+package models
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CounterDrift is a denormalized counter which differs from the count of its source collection
+type CounterDrift struct {
+	Collection string
+	ID         interface{}
+	Key        string
+	Stored     int64
+	Actual     int64
+}
+
+// ReconcileUserCounters recomputes following_count and fans_count from the follows and storage_used
+// from the attachments, drifted counters are repaired unless dryRun
+func ReconcileUserCounters(ctx context.Context, dryRun bool) ([]*CounterDrift, error) {
+	following, err := groupCount(ctx, "follows", bson.M{}, "$from_uid")
+	if err != nil {
+		return nil, err
+	}
+	fans, err := groupCount(ctx, "follows", bson.M{}, "$to_uid")
+	if err != nil {
+		return nil, err
+	}
+	storageUsed, err := groupSum(ctx, "attachments", bson.M{"uid": bson.M{"$gt": 0}}, "$uid", "$size")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := db.DB().Collection("users").Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{
+		"following_count": 1,
+		"fans_count":      1,
+		"storage_used":    1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	drifts := make([]*CounterDrift, 0)
+	for cursor.Next(ctx) {
+		user := &User{}
+		if err = cursor.Decode(user); err != nil {
+			return nil, err
+		}
+		found := compareCounters("users", user.UID, map[string][2]int64{
+			"following_count": {user.FollowingCount, following[int64(user.UID)]},
+			"fans_count":      {user.FansCount, fans[int64(user.UID)]},
+			"storage_used":    {user.StorageUsed, storageUsed[int64(user.UID)]},
+		})
+		if err = repairCounters(ctx, dryRun, found); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, found...)
+	}
+	return drifts, cursor.Err()
+}
+
+// ReconcileStatusCounters recomputes likes_count, comments_count and forwards_count of the statuses,
+// the comments_count of a top level comment includes the replies in its thread
+func ReconcileStatusCounters(ctx context.Context, dryRun bool) ([]*CounterDrift, error) {
+	likes, err := groupCount(ctx, "likes", bson.M{"deleted_at": nil}, "$target_id")
+	if err != nil {
+		return nil, err
+	}
+	comments, err := groupCount(ctx, "statuses", bson.M{"from_type": enum.FromComment, "deleted_at": nil}, "$parent_id")
+	if err != nil {
+		return nil, err
+	}
+	replies, err := groupCount(ctx, "statuses", bson.M{
+		"from_type":  enum.FromComment,
+		"deleted_at": nil,
+		"root_id":    bson.M{"$exists": true},
+		"$expr":      bson.M{"$ne": bson.A{"$root_id", "$parent_id"}},
+	}, "$root_id")
+	if err != nil {
+		return nil, err
+	}
+	forwards, err := groupCount(ctx, "statuses", bson.M{"from_type": enum.FromForward, "deleted_at": nil}, "$parent_id")
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := db.DB().Collection("statuses").Find(ctx, bson.M{"deleted_at": nil}, options.Find().SetProjection(bson.M{
+		"likes_count":    1,
+		"comments_count": 1,
+		"forwards_count": 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	drifts := make([]*CounterDrift, 0)
+	for cursor.Next(ctx) {
+		status := &Status{}
+		if err = cursor.Decode(status); err != nil {
+			return nil, err
+		}
+		found := compareCounters("statuses", status.ID, map[string][2]int64{
+			"likes_count":    {int64(status.LikesCount), likes[status.ID]},
+			"comments_count": {int64(status.CommentsCount), comments[status.ID] + replies[status.ID]},
+			"forwards_count": {int64(status.ForwardsCount), forwards[status.ID]},
+		})
+		if err = repairCounters(ctx, dryRun, found); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, found...)
+	}
+	return drifts, cursor.Err()
+}
+
+// groupCount counts the matched documents of the collection grouped by the field,
+// integer ids are decoded as int64 and object ids as primitive.ObjectID
+func groupCount(ctx context.Context, collection string, match bson.M, field string) (map[interface{}]int64, error) {
+	return groupSum(ctx, collection, match, field, 1)
+}
+
+// groupSum sums the value of the matched documents of the collection grouped by the field
+func groupSum(ctx context.Context, collection string, match bson.M, field string, value interface{}) (map[interface{}]int64, error) {
+	cursor, err := db.DB().Collection(collection).Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$group": bson.M{"_id": field, "count": bson.M{"$sum": value}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	counts := make(map[interface{}]int64)
+	for cursor.Next(ctx) {
+		result := struct {
+			ID    interface{} `bson:"_id"`
+			Count int64       `bson:"count"`
+		}{}
+		if err = cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		if result.ID != nil {
+			counts[result.ID] = result.Count
+		}
+	}
+	return counts, cursor.Err()
+}
+
+// compareCounters takes the stored and actual value of each counter
+func compareCounters(collection string, id interface{}, counters map[string][2]int64) []*CounterDrift {
+	drifts := make([]*CounterDrift, 0)
+	for key, values := range counters {
+		if values[0] != values[1] {
+			drifts = append(drifts, &CounterDrift{
+				Collection: collection,
+				ID:         id,
+				Key:        key,
+				Stored:     values[0],
+				Actual:     values[1],
+			})
+		}
+	}
+	return drifts
+}
+
+// repairCounters only sets a counter still holding the stored value, a counter changed by a
+// concurrent request is left to the next run
+func repairCounters(ctx context.Context, dryRun bool, drifts []*CounterDrift) error {
+	if dryRun {
+		return nil
+	}
+	for _, drift := range drifts {
+		filter := bson.M{"_id": drift.ID, drift.Key: drift.Stored}
+		if drift.Stored == 0 {
+			filter[drift.Key] = bson.M{"$in": bson.A{0, nil}}
+		}
+		_, err := db.DB().Collection(drift.Collection).UpdateOne(ctx, filter, bson.M{"$set": bson.M{drift.Key: drift.Actual}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
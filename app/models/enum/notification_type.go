@@ -0,0 +1,58 @@
+package enum
+
+import "github.com/mises-id/sns/lib/codes"
+
+type NotificationType uint8
+
+const (
+	NotifyLike NotificationType = iota
+	NotifyComment
+	NotifyForward
+	NotifyFollow
+	NotifyReply
+	NotifyMention
+	NotifyFollowRequest
+	NotifyFollowApproved
+)
+
+var (
+	notificationTypeMap = map[NotificationType]string{
+		NotifyLike:           "like",
+		NotifyComment:        "comment",
+		NotifyForward:        "forward",
+		NotifyFollow:         "follow",
+		NotifyReply:          "reply",
+		NotifyMention:        "mention",
+		NotifyFollowRequest:  "follow_request",
+		NotifyFollowApproved: "follow_approved",
+	}
+	notificationTypeStringMap = map[string]NotificationType{}
+	// notifications of these types are merged into one entry until they are read
+	groupedNotificationTypes = map[NotificationType]bool{
+		NotifyLike:          true,
+		NotifyFollow:        true,
+		NotifyFollowRequest: true,
+	}
+)
+
+func init() {
+	for key, val := range notificationTypeMap {
+		notificationTypeStringMap[val] = key
+	}
+}
+
+func (tp NotificationType) String() string {
+	return notificationTypeMap[tp]
+}
+
+func (tp NotificationType) Grouped() bool {
+	return groupedNotificationTypes[tp]
+}
+
+func NotificationTypeFromString(tp string) (NotificationType, error) {
+	notificationType, ok := notificationTypeStringMap[tp]
+	if !ok {
+		return NotifyLike, codes.ErrInvalidArgument.Newf("invalid notification type: %s", tp)
+	}
+	return notificationType, nil
+}
@@ -8,6 +8,8 @@ const (
 	Following RelationType = iota + 1
 	Fan
 	Friend
+	Block
+	Mute
 )
 
 var (
@@ -15,6 +17,8 @@ var (
 		Following: "following",
 		Fan:       "fan",
 		Friend:    "friend",
+		Block:     "block",
+		Mute:      "mute",
 	}
 	relationTypeStringMap = map[string]RelationType{}
 )
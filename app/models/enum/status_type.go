@@ -7,12 +7,16 @@ type StatusType uint8
 const (
 	TextStatus StatusType = iota
 	LinkStatus
+	ImageStatus
+	VideoStatus
 )
 
 var (
 	statusTypeMap = map[StatusType]string{
-		TextStatus: "text",
-		LinkStatus: "link",
+		TextStatus:  "text",
+		LinkStatus:  "link",
+		ImageStatus: "image",
+		VideoStatus: "video",
 	}
 	statusTypeStringMap = map[string]StatusType{}
 )
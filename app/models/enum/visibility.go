@@ -0,0 +1,43 @@
+package enum
+
+import "github.com/mises-id/sns/lib/codes"
+
+// Visibility is the audience of a status, a larger value is more restrictive
+type Visibility uint8
+
+const (
+	VisibilityPublic Visibility = iota
+	VisibilityFollowers
+	VisibilityFriends
+	VisibilityOnlyMe
+)
+
+var (
+	visibilityMap = map[Visibility]string{
+		VisibilityPublic:    "public",
+		VisibilityFollowers: "followers",
+		VisibilityFriends:   "friends",
+		VisibilityOnlyMe:    "only_me",
+	}
+	visibilityStringMap = map[string]Visibility{}
+	// NonPublicVisibilities is used to query public statuses, statuses created before visibility have no such field
+	NonPublicVisibilities = []Visibility{VisibilityFollowers, VisibilityFriends, VisibilityOnlyMe}
+)
+
+func init() {
+	for key, val := range visibilityMap {
+		visibilityStringMap[val] = key
+	}
+}
+
+func VisibilityFromString(visibility string) (Visibility, error) {
+	result, ok := visibilityStringMap[visibility]
+	if !ok {
+		return VisibilityPublic, codes.ErrInvalidArgument.Newf("invalid visibility: %s", visibility)
+	}
+	return result, nil
+}
+
+func (visibility Visibility) String() string {
+	return visibilityMap[visibility]
+}
@@ -29,15 +29,23 @@ func (a *Follow) BeforeCreate(ctx context.Context) error {
 	return nil
 }
 
-func ListFollow(ctx context.Context, uid uint64, relationType enum.RelationType, pageParams *pagination.QuickPagination) ([]*Follow, pagination.Pagination, error) {
+func ListFollow(ctx context.Context, uid uint64, relationType enum.RelationType, excludeUIDs []uint64, pageParams *pagination.QuickPagination) ([]*Follow, pagination.Pagination, error) {
 	follows := make([]*Follow, 0)
 	chain := db.ODM(ctx)
 	if relationType == enum.Fan {
 		chain = chain.Where(bson.M{"to_uid": uid})
-	} else if relationType == enum.Following {
-		chain = chain.Where(bson.M{"from_uid": uid})
+		if len(excludeUIDs) > 0 {
+			chain = chain.Where(bson.M{"from_uid": bson.M{"$nin": excludeUIDs}})
+		}
 	} else {
-		chain = chain.Where(bson.M{"from_uid": uid, "is_friend": true})
+		if relationType == enum.Following {
+			chain = chain.Where(bson.M{"from_uid": uid})
+		} else {
+			chain = chain.Where(bson.M{"from_uid": uid, "is_friend": true})
+		}
+		if len(excludeUIDs) > 0 {
+			chain = chain.Where(bson.M{"to_uid": bson.M{"$nin": excludeUIDs}})
+		}
 	}
 	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
 	page, err := paginator.Paginate(&follows)
@@ -83,9 +91,13 @@ func GetFollow(ctx context.Context, fromUID, toUID uint64) (*Follow, error) {
 	return follow, result.Decode(follow)
 }
 
-func DeleteFollow(ctx context.Context, fromUID, toUID uint64) error {
-	_, err := db.DB().Collection("follows").DeleteOne(ctx, bson.M{"from_uid": fromUID, "to_uid": toUID})
-	return err
+// DeleteFollow reports whether the follow existed
+func DeleteFollow(ctx context.Context, fromUID, toUID uint64) (bool, error) {
+	result, err := db.DB().Collection("follows").DeleteOne(ctx, bson.M{"from_uid": fromUID, "to_uid": toUID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
 }
 
 func ListFollowingUserIDs(ctx context.Context, uid uint64) ([]uint64, error) {
@@ -155,6 +167,24 @@ func BatchSetFolloweState(ctx context.Context, users ...*User) error {
 	return nil
 }
 
+// ListFriendUIDs returns the users followed by the user who follow the user back
+func ListFriendUIDs(ctx context.Context, uid uint64) ([]uint64, error) {
+	follows := make([]*Follow, 0)
+	err := db.ODM(ctx).Where(bson.M{
+		"from_uid":   uid,
+		"is_friend":  true,
+		"deleted_at": nil,
+	}).Find(&follows).Error
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]uint64, len(follows))
+	for i, follow := range follows {
+		uids[i] = follow.ToUID
+	}
+	return uids, nil
+}
+
 func GetFollowMap(ctx context.Context, fromUID uint64, toUserIDs []uint64) (map[uint64]*Follow, error) {
 	follows := make([]*Follow, 0)
 	err := db.ODM(ctx).Where(bson.M{
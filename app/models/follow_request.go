@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FollowRequest is a pending follow of a private user
+type FollowRequest struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	FromUID   uint64             `bson:"from_uid,omitempty"`
+	ToUID     uint64             `bson:"to_uid,omitempty"`
+	CreatedAt time.Time          `bson:"created_at,omitempty"`
+	FromUser  *User              `bson:"-"`
+	ToUser    *User              `bson:"-"`
+}
+
+// CreateFollowRequest is idempotent, the existing request is returned
+func CreateFollowRequest(ctx context.Context, fromUID, toUID uint64) (*FollowRequest, error) {
+	request := &FollowRequest{}
+	err := db.DB().Collection("followrequests").FindOneAndUpdate(ctx, bson.M{
+		"from_uid": fromUID,
+		"to_uid":   toUID,
+	}, bson.M{
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(request)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func GetFollowRequest(ctx context.Context, fromUID, toUID uint64) (*FollowRequest, error) {
+	request := &FollowRequest{}
+	err := db.DB().Collection("followrequests").FindOne(ctx, bson.M{
+		"from_uid": fromUID,
+		"to_uid":   toUID,
+	}).Decode(request)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// DeleteFollowRequest reports whether a request is deleted
+func DeleteFollowRequest(ctx context.Context, fromUID, toUID uint64) (bool, error) {
+	result, err := db.DB().Collection("followrequests").DeleteOne(ctx, bson.M{
+		"from_uid": fromUID,
+		"to_uid":   toUID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ListFollowRequest lists the requests received by the user
+func ListFollowRequest(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*FollowRequest, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	requests := make([]*FollowRequest, 0)
+	chain := db.ODM(ctx).Where(bson.M{"to_uid": uid})
+	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
+	page, err := paginator.Paginate(&requests)
+	if err != nil {
+		return nil, nil, err
+	}
+	return requests, page, preloadFollowRequestUser(ctx, requests)
+}
+
+func preloadFollowRequestUser(ctx context.Context, requests []*FollowRequest) error {
+	userIDs := make([]uint64, 0)
+	for _, request := range requests {
+		userIDs = append(userIDs, request.FromUID, request.ToUID)
+	}
+	users := make([]*User, 0)
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": userIDs}}).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	if err = PreloadUserAvatar(ctx, users...); err != nil {
+		return err
+	}
+	userMap := make(map[uint64]*User)
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+	for _, request := range requests {
+		request.FromUser = userMap[request.FromUID]
+		request.ToUser = userMap[request.ToUID]
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/mises-id/sns/config/env"
 	"github.com/mises-id/sns/lib/db"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -29,6 +30,10 @@ func EnsureIndex() {
 				Unique: &trueBool,
 			},
 		},
+		{
+			Keys:    bson.M{"username": "text"},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
 	}, opts)
 	if err != nil {
 		logrus.Debug(err)
@@ -59,6 +64,86 @@ func EnsureIndex() {
 		{
 			Keys: bson.M{"deleted_at": 1},
 		},
+		{
+			Keys: bson.M{"parent_id": 1},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "mentions.uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "_id", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "tags", Value: bsonx.Int32(1),
+			}, {
+				Key: "_id", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "content", Value: bsonx.String("text"),
+			}, {
+				Key: "link_title", Value: bsonx.String("text")},
+			},
+			// no stemming or stop words, statuses are written in many languages
+			Options: options.Index().SetDefaultLanguage("none").SetWeights(bson.M{"content": 1, "link_title": 2}),
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("timelines").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "status_id", Value: bsonx.Int32(-1)},
+			},
+			Options: &options.IndexOptions{
+				Unique: &trueBool,
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "author_uid", Value: bsonx.Int32(1)},
+			},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("notifications").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "sort_id", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "read_at", Value: bsonx.Int32(1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "group_key", Value: bsonx.Int32(1)},
+			},
+			Options: &options.IndexOptions{
+				Unique:                  &trueBool,
+				PartialFilterExpression: bson.M{"group_key": bson.M{"$exists": true}},
+			},
+		},
 	}, opts)
 	if err != nil {
 		logrus.Debug(err)
@@ -83,4 +168,141 @@ func EnsureIndex() {
 	if err != nil {
 		logrus.Debug(err)
 	}
+
+	_, err = db.DB().Collection("linkpreviews").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.M{"url": 1},
+			Options: &options.IndexOptions{
+				Unique: &trueBool,
+			},
+		},
+		{
+			Keys:    bson.M{"created_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(env.Envs.LinkPreviewTTL.Seconds())),
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("tagbuckets").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "tag", Value: bsonx.Int32(1),
+			}, {
+				Key: "hour", Value: bsonx.Int32(1)},
+			},
+			Options: &options.IndexOptions{
+				Unique: &trueBool,
+			},
+		},
+		{
+			Keys:    bson.M{"hour": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(env.Envs.TagBucketRetention.Seconds())),
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("attachments").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "sha256", Value: bsonx.Int32(1)},
+			},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("upload_sessions").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.M{"expires_at": 1},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("sessions").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bson.M{"uid": 1},
+		},
+		{
+			Keys: bson.M{"refresh_token_hash": 1},
+		},
+		{
+			Keys: bson.M{"previous_token_hash": 1},
+			Options: &options.IndexOptions{
+				Sparse: &trueBool,
+			},
+		},
+		{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("status_revisions").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "status_id", Value: bsonx.Int32(1),
+			}, {
+				Key: "_id", Value: bsonx.Int32(-1)},
+			},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("followrequests").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "from_uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "to_uid", Value: bsonx.Int32(1)},
+			},
+			Options: &options.IndexOptions{
+				Unique: &trueBool,
+			},
+		},
+		{
+			Keys: bson.M{"to_uid": 1},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
+
+	_, err = db.DB().Collection("userrelations").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{
+				Key: "from_uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "to_uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "relation_type", Value: bsonx.Int32(1)},
+			},
+			Options: &options.IndexOptions{
+				Unique: &trueBool,
+			},
+		},
+		{
+			Keys: bsonx.Doc{{
+				Key: "to_uid", Value: bsonx.Int32(1),
+			}, {
+				Key: "relation_type", Value: bsonx.Int32(1)},
+			},
+		},
+	}, opts)
+	if err != nil {
+		logrus.Debug(err)
+	}
 }
@@ -6,8 +6,10 @@ import (
 
 	"github.com/mises-id/sns/app/models/enum"
 	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Like struct {
@@ -18,6 +20,14 @@ type Like struct {
 	DeletedAt  time.Time           `bson:"deleted_at,omitempty"`
 	CreatedAt  time.Time           `bson:"created_at,omitempty"`
 	UpdatedAt  time.Time           `bson:"updated_at,omitempty"`
+	User       *User               `bson:"-"`
+}
+
+type ListLikeParams struct {
+	UID         uint64
+	TargetID    primitive.ObjectID
+	TargetTypes []enum.LikeTargetType
+	PageParams  *pagination.PageQuickParams
 }
 
 func CreateLike(ctx context.Context, uid uint64, targetID primitive.ObjectID, targetType enum.LikeTargetType) (*Like, error) {
@@ -36,23 +46,67 @@ func DeleteLike(ctx context.Context, id primitive.ObjectID) error {
 	}, bson.M{"$set": bson.M{"deleted_at": time.Now()}}).Err()
 }
 
+// FindLike finds the like of the target, the comment likes made before they had their own type are
+// stored as status likes, so they are matched too until the comment-likes migration has run
 func FindLike(ctx context.Context, uid uint64, targetID primitive.ObjectID, targetType enum.LikeTargetType) (*Like, error) {
 	like := &Like{}
+	targetTypes := []enum.LikeTargetType{targetType}
+	if targetType == enum.LikeComment {
+		targetTypes = append(targetTypes, enum.LikeStatus)
+	}
 	err := db.ODM(ctx).Where(bson.M{
 		"uid":         uid,
 		"target_id":   targetID,
-		"target_type": targetType,
+		"target_type": bson.M{"$in": targetTypes},
 		"deleted_at":  nil,
 	}).First(like).Error
 	return like, err
 }
 
+// RetypeCommentLikes retypes the likes of comments stored as status likes to comment likes.
+// A comment liked again as a comment keeps the new like only, the count of the old one is taken back.
+func RetypeCommentLikes(ctx context.Context) (int, error) {
+	commentIDs := make(map[primitive.ObjectID]bool)
+	err := eachDocument(ctx, "statuses", bson.M{"from_type": enum.FromComment}, bson.M{"_id": 1}, func(raw bson.Raw) error {
+		commentIDs[raw.Lookup("_id").ObjectID()] = true
+		return nil
+	})
+	if err != nil || len(commentIDs) == 0 {
+		return 0, err
+	}
+	count := 0
+	err = eachDocument(ctx, "likes", bson.M{"target_type": enum.LikeStatus}, bson.M{"_id": 1, "target_id": 1, "deleted_at": 1}, func(raw bson.Raw) error {
+		like := &Like{}
+		if err := bson.Unmarshal(raw, like); err != nil {
+			return err
+		}
+		if !commentIDs[like.TargetID] {
+			return nil
+		}
+		count++
+		_, err := db.DB().Collection("likes").UpdateOne(ctx, bson.M{"_id": like.ID},
+			bson.M{"$set": bson.M{"target_type": enum.LikeComment}})
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if !like.DeletedAt.IsZero() {
+			_, err = db.DB().Collection("likes").DeleteOne(ctx, bson.M{"_id": like.ID})
+			return err
+		}
+		if err = DeleteLike(ctx, like.ID); err != nil {
+			return err
+		}
+		return (&Status{ID: like.TargetID}).IncStatusCounter(ctx, "likes_count", -1)
+	})
+	return count, err
+}
+
 func GetStatusLikeMap(ctx context.Context, uid uint64, statusIDs []primitive.ObjectID) (map[primitive.ObjectID]*Like, error) {
 	likes := make([]*Like, 0)
 	err := db.ODM(ctx).Where(bson.M{
 		"uid":         uid,
 		"target_id":   bson.M{"$in": statusIDs},
-		"target_type": enum.LikeStatus,
+		"target_type": bson.M{"$in": []enum.LikeTargetType{enum.LikeStatus, enum.LikeComment}},
 		"deleted_at":  nil,
 	}).Find(&likes).Error
 	if err != nil {
@@ -64,3 +118,52 @@ func GetStatusLikeMap(ctx context.Context, uid uint64, statusIDs []primitive.Obj
 	}
 	return likeMap, nil
 }
+
+func ListLike(ctx context.Context, params *ListLikeParams) ([]*Like, pagination.Pagination, error) {
+	if params.PageParams == nil {
+		params.PageParams = pagination.DefaultQuickParams()
+	}
+	likes := make([]*Like, 0)
+	chain := db.ODM(ctx).Where(bson.M{"deleted_at": nil})
+	if params.UID != 0 {
+		chain = chain.Where(bson.M{"uid": params.UID})
+	}
+	if !params.TargetID.IsZero() {
+		chain = chain.Where(bson.M{"target_id": params.TargetID})
+	}
+	if params.TargetTypes != nil {
+		chain = chain.Where(bson.M{"target_type": bson.M{"$in": params.TargetTypes}})
+	}
+	paginator := pagination.NewQuickPaginator(params.PageParams.Limit, params.PageParams.NextID, chain)
+	page, err := paginator.Paginate(&likes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return likes, page, preloadLikeUser(ctx, likes...)
+}
+
+func preloadLikeUser(ctx context.Context, likes ...*Like) error {
+	userIds := make([]uint64, 0)
+	for _, like := range likes {
+		userIds = append(userIds, like.UID)
+	}
+	users := make([]*User, 0)
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": userIds}}).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	if err = PreloadUserAvatar(ctx, users...); err != nil {
+		return err
+	}
+	if err = BatchSetFolloweState(ctx, users...); err != nil {
+		return err
+	}
+	userMap := make(map[uint64]*User)
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+	for _, like := range likes {
+		like.User = userMap[like.UID]
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LinkPreview caches the unfurled metadata of a link, expired by the ttl index of created_at.
+// A link failed to unfurl is cached too, it is fetched again after the retry interval.
+type LinkPreview struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	URL          string             `bson:"url"`
+	Title        string             `bson:"title,omitempty"`
+	Description  string             `bson:"description,omitempty"`
+	Host         string             `bson:"host,omitempty"`
+	AttachmentID uint64             `bson:"attachment_id,omitempty"`
+	Failed       bool               `bson:"failed,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+}
+
+// FindLinkPreview returns nil if the link is not cached or the failure of it is due to retry
+func FindLinkPreview(ctx context.Context, url string) (*LinkPreview, error) {
+	preview := &LinkPreview{}
+	err := db.ODM(ctx).First(preview, bson.M{"url": url}).Error
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if preview.Failed && time.Since(preview.CreatedAt) > env.Envs.LinkPreviewRetry {
+		return nil, nil
+	}
+	return preview, nil
+}
+
+func SaveLinkPreview(ctx context.Context, preview *LinkPreview) error {
+	preview.CreatedAt = time.Now()
+	_, err := db.DB().Collection("linkpreviews").UpdateOne(ctx, bson.M{"url": preview.URL}, bson.M{
+		"$set": bson.M{
+			"title":         preview.Title,
+			"description":   preview.Description,
+			"host":          preview.Host,
+			"attachment_id": preview.AttachmentID,
+			"failed":        preview.Failed,
+			"created_at":    preview.CreatedAt,
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
@@ -2,6 +2,7 @@ package meta
 
 type LinkMeta struct {
 	Title         string `json:"title"`
+	Description   string `json:"description,omitempty"`
 	Host          string `json:"host"`
 	AttachmentID  uint64 `json:"attachment_id"`
 	Link          string `json:"link"`
@@ -0,0 +1,29 @@
+package meta
+
+// MediaItem is an attachment of a image or video status, the order of items is kept
+type MediaItem struct {
+	AttachmentID  uint64 `json:"attachment_id"`
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	Alt           string `json:"alt,omitempty"`
+	AttachmentURL string `json:"-"`
+	// the urls of the sized variants, only images have them
+	SmallURL  string `json:"-"`
+	MediumURL string `json:"-"`
+	LargeURL  string `json:"-"`
+	// the poster url and duration in seconds of a video
+	PosterURL string  `json:"-"`
+	Duration  float64 `json:"-"`
+}
+
+type ImageMeta struct {
+	Images []*MediaItem `json:"images"`
+}
+
+func (*ImageMeta) isMetaData() {}
+
+type VideoMeta struct {
+	Videos []*MediaItem `json:"videos"`
+}
+
+func (*VideoMeta) isMetaData() {}
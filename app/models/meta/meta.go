@@ -23,6 +23,10 @@ func BuildStatusMeta(statusType enum.StatusType, metaData json.RawMessage) (Meta
 		data = &TextMeta{}
 	case enum.LinkStatus:
 		data = &LinkMeta{}
+	case enum.ImageStatus:
+		data = &ImageMeta{}
+	case enum.VideoStatus:
+		data = &VideoMeta{}
 	}
 	return data, json.Unmarshal(metaData, data)
 }
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// how many latest actors are kept in a grouped notification
+	maxNotificationActors = 10
+	// how many latest actors are kept to count each actor once, an earlier actor acting again is counted again
+	maxNotificationActorSet = 1000
+)
+
+type Notification struct {
+	ID         primitive.ObjectID    `bson:"_id,omitempty"`
+	UID        uint64                `bson:"uid,omitempty"`
+	NotifyType enum.NotificationType `bson:"notify_type"`
+	GroupKey   string                `bson:"group_key,omitempty"`
+	ActorUIDs  []uint64              `bson:"actor_uids,omitempty"`
+	// ActorUIDSet has the latest actors up to maxNotificationActorSet to count each actor once,
+	// ActorUIDs only has the latest ones shown
+	ActorUIDSet []uint64           `bson:"actor_uid_set,omitempty"`
+	ActorsCount uint64             `bson:"actors_count,omitempty"`
+	StatusID    primitive.ObjectID `bson:"status_id"`
+	SourceID    primitive.ObjectID `bson:"source_id,omitempty"`
+	SortID      primitive.ObjectID `bson:"sort_id"`
+	ReadAt      *time.Time         `bson:"read_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at,omitempty"`
+	UpdatedAt   time.Time          `bson:"updated_at,omitempty"`
+	Actors      []*User            `bson:"-"`
+	Status      *Status            `bson:"-"`
+	Source      *Status            `bson:"-"`
+}
+
+type CreateNotificationParams struct {
+	UID        uint64
+	ActorUID   uint64
+	NotifyType enum.NotificationType
+	// StatusID is the status of the receiver which is acted on
+	StatusID primitive.ObjectID
+	// SourceID is the comment or forward status created by the actor
+	SourceID primitive.ObjectID
+}
+
+func (n *Notification) BeforeCreate(ctx context.Context) error {
+	n.SortID = primitive.NewObjectID()
+	n.CreatedAt = time.Now()
+	n.UpdatedAt = time.Now()
+	return nil
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+func CreateNotification(ctx context.Context, params *CreateNotificationParams) error {
+	if params.NotifyType.Grouped() {
+		return upsertGroupedNotification(ctx, params)
+	}
+	notification := &Notification{
+		UID:         params.UID,
+		NotifyType:  params.NotifyType,
+		ActorUIDs:   []uint64{params.ActorUID},
+		ActorsCount: 1,
+		StatusID:    params.StatusID,
+		SourceID:    params.SourceID,
+	}
+	if err := notification.BeforeCreate(ctx); err != nil {
+		return err
+	}
+	return db.ODM(ctx).Create(notification).Error
+}
+
+// upsertGroupedNotification merges the actor into the unread notification of the same group,
+// and moves the notification to the top of the list. An actor already in the group is counted once,
+// the group is matched only without the actor so the check and the merge are one atomic update.
+func upsertGroupedNotification(ctx context.Context, params *CreateNotificationParams) error {
+	groupKey := params.NotifyType.String() + ":" + params.StatusID.Hex()
+	now := time.Now()
+	upsert := func() error {
+		return db.DB().Collection("notifications").FindOneAndUpdate(ctx, bson.M{
+			"uid":           params.UID,
+			"group_key":     groupKey,
+			"read_at":       nil,
+			"actor_uids":    bson.M{"$ne": params.ActorUID},
+			"actor_uid_set": bson.M{"$ne": params.ActorUID},
+		}, bson.M{
+			"$set": bson.M{
+				"sort_id":    primitive.NewObjectID(),
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{
+				"notify_type": params.NotifyType,
+				"status_id":   params.StatusID,
+				"created_at":  now,
+			},
+			// the actor is not in the set as matched, so the capped push adds it once
+			"$push": bson.M{
+				"actor_uids": bson.M{
+					"$each":     []uint64{params.ActorUID},
+					"$position": 0,
+					"$slice":    maxNotificationActors,
+				},
+				"actor_uid_set": bson.M{
+					"$each":  []uint64{params.ActorUID},
+					"$slice": -maxNotificationActorSet,
+				},
+			},
+			"$inc": bson.M{"actors_count": 1},
+		}, options.FindOneAndUpdate().SetUpsert(true).SetProjection(bson.M{"_id": 1})).Err()
+	}
+	// the insert conflicts on the unique group index when the group already has the actor,
+	// or when a concurrent upsert created the group first, so retry once to tell them apart
+	err := upsert()
+	if mongo.IsDuplicateKeyError(err) {
+		if err = upsert(); mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+// ListNotification pages the notifications by sort_id, the latest first. Each new actor gives a grouped notification
+// a new sort_id, so a group bumped while the user is paging moves before the cursor: it is never listed twice,
+// but a group not listed yet is skipped by the following pages and only shows up again from the first page.
+func ListNotification(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*Notification, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	notifications := make([]*Notification, 0)
+	chain := db.ODM(ctx).Where(bson.M{"uid": uid})
+	paginator := pagination.NewQuickPaginatorWithKey(pageParams.Limit, pageParams.NextID, "sort_id", chain)
+	page, err := paginator.Paginate(&notifications)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = preloadNotificationActors(ctx, notifications...); err != nil {
+		return nil, nil, err
+	}
+	return notifications, page, preloadNotificationStatus(ctx, notifications...)
+}
+
+// ReadNotifications marks the notifications as read, all unread notifications will be marked if ids is empty
+func ReadNotifications(ctx context.Context, uid uint64, ids []primitive.ObjectID) error {
+	filter := bson.M{
+		"uid":     uid,
+		"read_at": nil,
+	}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	_, err := db.DB().Collection("notifications").UpdateMany(ctx, filter, bson.M{
+		"$set":   bson.M{"read_at": time.Now()},
+		"$unset": bson.M{"group_key": ""},
+	})
+	return err
+}
+
+func CountUnreadNotification(ctx context.Context, uid uint64) (int64, error) {
+	return db.DB().Collection("notifications").CountDocuments(ctx, bson.M{
+		"uid":     uid,
+		"read_at": nil,
+	})
+}
+
+func preloadNotificationActors(ctx context.Context, notifications ...*Notification) error {
+	userIDs := make([]uint64, 0)
+	for _, notification := range notifications {
+		userIDs = append(userIDs, notification.ActorUIDs...)
+	}
+	users := make([]*User, 0)
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": userIDs}}).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	if err = PreloadUserAvatar(ctx, users...); err != nil {
+		return err
+	}
+	if err = BatchSetFolloweState(ctx, users...); err != nil {
+		return err
+	}
+	userMap := make(map[uint64]*User)
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+	for _, notification := range notifications {
+		notification.Actors = make([]*User, 0, len(notification.ActorUIDs))
+		for _, uid := range notification.ActorUIDs {
+			if user := userMap[uid]; user != nil {
+				notification.Actors = append(notification.Actors, user)
+			}
+		}
+	}
+	return nil
+}
+
+func preloadNotificationStatus(ctx context.Context, notifications ...*Notification) error {
+	statusIDs := make([]primitive.ObjectID, 0)
+	for _, notification := range notifications {
+		if !notification.StatusID.IsZero() {
+			statusIDs = append(statusIDs, notification.StatusID)
+		}
+		if !notification.SourceID.IsZero() {
+			statusIDs = append(statusIDs, notification.SourceID)
+		}
+	}
+	statuses, err := ListStatusByIDs(ctx, statusIDs...)
+	if err != nil {
+		return err
+	}
+	statusMap := make(map[primitive.ObjectID]*Status)
+	for _, status := range statuses {
+		statusMap[status.ID] = status
+	}
+	for _, notification := range notifications {
+		notification.Status = statusMap[notification.StatusID]
+		notification.Source = statusMap[notification.SourceID]
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	textScore     = bson.M{"$meta": "textScore"}
+	textScoreSort = bson.D{{Key: "score", Value: textScore}, {Key: "_id", Value: -1}}
+)
+
+// SearchStatus searches public posts and forwards by the content and the link title, most relevant first
+func SearchStatus(ctx context.Context, query string, excludeUIDs []uint64, pageParams *pagination.TraditionalParams) ([]*Status, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultTraditionalParams()
+	}
+	statuses := make([]*Status, 0)
+	filter := bson.M{
+		"$text":      bson.M{"$search": query},
+		"from_type":  bson.M{"$in": []enum.FromType{enum.FromPost, enum.FromForward}},
+		"visibility": bson.M{"$nin": enum.NonPublicVisibilities},
+		"deleted_at": nil,
+	}
+	if len(excludeUIDs) > 0 {
+		filter["uid"] = bson.M{"$nin": excludeUIDs}
+	}
+	chain := db.ODM(ctx).Where(filter).Projection(bson.M{"score": textScore})
+	paginator := pagination.NewTraditionalPaginatorWithSort(pageParams.PageNum, pageParams.PageSize, textScoreSort, chain)
+	page, err := paginator.Paginate(&statuses)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = preloadRelatedStatus(ctx, statuses...); err != nil {
+		return nil, nil, err
+	}
+	if err = preloadAttachment(ctx, statuses...); err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, preloadStatusUser(ctx, statuses...)
+}
+
+// SearchUser searches users by username, most relevant first
+func SearchUser(ctx context.Context, query string, excludeUIDs []uint64, pageParams *pagination.TraditionalParams) ([]*User, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultTraditionalParams()
+	}
+	users := make([]*User, 0)
+	filter := bson.M{"$text": bson.M{"$search": query}}
+	if len(excludeUIDs) > 0 {
+		filter["_id"] = bson.M{"$nin": excludeUIDs}
+	}
+	chain := db.ODM(ctx).Where(filter).Projection(bson.M{"score": textScore})
+	paginator := pagination.NewTraditionalPaginatorWithSort(pageParams.PageNum, pageParams.PageSize, textScoreSort, chain)
+	page, err := paginator.Paginate(&users)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = PreloadUserAvatar(ctx, users...); err != nil {
+		return nil, nil, err
+	}
+	return users, page, BatchSetFolloweState(ctx, users...)
+}
+
+// BackfillLinkTitles copies the title out of the meta of the link statuses created before it was searchable
+func BackfillLinkTitles(ctx context.Context) (int, error) {
+	count := 0
+	filter := bson.M{"status_type": enum.LinkStatus, "link_title": bson.M{"$exists": false}}
+	err := eachDocument(ctx, "statuses", filter, bson.M{"status_type": 1, "meta": 1}, func(raw bson.Raw) error {
+		status := &Status{}
+		if err := bson.Unmarshal(raw, status); err != nil {
+			return err
+		}
+		metaData, err := status.GetMetaData()
+		if err != nil {
+			return err
+		}
+		linkMeta, ok := metaData.(*meta.LinkMeta)
+		if !ok || linkMeta.Title == "" {
+			return nil
+		}
+		count++
+		_, err = db.DB().Collection("statuses").UpdateOne(ctx, bson.M{"_id": status.ID},
+			bson.M{"$set": bson.M{"link_title": linkMeta.Title}})
+		return err
+	})
+	return count, err
+}
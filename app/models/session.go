@@ -0,0 +1,214 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxDeviceIDLength  = 128
+	maxUserAgentLength = 512
+)
+
+// Session is a signed in device of a user, the access tokens of a session are valid until it is revoked or expired.
+// Only the hash of the refresh token is stored, the token is rotated on each refresh.
+type Session struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	UID               uint64             `bson:"uid"`
+	Device            `bson:",inline"`
+	RefreshTokenHash  string     `bson:"refresh_token_hash"`
+	PreviousTokenHash string     `bson:"previous_token_hash,omitempty"`
+	ExpiresAt         time.Time  `bson:"expires_at"`
+	LastUsedAt        time.Time  `bson:"last_used_at"`
+	RevokedAt         *time.Time `bson:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `bson:"created_at"`
+	UpdatedAt         time.Time  `bson:"updated_at"`
+}
+
+// Device is the client of a session
+type Device struct {
+	DeviceID  string `bson:"device_id,omitempty"`
+	UserAgent string `bson:"user_agent,omitempty"`
+	IP        string `bson:"ip,omitempty"`
+}
+
+func (*Session) CollectionName() string {
+	return "sessions"
+}
+
+// CreateSession returns the session and its refresh token
+func CreateSession(ctx context.Context, uid uint64, device *Device) (*Session, string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := &Session{
+		UID:              uid,
+		Device:           device.truncate(),
+		RefreshTokenHash: hash,
+		ExpiresAt:        now.Add(env.Envs.RefreshTokenDuration),
+		LastUsedAt:       now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	result, err := db.DB().Collection(session.CollectionName()).InsertOne(ctx, session)
+	if err != nil {
+		return nil, "", err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return session, token, nil
+}
+
+// FindActiveSession finds the session of the user which is neither revoked nor expired
+func FindActiveSession(ctx context.Context, uid uint64, id primitive.ObjectID) (*Session, error) {
+	session := &Session{}
+	err := db.ODM(ctx).First(session, activeSessionFilter(bson.M{"_id": id, "uid": uid})).Error
+	if err == mongo.ErrNoDocuments {
+		return nil, codes.ErrNotFound.New("session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListActiveSessions lists the sessions of the user, the most recently used first
+func ListActiveSessions(ctx context.Context, uid uint64) ([]*Session, error) {
+	sessions := make([]*Session, 0)
+	cursor, err := db.DB().Collection("sessions").Find(ctx, activeSessionFilter(bson.M{"uid": uid}),
+		options.Find().SetSort(bson.M{"last_used_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RefreshSession rotates the refresh token of the session and returns the new one.
+// A refresh token used twice is taken as stolen, the session is revoked.
+func RefreshSession(ctx context.Context, refreshToken string, device *Device) (*Session, string, error) {
+	hash := hashRefreshToken(refreshToken)
+	session := &Session{}
+	err := db.ODM(ctx).First(session, activeSessionFilter(bson.M{"refresh_token_hash": hash})).Error
+	if err == mongo.ErrNoDocuments {
+		if revokeErr := revokeReusedSession(ctx, hash); revokeErr != nil {
+			return nil, "", revokeErr
+		}
+		return nil, "", codes.ErrUnauthorized.New("invalid refresh token")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	token, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	update := bson.M{
+		"refresh_token_hash":  newHash,
+		"previous_token_hash": hash,
+		"expires_at":          now.Add(env.Envs.RefreshTokenDuration),
+		"last_used_at":        now,
+		"updated_at":          now,
+	}
+	truncated := device.truncate()
+	if truncated.UserAgent != "" {
+		update["user_agent"] = truncated.UserAgent
+	}
+	if truncated.IP != "" {
+		update["ip"] = truncated.IP
+	}
+	// the hash is compared again so only one of the concurrent refreshes wins
+	result, err := db.DB().Collection(session.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": session.ID, "refresh_token_hash": hash}, bson.M{"$set": update})
+	if err != nil {
+		return nil, "", err
+	}
+	if result.MatchedCount == 0 {
+		return nil, "", codes.ErrUnauthorized.New("invalid refresh token")
+	}
+	session.RefreshTokenHash, session.PreviousTokenHash = newHash, hash
+	session.ExpiresAt, session.LastUsedAt, session.UpdatedAt = now.Add(env.Envs.RefreshTokenDuration), now, now
+	if truncated.UserAgent != "" {
+		session.UserAgent = truncated.UserAgent
+	}
+	if truncated.IP != "" {
+		session.IP = truncated.IP
+	}
+	return session, token, nil
+}
+
+// Revoke revokes the session, the access and refresh tokens of it are rejected after
+func (s *Session) Revoke(ctx context.Context) error {
+	now := time.Now()
+	_, err := db.DB().Collection(s.CollectionName()).UpdateOne(ctx, bson.M{"_id": s.ID},
+		bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}})
+	if err != nil {
+		return err
+	}
+	s.RevokedAt, s.UpdatedAt = &now, now
+	return nil
+}
+
+func revokeReusedSession(ctx context.Context, hash string) error {
+	session := &Session{}
+	err := db.ODM(ctx).First(session, activeSessionFilter(bson.M{"previous_token_hash": hash})).Error
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logrus.Warnf("refresh token of session %s is reused, revoke the session", session.ID.Hex())
+	return session.Revoke(ctx)
+}
+
+func activeSessionFilter(filter bson.M) bson.M {
+	filter["revoked_at"] = nil
+	filter["expires_at"] = bson.M{"$gt": time.Now()}
+	return filter
+}
+
+func newRefreshToken() (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (d *Device) truncate() Device {
+	if d == nil {
+		return Device{}
+	}
+	device := *d
+	if len(device.DeviceID) > maxDeviceIDLength {
+		device.DeviceID = device.DeviceID[:maxDeviceIDLength]
+	}
+	if len(device.UserAgent) > maxUserAgentLength {
+		device.UserAgent = device.UserAgent[:maxUserAgentLength]
+	}
+	return device
+}
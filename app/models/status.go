@@ -10,33 +10,52 @@ import (
 	"github.com/mises-id/sns/lib/codes"
 	"github.com/mises-id/sns/lib/db"
 	"github.com/mises-id/sns/lib/pagination"
+	"github.com/mises-id/sns/lib/textparse"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Status struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty"`
 	ParentID      primitive.ObjectID `bson:"parent_id,omitempty"`
 	OriginID      primitive.ObjectID `bson:"origin_id,omitempty"`
+	RootID        primitive.ObjectID `bson:"root_id,omitempty"`
+	ReplyToUID    uint64             `bson:"reply_to_uid,omitempty"`
 	UID           uint64             `bson:"uid,omitempty"`
 	FromType      enum.FromType      `bson:"from_type"`
 	StatusType    enum.StatusType    `bson:"status_type"`
+	Visibility    enum.Visibility    `bson:"visibility,omitempty"`
 	Meta          json.RawMessage    `bson:"meta,omitempty"`
 	Content       string             `bson:"content,omitempty" validate:"min=0,max=4000"`
+	LinkTitle     string             `bson:"link_title,omitempty"`
+	Tags          []string           `bson:"tags,omitempty"`
+	Mentions      []*Mention         `bson:"mentions,omitempty"`
 	CommentsCount uint64             `bson:"comments_count,omitempty"`
 	LikesCount    uint64             `bson:"likes_count,omitempty"`
 	ForwardsCount uint64             `bson:"forwards_count,omitempty"`
+	EditedAt      *time.Time         `bson:"edited_at,omitempty"`
 	DeletedAt     *time.Time         `bson:"deleted_at,omitempty"`
 	CreatedAt     time.Time          `bson:"created_at,omitempty"`
 	UpdatedAt     time.Time          `bson:"updated_at,omitempty"`
 	User          *User              `bson:"-"`
+	ReplyToUser   *User              `bson:"-"`
+	Replies       []*Status          `bson:"-"`
 	IsLiked       bool               `bson:"-"`
 	ParentStatus  *Status            `bson:"-"`
 	OriginStatus  *Status            `bson:"-"`
 	metaData      meta.MetaData      `bson:"-"`
 }
 
+// Mention is a resolved @username in the content, start and end are offsets counted in runes
+type Mention struct {
+	UID      uint64 `bson:"uid"`
+	Username string `bson:"username"`
+	Start    int    `bson:"start"`
+	End      int    `bson:"end"`
+}
+
 func (s *Status) validate(ctx context.Context) error {
 	logrus.Info("xxxxx")
 	err := Validate.Struct(s)
@@ -55,14 +74,17 @@ func (s *Status) BeforeCreate(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		if s.FromType == enum.FromComment && s.RootID.IsZero() {
+			s.RootID = s.ParentID
+		}
 		s.OriginID = s.ParentStatus.OriginID
 		if s.OriginID.IsZero() {
 			s.OriginID = s.ParentID
 		}
 	}
 	if !s.OriginID.IsZero() {
-		s.OriginStatus, err = FindStatus(ctx, s.OriginID)
-		if err != nil {
+		// the origin status may have been deleted, forwarding its forwards is still allowed
+		if s.OriginStatus, err = FindStatusIncludeDeleted(ctx, s.OriginID); err != nil {
 			return err
 		}
 	}
@@ -70,7 +92,10 @@ func (s *Status) BeforeCreate(ctx context.Context) error {
 }
 
 func (s *Status) AfterCreate(ctx context.Context) error {
-	var err error
+	err := IncTagBuckets(ctx, s.trendingTags(), s.CreatedAt, 1)
+	if err != nil {
+		return err
+	}
 	counterKey := s.FromType.CounterKey()
 	if s.ParentStatus != nil {
 		err = s.ParentStatus.IncStatusCounter(ctx, counterKey)
@@ -78,9 +103,51 @@ func (s *Status) AfterCreate(ctx context.Context) error {
 			return err
 		}
 	}
+	if s.IsReply() {
+		root := &Status{ID: s.RootID}
+		return root.IncStatusCounter(ctx, counterKey)
+	}
 	return nil
 }
 
+func (s *Status) AfterDelete(ctx context.Context) error {
+	if err := IncTagBuckets(ctx, s.trendingTags(), s.CreatedAt, -1); err != nil {
+		return err
+	}
+	if !s.ParentID.IsZero() {
+		parent := &Status{ID: s.ParentID}
+		if err := parent.IncStatusCounter(ctx, s.FromType.CounterKey(), -1); err != nil {
+			return err
+		}
+	}
+	if s.IsReply() {
+		root := &Status{ID: s.RootID}
+		if err := root.IncStatusCounter(ctx, s.FromType.CounterKey(), -1); err != nil {
+			return err
+		}
+	}
+	_, err := db.DB().Collection("timelines").DeleteMany(ctx, bson.M{"status_id": s.ID})
+	return err
+}
+
+// only public statuses are counted in trending tags
+func (s *Status) trendingTags() []string {
+	if s.Visibility != enum.VisibilityPublic {
+		return nil
+	}
+	return s.Tags
+}
+
+func (s *Status) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
+
+// IsReply reports whether the status is a reply in the thread of a top level comment,
+// the comments_count of a comment is the count of its replies
+func (s *Status) IsReply() bool {
+	return s.FromType == enum.FromComment && !s.RootID.IsZero() && s.RootID != s.ParentID
+}
+
 func (s *Status) IncStatusCounter(ctx context.Context, counterKey string, values ...int) error {
 	if counterKey == "" {
 		return nil
@@ -89,7 +156,12 @@ func (s *Status) IncStatusCounter(ctx context.Context, counterKey string, values
 	if len(values) > 0 {
 		value = values[0]
 	}
-	return db.DB().Collection("statuses").FindOneAndUpdate(ctx, bson.M{"_id": s.ID},
+	filter := bson.M{"_id": s.ID}
+	if value < 0 {
+		// counters are unsigned, never decrease them below zero
+		filter[counterKey] = bson.M{"$gte": -value}
+	}
+	err := db.DB().Collection("statuses").FindOneAndUpdate(ctx, filter,
 		bson.D{{
 			Key: "$inc",
 			Value: bson.D{{
@@ -97,6 +169,18 @@ func (s *Status) IncStatusCounter(ctx context.Context, counterKey string, values
 				Value: value,
 			}}},
 		}).Err()
+	if err == mongo.ErrNoDocuments && value < 0 {
+		return nil
+	}
+	return err
+}
+
+func (s *Status) hasAttachment() bool {
+	switch s.StatusType {
+	case enum.LinkStatus, enum.ImageStatus, enum.VideoStatus:
+		return true
+	}
+	return false
 }
 
 func (s *Status) GetMetaData() (meta.MetaData, error) {
@@ -109,7 +193,7 @@ func (s *Status) GetMetaData() (meta.MetaData, error) {
 
 func FindStatus(ctx context.Context, id primitive.ObjectID) (*Status, error) {
 	status := &Status{}
-	err := db.ODM(ctx).First(status, bson.M{"_id": id}).Error
+	err := db.ODM(ctx).First(status, bson.M{"_id": id, "deleted_at": nil}).Error
 	if err != nil {
 		return nil, err
 	}
@@ -122,11 +206,24 @@ func FindStatus(ctx context.Context, id primitive.ObjectID) (*Status, error) {
 	return status, preloadStatusUser(ctx, status)
 }
 
+// FindStatusIncludeDeleted finds the status even if it is deleted, related data are not preloaded
+func FindStatusIncludeDeleted(ctx context.Context, id primitive.ObjectID) (*Status, error) {
+	status := &Status{}
+	if err := db.ODM(ctx).First(status, bson.M{"_id": id}).Error; err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
 type CreateStatusParams struct {
 	UID        uint64
 	ParentID   primitive.ObjectID
+	RootID     primitive.ObjectID
+	ReplyToUID uint64
+	Mentions   []*Mention
 	StatusType enum.StatusType
 	FromType   enum.FromType
+	Visibility enum.Visibility
 	Content    string
 	MetaData   meta.MetaData
 }
@@ -136,15 +233,26 @@ func CreateStatus(ctx context.Context, params *CreateStatusParams) (*Status, err
 		UID:        params.UID,
 		StatusType: params.StatusType,
 		FromType:   params.FromType,
+		Visibility: params.Visibility,
 		ParentID:   params.ParentID,
+		RootID:     params.RootID,
+		ReplyToUID: params.ReplyToUID,
+		Mentions:   params.Mentions,
 		Content:    params.Content,
 	}
+	if status.FromType == enum.FromPost || status.FromType == enum.FromForward {
+		status.Tags = textparse.Hashtags(status.Content)
+	}
 	var err error
 	if params.MetaData != nil {
 		status.Meta, err = json.Marshal(params.MetaData)
 		if err != nil {
 			return nil, err
 		}
+		// meta is stored as binary, the link title is copied out to be searchable
+		if linkMeta, ok := params.MetaData.(*meta.LinkMeta); ok {
+			status.LinkTitle = linkMeta.Title
+		}
 	}
 	if err = status.BeforeCreate(ctx); err != nil {
 		return nil, err
@@ -164,16 +272,134 @@ func CreateStatus(ctx context.Context, params *CreateStatusParams) (*Status, err
 	return status, preloadStatusUser(ctx, status)
 }
 
+type UpdateStatusParams struct {
+	Content  string
+	MetaData meta.MetaData
+	Mentions []*Mention
+}
+
+// UpdateStatus replaces the content and meta, and keeps the current version as a revision in the same transaction.
+// The update fails with conflict if the status is changed after it is loaded.
+func UpdateStatus(ctx context.Context, status *Status, params *UpdateStatusParams) error {
+	edited := *status
+	edited.Content = params.Content
+	edited.Mentions = params.Mentions
+	edited.Tags = nil
+	if edited.FromType == enum.FromPost || edited.FromType == enum.FromForward {
+		edited.Tags = textparse.Hashtags(edited.Content)
+	}
+	var err error
+	if params.MetaData != nil {
+		if edited.Meta, err = json.Marshal(params.MetaData); err != nil {
+			return err
+		}
+		edited.metaData = params.MetaData
+		if linkMeta, ok := params.MetaData.(*meta.LinkMeta); ok {
+			edited.LinkTitle = linkMeta.Title
+		}
+	}
+	if err = edited.validate(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	edited.EditedAt = &now
+	edited.UpdatedAt = now
+	filter := bson.M{
+		"_id":        status.ID,
+		"updated_at": status.UpdatedAt,
+		"deleted_at": nil,
+	}
+	if status.UpdatedAt.IsZero() {
+		filter["updated_at"] = bson.M{"$exists": false}
+	}
+	// the revision is kept only if the update wins
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		result, err := db.DB().Collection("statuses").UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+			"content":    edited.Content,
+			"meta":       edited.Meta,
+			"link_title": edited.LinkTitle,
+			"tags":       edited.Tags,
+			"mentions":   edited.Mentions,
+			"edited_at":  edited.EditedAt,
+			"updated_at": edited.UpdatedAt,
+		}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return codes.ErrConflict.New("status is changed by another request")
+		}
+		return createStatusRevision(ctx, status)
+	})
+	if err != nil {
+		return err
+	}
+	if err = IncTagBuckets(ctx, status.trendingTags(), status.CreatedAt, -1); err != nil {
+		return err
+	}
+	if err = IncTagBuckets(ctx, edited.trendingTags(), edited.CreatedAt, 1); err != nil {
+		return err
+	}
+	*status = edited
+	return nil
+}
+
+// UpdateStatusLinkMeta fills the preview of the link unfurled after the status is saved.
+// The update fails with conflict if the status is edited after it is loaded.
+func UpdateStatusLinkMeta(ctx context.Context, status *Status, linkMeta *meta.LinkMeta) error {
+	data, err := json.Marshal(linkMeta)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{
+		"_id":        status.ID,
+		"updated_at": status.UpdatedAt,
+		"deleted_at": nil,
+	}
+	if status.UpdatedAt.IsZero() {
+		filter["updated_at"] = bson.M{"$exists": false}
+	}
+	now := time.Now()
+	result, err := db.DB().Collection("statuses").UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"meta":       json.RawMessage(data),
+		"link_title": linkMeta.Title,
+		"updated_at": now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return codes.ErrConflict.New("status is changed by another request")
+	}
+	status.Meta, status.metaData = data, linkMeta
+	status.LinkTitle, status.UpdatedAt = linkMeta.Title, now
+	return nil
+}
+
 func DeleteStatus(ctx context.Context, id primitive.ObjectID) error {
-	_, err := db.DB().Collection("statuses").DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	status := &Status{}
+	now := time.Now()
+	err := db.DB().Collection("statuses").FindOneAndUpdate(ctx, bson.M{
+		"_id":        id,
+		"deleted_at": nil,
+	}, bson.M{"$set": bson.M{"deleted_at": now}}).Decode(status)
+	if err != nil {
+		return err
+	}
+	status.DeletedAt = &now
+	return status.AfterDelete(ctx)
 }
 
 type ListStatusParams struct {
 	UIDs           []uint64
+	Tag            string
+	MentionUID     uint64
 	ParentStatusID primitive.ObjectID
 	FromTypes      []enum.FromType
-	PageParams     *pagination.PageQuickParams
+	// ExcludeUIDs are the authors hidden from the current user
+	ExcludeUIDs         []uint64
+	ExcludeVisibilities []enum.Visibility
+	PageParams          *pagination.PageQuickParams
 }
 
 func ListStatus(ctx context.Context, params *ListStatusParams) ([]*Status, pagination.Pagination, error) {
@@ -181,16 +407,28 @@ func ListStatus(ctx context.Context, params *ListStatusParams) ([]*Status, pagin
 		params.PageParams = pagination.DefaultQuickParams()
 	}
 	statuses := make([]*Status, 0)
-	chain := db.ODM(ctx)
+	chain := db.ODM(ctx).Where(bson.M{"deleted_at": nil})
 	if params.UIDs != nil && len(params.UIDs) > 0 {
 		chain = chain.Where(bson.M{"uid": bson.M{"$in": params.UIDs}})
 	}
 	if !params.ParentStatusID.IsZero() {
 		chain = chain.Where(bson.M{"parent_id": params.ParentStatusID})
 	}
+	if params.Tag != "" {
+		chain = chain.Where(bson.M{"tags": params.Tag})
+	}
+	if params.MentionUID != 0 {
+		chain = chain.Where(bson.M{"mentions.uid": params.MentionUID})
+	}
 	if params.FromTypes != nil {
 		chain = chain.Where(bson.M{"from_type": bson.M{"$in": params.FromTypes}})
 	}
+	if len(params.ExcludeUIDs) > 0 {
+		chain = chain.Where(bson.M{"uid": bson.M{"$nin": params.ExcludeUIDs}})
+	}
+	if len(params.ExcludeVisibilities) > 0 {
+		chain = chain.Where(bson.M{"visibility": bson.M{"$nin": params.ExcludeVisibilities}})
+	}
 	paginator := pagination.NewQuickPaginator(params.PageParams.Limit, params.PageParams.NextID, chain)
 	page, err := paginator.Paginate(&statuses)
 	if err != nil {
@@ -205,12 +443,24 @@ func ListStatus(ctx context.Context, params *ListStatusParams) ([]*Status, pagin
 	return statuses, page, preloadStatusUser(ctx, statuses...)
 }
 
-func ListCommentStatus(ctx context.Context, statusID primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*Status, pagination.Pagination, error) {
+// ListCommentStatus lists the top level comments of the status with their latest replies,
+// deleted comments are kept as tombstones while they still have replies
+func ListCommentStatus(ctx context.Context, statusID primitive.ObjectID, repliesLimit int64, excludeUIDs []uint64, pageParams *pagination.PageQuickParams) ([]*Status, pagination.Pagination, error) {
 	if pageParams == nil {
 		pageParams = pagination.DefaultQuickParams()
 	}
 	statuses := make([]*Status, 0)
-	chain := db.ODM(ctx).Where(bson.M{"parent_id": statusID, "from_type": enum.FromComment})
+	chain := db.ODM(ctx).Where(bson.M{
+		"parent_id": statusID,
+		"from_type": enum.FromComment,
+		"$or": bson.A{
+			bson.M{"deleted_at": nil},
+			bson.M{"comments_count": bson.M{"$gt": 0}},
+		},
+	})
+	if len(excludeUIDs) > 0 {
+		chain = chain.Where(bson.M{"uid": bson.M{"$nin": excludeUIDs}})
+	}
 	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
 	page, err := paginator.Paginate(&statuses)
 	if err != nil {
@@ -219,16 +469,92 @@ func ListCommentStatus(ctx context.Context, statusID primitive.ObjectID, pagePar
 	if err = preloadRelatedStatus(ctx, statuses...); err != nil {
 		return nil, nil, err
 	}
-	if err = preloadAttachment(ctx, statuses...); err != nil {
+	if err = preloadReplies(ctx, repliesLimit, excludeUIDs, statuses...); err != nil {
+		return nil, nil, err
+	}
+	replies := make([]*Status, 0)
+	for _, status := range statuses {
+		replies = append(replies, status.Replies...)
+	}
+	if err = preloadStatusUser(ctx, replies...); err != nil {
 		return nil, nil, err
 	}
 	return statuses, page, preloadStatusUser(ctx, statuses...)
 }
 
+// ListCommentReplies lists the replies in the thread of the top level comment
+func ListCommentReplies(ctx context.Context, commentID primitive.ObjectID, excludeUIDs []uint64, pageParams *pagination.PageQuickParams) ([]*Status, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	statuses := make([]*Status, 0)
+	chain := db.ODM(ctx).Where(bson.M{"parent_id": commentID, "from_type": enum.FromComment, "deleted_at": nil})
+	if len(excludeUIDs) > 0 {
+		chain = chain.Where(bson.M{"uid": bson.M{"$nin": excludeUIDs}})
+	}
+	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
+	page, err := paginator.Paginate(&statuses)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, preloadStatusUser(ctx, statuses...)
+}
+
+// FindStatusMap finds statuses including deleted ones without preloading
+func FindStatusMap(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*Status, error) {
+	statusMap := make(map[primitive.ObjectID]*Status)
+	if len(ids) == 0 {
+		return statusMap, nil
+	}
+	statuses := make([]*Status, 0)
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": ids}}).Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		statusMap[status.ID] = status
+	}
+	return statusMap, nil
+}
+
+// ListStatusByIDs finds statuses and keeps the order of the given ids
+func ListStatusByIDs(ctx context.Context, ids ...primitive.ObjectID) ([]*Status, error) {
+	statuses := make([]*Status, 0)
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": ids}, "deleted_at": nil}).Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	statusMap := make(map[primitive.ObjectID]*Status)
+	for _, status := range statuses {
+		statusMap[status.ID] = status
+	}
+	result := make([]*Status, 0, len(statuses))
+	for _, id := range ids {
+		if status := statusMap[id]; status != nil {
+			result = append(result, status)
+		}
+	}
+	if err = preloadRelatedStatus(ctx, result...); err != nil {
+		return nil, err
+	}
+	if err = preloadAttachment(ctx, result...); err != nil {
+		return nil, err
+	}
+	return result, preloadStatusUser(ctx, result...)
+}
+
 func preloadStatusUser(ctx context.Context, statuses ...*Status) error {
 	userIds := make([]uint64, 0)
 	for _, status := range statuses {
-		userIds = append(userIds, status.UID)
+		if !status.IsDeleted() {
+			userIds = append(userIds, status.UID)
+			if status.ReplyToUID != 0 {
+				userIds = append(userIds, status.ReplyToUID)
+			}
+		}
 	}
 	users := make([]*User, 0)
 	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": userIds}}).Find(&users).Error
@@ -246,7 +572,66 @@ func preloadStatusUser(ctx context.Context, statuses ...*Status) error {
 		userMap[user.UID] = user
 	}
 	for _, status := range statuses {
-		status.User = userMap[status.UID]
+		if !status.IsDeleted() {
+			status.User = userMap[status.UID]
+			status.ReplyToUser = userMap[status.ReplyToUID]
+		}
+	}
+	return nil
+}
+
+// preloadReplies loads the latest replies of each comment in one aggregation
+func preloadReplies(ctx context.Context, limit int64, excludeUIDs []uint64, comments ...*Status) error {
+	commentIDs := make([]primitive.ObjectID, 0)
+	for _, comment := range comments {
+		if comment.CommentsCount > 0 {
+			commentIDs = append(commentIDs, comment.ID)
+		}
+	}
+	if len(commentIDs) == 0 || limit <= 0 {
+		return nil
+	}
+	// each comment looks up only its latest replies, the replies beyond the limit are never loaded
+	match := bson.M{
+		"$expr":      bson.M{"$eq": bson.A{"$parent_id", "$$comment_id"}},
+		"from_type":  enum.FromComment,
+		"deleted_at": nil,
+	}
+	if len(excludeUIDs) > 0 {
+		match["uid"] = bson.M{"$nin": excludeUIDs}
+	}
+	cursor, err := db.DB().Collection("statuses").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"_id": bson.M{"$in": commentIDs}}},
+		bson.M{"$lookup": bson.M{
+			"from": "statuses",
+			"let":  bson.M{"comment_id": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": match},
+				bson.M{"$sort": bson.M{"_id": -1}},
+				bson.M{"$limit": limit},
+			},
+			"as": "replies",
+		}},
+		bson.M{"$project": bson.M{"replies": 1}},
+	})
+	if err != nil {
+		return err
+	}
+	groups := make([]*struct {
+		ID      primitive.ObjectID `bson:"_id"`
+		Replies []*Status          `bson:"replies"`
+	}, 0)
+	if err = cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+	repliesMap := make(map[primitive.ObjectID][]*Status)
+	for _, group := range groups {
+		if len(group.Replies) > 0 {
+			repliesMap[group.ID] = group.Replies
+		}
+	}
+	for _, comment := range comments {
+		comment.Replies = repliesMap[comment.ID]
 	}
 	return nil
 }
@@ -261,6 +646,7 @@ func preloadRelatedStatus(ctx context.Context, statuses ...*Status) error {
 			statusIds = append(statusIds, status.OriginID)
 		}
 	}
+	// deleted statuses are kept here and rendered as tombstones
 	relatedStatuses := make([]*Status, 0)
 	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": statusIds}}).Find(&relatedStatuses).Error
 	if err != nil {
@@ -286,23 +672,39 @@ func preloadRelatedStatus(ctx context.Context, statuses ...*Status) error {
 func preloadAttachment(ctx context.Context, statuses ...*Status) error {
 	attachmentIDs := make([]uint64, 0)
 	linkMetas := make([]*meta.LinkMeta, 0)
+	mediaItems := make([]*meta.MediaItem, 0)
 	for _, status := range statuses {
-		if status.StatusType != enum.LinkStatus {
+		if !status.hasAttachment() || status.IsDeleted() {
 			continue
 		}
 		metaData, err := status.GetMetaData()
 		if err != nil {
 			return err
 		}
-		linkMeta := metaData.(*meta.LinkMeta)
-		attachmentIDs = append(attachmentIDs, linkMeta.AttachmentID)
-		linkMetas = append(linkMetas, linkMeta)
+		switch data := metaData.(type) {
+		case *meta.LinkMeta:
+			attachmentIDs = append(attachmentIDs, data.AttachmentID)
+			linkMetas = append(linkMetas, data)
+		case *meta.ImageMeta:
+			mediaItems = append(mediaItems, data.Images...)
+		case *meta.VideoMeta:
+			mediaItems = append(mediaItems, data.Videos...)
+		}
+	}
+	for _, item := range mediaItems {
+		attachmentIDs = append(attachmentIDs, item.AttachmentID)
+	}
+	if len(attachmentIDs) == 0 {
+		return nil
 	}
 	attachments := make([]*Attachment, 0)
 	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": attachmentIDs}}).Find(&attachments).Error
 	if err != nil {
 		return err
 	}
+	if err = PreloadPosters(ctx, attachments...); err != nil {
+		return err
+	}
 	attachmentMap := make(map[uint64]*Attachment)
 	for _, attachment := range attachments {
 		attachmentMap[attachment.ID] = attachment
@@ -312,5 +714,19 @@ func preloadAttachment(ctx context.Context, statuses ...*Status) error {
 			linkMeta.AttachmentURL = attachmentMap[linkMeta.AttachmentID].FileUrl()
 		}
 	}
+	for _, item := range mediaItems {
+		attachment := attachmentMap[item.AttachmentID]
+		if attachment == nil {
+			continue
+		}
+		item.AttachmentURL = attachment.FileUrl()
+		if len(attachment.Variants) > 0 {
+			item.SmallURL = attachment.VariantUrl("small")
+			item.MediumURL = attachment.VariantUrl("medium")
+			item.LargeURL = attachment.VariantUrl("large")
+		}
+		item.PosterURL = attachment.PosterUrl()
+		item.Duration = attachment.Duration
+	}
 	return nil
 }
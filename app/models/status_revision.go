@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StatusRevision is a prior version of an edited status
+type StatusRevision struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	StatusID   primitive.ObjectID `bson:"status_id"`
+	UID        uint64             `bson:"uid"`
+	StatusType enum.StatusType    `bson:"status_type"`
+	Content    string             `bson:"content,omitempty"`
+	Meta       json.RawMessage    `bson:"meta,omitempty"`
+	Mentions   []*Mention         `bson:"mentions,omitempty"`
+	// CreatedAt is the time this version was published
+	CreatedAt time.Time `bson:"created_at"`
+	// Status renders the revision as a status with preloaded attachments
+	Status *Status `bson:"-"`
+}
+
+func (*StatusRevision) CollectionName() string {
+	return "status_revisions"
+}
+
+func createStatusRevision(ctx context.Context, status *Status) error {
+	revision := &StatusRevision{
+		StatusID:   status.ID,
+		UID:        status.UID,
+		StatusType: status.StatusType,
+		Content:    status.Content,
+		Meta:       status.Meta,
+		Mentions:   status.Mentions,
+		CreatedAt:  status.CreatedAt,
+	}
+	if status.EditedAt != nil {
+		revision.CreatedAt = *status.EditedAt
+	}
+	_, err := db.DB().Collection(revision.CollectionName()).InsertOne(ctx, revision)
+	return err
+}
+
+// ListStatusRevision lists the prior versions of the status, latest first
+func ListStatusRevision(ctx context.Context, statusID primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*StatusRevision, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	revisions := make([]*StatusRevision, 0)
+	chain := db.ODM(ctx).Where(bson.M{"status_id": statusID})
+	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
+	page, err := paginator.Paginate(&revisions)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses := make([]*Status, len(revisions))
+	for i, revision := range revisions {
+		revision.Status = &Status{
+			ID:         revision.StatusID,
+			UID:        revision.UID,
+			StatusType: revision.StatusType,
+			Content:    revision.Content,
+			Meta:       revision.Meta,
+			Mentions:   revision.Mentions,
+			CreatedAt:  revision.CreatedAt,
+		}
+		statuses[i] = revision.Status
+	}
+	return revisions, page, preloadAttachment(ctx, statuses...)
+}
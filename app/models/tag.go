@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/lib/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TagBucket counts the statuses of a tag created in an hour,
+// trending tags are summed over the buckets of a sliding window
+type TagBucket struct {
+	Tag   string    `bson:"tag"`
+	Hour  time.Time `bson:"hour"`
+	Count int64     `bson:"count"`
+}
+
+type TrendingTag struct {
+	Tag   string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+func IncTagBuckets(ctx context.Context, tags []string, at time.Time, value int64) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	hour := at.UTC().Truncate(time.Hour)
+	writes := make([]mongo.WriteModel, len(tags))
+	for i, tag := range tags {
+		update := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"tag": tag, "hour": hour}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": value}})
+		if value > 0 {
+			update.SetUpsert(true)
+		} else {
+			update.SetFilter(bson.M{"tag": tag, "hour": hour, "count": bson.M{"$gte": -value}})
+		}
+		writes[i] = update
+	}
+	_, err := db.DB().Collection("tagbuckets").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// ListTrendingTags sums the buckets since the time, most used first
+func ListTrendingTags(ctx context.Context, since time.Time, limit int64) ([]*TrendingTag, error) {
+	cursor, err := db.DB().Collection("tagbuckets").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{
+			"hour":  bson.M{"$gte": since.UTC().Truncate(time.Hour)},
+			"count": bson.M{"$gt": 0},
+		}},
+		bson.M{"$group": bson.M{
+			"_id":   "$tag",
+			"count": bson.M{"$sum": "$count"},
+		}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]*TrendingTag, 0)
+	return tags, cursor.All(ctx, &tags)
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const fanOutBatchSize = 500
+
+// Timeline is an entry of the user's timeline inbox, written when a followed user posts
+type Timeline struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UID       uint64             `bson:"uid,omitempty"`
+	StatusID  primitive.ObjectID `bson:"status_id,omitempty"`
+	AuthorUID uint64             `bson:"author_uid,omitempty"`
+	// Visibility is copied from the status, so the timeline is filtered before it is paginated
+	Visibility enum.Visibility `bson:"visibility,omitempty"`
+	CreatedAt  time.Time       `bson:"created_at,omitempty"`
+}
+
+func newTimeline(uid uint64, status *Status) *Timeline {
+	return &Timeline{
+		UID:        uid,
+		StatusID:   status.ID,
+		AuthorUID:  status.UID,
+		Visibility: status.Visibility,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// FanOutStatus pushes the status into the timeline inbox of every fan of the author
+func FanOutStatus(ctx context.Context, status *Status) error {
+	cursor, err := db.DB().Collection("follows").Find(ctx, bson.M{
+		"to_uid": status.UID,
+	}, &options.FindOptions{
+		Projection: bson.M{"from_uid": 1},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	entries := make([]interface{}, 0, fanOutBatchSize)
+	for cursor.Next(ctx) {
+		follow := &Follow{}
+		if err = cursor.Decode(follow); err != nil {
+			return err
+		}
+		entries = append(entries, newTimeline(follow.FromUID, status))
+		if len(entries) >= fanOutBatchSize {
+			if err = insertTimelines(ctx, entries); err != nil {
+				return err
+			}
+			entries = entries[:0]
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return err
+	}
+	return insertTimelines(ctx, entries)
+}
+
+// BackfillTimeline copies the recent statuses of the author into the user's timeline inbox
+func BackfillTimeline(ctx context.Context, uid, authorUID uint64, limit int64) error {
+	statuses := make([]*Status, 0)
+	err := db.ODM(ctx).Where(bson.M{
+		"uid":        authorUID,
+		"from_type":  bson.M{"$in": []enum.FromType{enum.FromPost, enum.FromForward}},
+		"visibility": bson.M{"$ne": enum.VisibilityOnlyMe},
+		"deleted_at": nil,
+	}).Sort(bson.M{"_id": -1}).Limit(limit).Find(&statuses).Error
+	if err != nil {
+		return err
+	}
+	entries := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		entries[i] = newTimeline(uid, status)
+	}
+	return insertTimelines(ctx, entries)
+}
+
+// BackfillFollowTimelines backfills the timeline inbox of every follow, the follows made before the inbox
+// existed have nothing in it. Statuses already in the inbox are skipped, so it is safe to run again.
+func BackfillFollowTimelines(ctx context.Context) (int, error) {
+	count := 0
+	err := eachDocument(ctx, "follows", bson.M{}, bson.M{"from_uid": 1, "to_uid": 1}, func(raw bson.Raw) error {
+		follow := &Follow{}
+		if err := bson.Unmarshal(raw, follow); err != nil {
+			return err
+		}
+		count++
+		return BackfillTimeline(ctx, follow.FromUID, follow.ToUID, env.Envs.TimelineBackfill)
+	})
+	return count, err
+}
+
+// BackfillTimelineVisibility copies the visibility of the statuses into their timeline entries written before it was kept
+func BackfillTimelineVisibility(ctx context.Context) (int, error) {
+	count := 0
+	filter := bson.M{
+		"from_type":  bson.M{"$in": []enum.FromType{enum.FromPost, enum.FromForward}},
+		"visibility": bson.M{"$in": enum.NonPublicVisibilities},
+	}
+	err := eachDocument(ctx, "statuses", filter, bson.M{"visibility": 1}, func(raw bson.Raw) error {
+		status := &Status{}
+		if err := bson.Unmarshal(raw, status); err != nil {
+			return err
+		}
+		result, err := db.DB().Collection("timelines").UpdateMany(ctx, bson.M{
+			"status_id":  status.ID,
+			"visibility": bson.M{"$ne": status.Visibility},
+		}, bson.M{"$set": bson.M{"visibility": status.Visibility}})
+		if err != nil {
+			return err
+		}
+		count += int(result.ModifiedCount)
+		return nil
+	})
+	return count, err
+}
+
+// RemoveTimelineAuthor removes the statuses of the author from the user's timeline inbox
+func RemoveTimelineAuthor(ctx context.Context, uid, authorUID uint64) error {
+	_, err := db.DB().Collection("timelines").DeleteMany(ctx, bson.M{
+		"uid":        uid,
+		"author_uid": authorUID,
+	})
+	return err
+}
+
+// ListTimelineStatus lists the user's timeline inbox, the statuses for friends are kept only if the author is a friend
+func ListTimelineStatus(ctx context.Context, uid uint64, excludeUIDs, friendUIDs []uint64, pageParams *pagination.PageQuickParams) ([]*Status, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	if friendUIDs == nil {
+		friendUIDs = []uint64{}
+	}
+	timelines := make([]*Timeline, 0)
+	chain := db.ODM(ctx).Where(bson.M{
+		"uid": uid,
+		"$or": bson.A{
+			bson.M{"visibility": bson.M{"$nin": []enum.Visibility{enum.VisibilityFriends, enum.VisibilityOnlyMe}}},
+			bson.M{"visibility": enum.VisibilityFriends, "author_uid": bson.M{"$in": friendUIDs}},
+		},
+	})
+	if len(excludeUIDs) > 0 {
+		chain = chain.Where(bson.M{"author_uid": bson.M{"$nin": excludeUIDs}})
+	}
+	paginator := pagination.NewQuickPaginatorWithKey(pageParams.Limit, pageParams.NextID, "status_id", chain)
+	page, err := paginator.Paginate(&timelines)
+	if err != nil {
+		return nil, nil, err
+	}
+	statusIDs := make([]primitive.ObjectID, len(timelines))
+	for i, timeline := range timelines {
+		statusIDs[i] = timeline.StatusID
+	}
+	statuses, err := ListStatusByIDs(ctx, statusIDs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, nil
+}
+
+func insertTimelines(ctx context.Context, entries []interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	_, err := db.DB().Collection("timelines").InsertMany(ctx, entries, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
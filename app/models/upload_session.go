@@ -0,0 +1,249 @@
+package models
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UploadSession is a resumable upload, the chunks are staged in the storage until the upload is complete
+type UploadSession struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	UID      uint64             `bson:"uid"`
+	FileType enum.FileType      `bson:"file_type"`
+	Filename string             `bson:"filename"`
+	// Length is the size of the file, Offset is the size received
+	Length int64          `bson:"length"`
+	Offset int64          `bson:"offset"`
+	Chunks []*UploadChunk `bson:"chunks"`
+	// AttachmentID is set when the chunks are assembled
+	AttachmentID uint64    `bson:"attachment_id,omitempty"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+	CreatedAt    time.Time `bson:"created_at"`
+	UpdatedAt    time.Time `bson:"updated_at"`
+}
+
+type UploadChunk struct {
+	Name   string `bson:"name"`
+	Offset int64  `bson:"offset"`
+	Size   int64  `bson:"size"`
+}
+
+func (*UploadSession) CollectionName() string {
+	return "upload_sessions"
+}
+
+func (s *UploadSession) IsComplete() bool {
+	return s.Offset == s.Length
+}
+
+func (s *UploadSession) chunkFolder() string {
+	return path.Join("tmp", "uploads", s.ID.Hex())
+}
+
+// CreateUploadSession checks the size limit and quota before any chunk is received
+func CreateUploadSession(ctx context.Context, uid uint64, tp enum.FileType, filename string, length int64) (*UploadSession, error) {
+	if length <= 0 {
+		return nil, codes.ErrInvalidArgument.New("invalid upload length")
+	}
+	if limit := maxFileSize(tp); length > limit {
+		return nil, codes.ErrPayloadTooLarge.Newf("%s is larger than %d bytes", tp, limit)
+	}
+	if err := checkStorageQuota(ctx, uid, length); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &UploadSession{
+		UID:       uid,
+		FileType:  tp,
+		Filename:  filename,
+		Length:    length,
+		Chunks:    []*UploadChunk{},
+		ExpiresAt: now.Add(env.Envs.UploadSessionTTL),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	result, err := db.DB().Collection(session.CollectionName()).InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return session, nil
+}
+
+// FindUploadSession finds the session of the user, an expired session is not found
+func FindUploadSession(ctx context.Context, uid uint64, id primitive.ObjectID) (*UploadSession, error) {
+	session := &UploadSession{}
+	err := db.ODM(ctx).First(session, bson.M{"_id": id, "uid": uid, "expires_at": bson.M{"$gt": time.Now()}}).Error
+	if err == mongo.ErrNoDocuments {
+		return nil, codes.ErrNotFound.New("upload session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// AppendChunk stages the chunk at the offset, which must be the offset of the session.
+// A chunk failed halfway is dropped, the client resumes from the offset of the session.
+func (s *UploadSession) AppendChunk(ctx context.Context, offset int64, chunk storage.File) error {
+	if offset != s.Offset {
+		return codes.ErrConflict.Newf("upload offset is %d", s.Offset)
+	}
+	remaining := s.Length - s.Offset
+	reader := &countingReader{reader: io.LimitReader(chunk, remaining+1)}
+	// the name is unique so a chunk of a concurrent request at the same offset is not overwritten
+	name := strconv.FormatInt(offset, 10) + "_" + primitive.NewObjectID().Hex()
+	if err := storage.UploadFile(ctx, s.chunkFolder(), name, reader); err != nil {
+		return err
+	}
+	if reader.size == 0 {
+		return storage.DeleteFile(ctx, s.chunkFolder(), name)
+	}
+	if reader.size > remaining {
+		_ = storage.DeleteFile(ctx, s.chunkFolder(), name)
+		return codes.ErrPayloadTooLarge.New("chunk exceeds the upload length")
+	}
+	now := time.Now()
+	uploadChunk := &UploadChunk{Name: name, Offset: offset, Size: reader.size}
+	result, err := db.DB().Collection(s.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": s.ID, "offset": offset},
+		bson.M{
+			"$set": bson.M{
+				"offset":     offset + reader.size,
+				"expires_at": now.Add(env.Envs.UploadSessionTTL),
+				"updated_at": now,
+			},
+			"$push": bson.M{"chunks": uploadChunk},
+		})
+	if err == nil && result.MatchedCount == 0 {
+		err = codes.ErrConflict.New("upload offset is changed")
+	}
+	if err != nil {
+		_ = storage.DeleteFile(ctx, s.chunkFolder(), name)
+		return err
+	}
+	s.Offset += reader.size
+	s.Chunks = append(s.Chunks, uploadChunk)
+	s.ExpiresAt, s.UpdatedAt = now.Add(env.Envs.UploadSessionTTL), now
+	return nil
+}
+
+// Complete assembles the chunks into an attachment, the chunks are deleted after
+func (s *UploadSession) Complete(ctx context.Context) (*Attachment, error) {
+	if s.AttachmentID != 0 {
+		attachmentMap, err := FindAttachmentMap(ctx, []uint64{s.AttachmentID})
+		if err != nil {
+			return nil, err
+		}
+		if attachment := attachmentMap[s.AttachmentID]; attachment != nil {
+			return attachment, nil
+		}
+		return nil, codes.ErrNotFound.New("attachment not found")
+	}
+	if !s.IsComplete() {
+		return nil, codes.ErrConflict.Newf("upload is incomplete, %d of %d bytes received", s.Offset, s.Length)
+	}
+	file, err := s.assemble(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	attachment, err := CreateAttachment(ctx, s.UID, s.FileType, s.Filename, file)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.DB().Collection(s.CollectionName()).UpdateOne(ctx, bson.M{"_id": s.ID}, bson.M{
+		"$set": bson.M{"attachment_id": attachment.ID, "chunks": []*UploadChunk{}, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.deleteChunks(ctx)
+	s.AttachmentID, s.Chunks = attachment.ID, []*UploadChunk{}
+	return attachment, nil
+}
+
+// assemble copies the chunks into a temp file, which is seekable for the validation of the attachment
+func (s *UploadSession) assemble(ctx context.Context) (*os.File, error) {
+	file, err := ioutil.TempFile("", "upload")
+	if err != nil {
+		return nil, err
+	}
+	err = func() error {
+		for _, chunk := range s.Chunks {
+			reader, err := storage.OpenFile(ctx, s.chunkFolder(), chunk.Name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, reader)
+			reader.Close()
+			if err != nil {
+				return err
+			}
+		}
+		_, err := file.Seek(0, io.SeekStart)
+		return err
+	}()
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete deletes the session and the staged chunks
+func (s *UploadSession) Delete(ctx context.Context) error {
+	s.deleteChunks(ctx)
+	_, err := db.DB().Collection(s.CollectionName()).DeleteOne(ctx, bson.M{"_id": s.ID})
+	return err
+}
+
+func (s *UploadSession) deleteChunks(ctx context.Context) {
+	for _, chunk := range s.Chunks {
+		if err := storage.DeleteFile(ctx, s.chunkFolder(), chunk.Name); err != nil {
+			logrus.Warnf("delete chunk %s of upload session %s error: %v", chunk.Name, s.ID.Hex(), err)
+		}
+	}
+}
+
+// DeleteExpiredUploadSessions deletes the abandoned sessions and the completed sessions after they expire
+func DeleteExpiredUploadSessions(ctx context.Context) (int, error) {
+	sessions := make([]*UploadSession, 0)
+	if err := db.ODM(ctx).Where(bson.M{"expires_at": bson.M{"$lte": time.Now()}}).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	for i, session := range sessions {
+		if err := session.Delete(ctx); err != nil {
+			return i, err
+		}
+	}
+	return len(sessions), nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	size   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	return n, err
+}
@@ -28,6 +28,8 @@ type User struct {
 	AvatarID       uint64      `bson:"avatar_id,omitempty"`
 	FollowingCount int64       `bson:"following_count,omitempty"`
 	FansCount      int64       `bson:"fans_count,omitempty"`
+	IsPrivate      bool        `bson:"is_private,omitempty"`
+	StorageUsed    int64       `bson:"storage_used,omitempty"`
 	CreatedAt      time.Time   `bson:"created_at,omitempty"`
 	UpdatedAt      time.Time   `bson:"updated_at,omitempty"`
 	Avatar         *Attachment `bson:"-"`
@@ -84,6 +86,23 @@ func (u *User) IncFansCount(ctx context.Context) error {
 		}).Err()
 }
 
+// DecFollowCount decreases the following count of the follower and the fans count of the followed user
+func DecFollowCount(ctx context.Context, fromUID, toUID uint64) error {
+	if err := decUserCounter(ctx, fromUID, "following_count"); err != nil {
+		return err
+	}
+	return decUserCounter(ctx, toUID, "fans_count")
+}
+
+// counters never go below zero
+func decUserCounter(ctx context.Context, uid uint64, counterKey string) error {
+	_, err := db.DB().Collection("users").UpdateOne(ctx,
+		bson.M{"_id": uid, counterKey: bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{counterKey: -1}},
+	)
+	return err
+}
+
 func FindUser(ctx context.Context, uid uint64) (*User, error) {
 	user := &User{}
 	result := db.DB().Collection("users").FindOne(ctx, &bson.M{
@@ -95,6 +114,15 @@ func FindUser(ctx context.Context, uid uint64) (*User, error) {
 	return user, result.Decode(user)
 }
 
+func FindUsersByUsernames(ctx context.Context, usernames []string) ([]*User, error) {
+	users := make([]*User, 0)
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	err := db.ODM(ctx).Where(bson.M{"username": bson.M{"$in": usernames}}).Find(&users).Error
+	return users, err
+}
+
 func FindOrCreateUserByMisesid(ctx context.Context, misesid string) (*User, error) {
 	user := &User{}
 	result := db.DB().Collection("users").FindOne(ctx, &bson.M{
@@ -157,6 +185,18 @@ func UpdateUserAvatar(ctx context.Context, user *User) error {
 	return err
 }
 
+func UpdateUserPrivacy(ctx context.Context, user *User) error {
+	_, err := db.DB().Collection("users").UpdateOne(ctx, &bson.M{
+		"_id": user.UID,
+	}, bson.D{{
+		Key: "$set",
+		Value: bson.M{
+			"is_private": user.IsPrivate,
+			"updated_at": time.Now(),
+		}}})
+	return err
+}
+
 func createMisesUser(ctx context.Context, misesid string) (*User, error) {
 	user := &User{
 		Misesid: misesid,
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserRelation is a block or mute from a user to another
+type UserRelation struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	FromUID      uint64             `bson:"from_uid,omitempty"`
+	ToUID        uint64             `bson:"to_uid,omitempty"`
+	RelationType enum.RelationType  `bson:"relation_type"`
+	CreatedAt    time.Time          `bson:"created_at,omitempty"`
+	ToUser       *User              `bson:"-"`
+}
+
+// CreateUserRelation is idempotent, the existing relation is returned
+func CreateUserRelation(ctx context.Context, fromUID, toUID uint64, relationType enum.RelationType) (*UserRelation, error) {
+	relation := &UserRelation{}
+	err := db.DB().Collection("userrelations").FindOneAndUpdate(ctx, bson.M{
+		"from_uid":      fromUID,
+		"to_uid":        toUID,
+		"relation_type": relationType,
+	}, bson.M{
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(relation)
+	if err != nil {
+		return nil, err
+	}
+	return relation, nil
+}
+
+func DeleteUserRelation(ctx context.Context, fromUID, toUID uint64, relationType enum.RelationType) error {
+	_, err := db.DB().Collection("userrelations").DeleteOne(ctx, bson.M{
+		"from_uid":      fromUID,
+		"to_uid":        toUID,
+		"relation_type": relationType,
+	})
+	return err
+}
+
+func ListUserRelation(ctx context.Context, uid uint64, relationType enum.RelationType, pageParams *pagination.PageQuickParams) ([]*UserRelation, pagination.Pagination, error) {
+	if pageParams == nil {
+		pageParams = pagination.DefaultQuickParams()
+	}
+	relations := make([]*UserRelation, 0)
+	chain := db.ODM(ctx).Where(bson.M{"from_uid": uid, "relation_type": relationType})
+	paginator := pagination.NewQuickPaginator(pageParams.Limit, pageParams.NextID, chain)
+	page, err := paginator.Paginate(&relations)
+	if err != nil {
+		return nil, nil, err
+	}
+	return relations, page, preloadRelationUser(ctx, relations)
+}
+
+// IsBlocked reports whether the user and one of the target users block each other
+func IsBlocked(ctx context.Context, uid uint64, targetUIDs ...uint64) (bool, error) {
+	if len(targetUIDs) == 0 {
+		return false, nil
+	}
+	count, err := db.DB().Collection("userrelations").CountDocuments(ctx, bson.M{
+		"relation_type": enum.Block,
+		"$or": bson.A{
+			bson.M{"from_uid": uid, "to_uid": bson.M{"$in": targetUIDs}},
+			bson.M{"from_uid": bson.M{"$in": targetUIDs}, "to_uid": uid},
+		},
+	})
+	return count > 0, err
+}
+
+// ListBlockUIDs returns the users blocked by the user and the users blocking the user
+func ListBlockUIDs(ctx context.Context, uid uint64) ([]uint64, error) {
+	relations, err := findUserRelations(ctx, bson.M{
+		"relation_type": enum.Block,
+		"$or": bson.A{
+			bson.M{"from_uid": uid},
+			bson.M{"to_uid": uid},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]uint64, len(relations))
+	for i, relation := range relations {
+		uids[i] = relation.ToUID
+		if relation.ToUID == uid {
+			uids[i] = relation.FromUID
+		}
+	}
+	return uids, nil
+}
+
+// ListMuteUIDs returns the users muted by the user
+func ListMuteUIDs(ctx context.Context, uid uint64) ([]uint64, error) {
+	relations, err := findUserRelations(ctx, bson.M{
+		"relation_type": enum.Mute,
+		"from_uid":      uid,
+	})
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]uint64, len(relations))
+	for i, relation := range relations {
+		uids[i] = relation.ToUID
+	}
+	return uids, nil
+}
+
+func findUserRelations(ctx context.Context, filter bson.M) ([]*UserRelation, error) {
+	relations := make([]*UserRelation, 0)
+	cursor, err := db.DB().Collection("userrelations").Find(ctx, filter, &options.FindOptions{
+		Projection: bson.M{"from_uid": 1, "to_uid": 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return relations, cursor.All(ctx, &relations)
+}
+
+func preloadRelationUser(ctx context.Context, relations []*UserRelation) error {
+	userIDs := make([]uint64, len(relations))
+	for i, relation := range relations {
+		userIDs[i] = relation.ToUID
+	}
+	users := make([]*User, 0)
+	err := db.ODM(ctx).Where(bson.M{"_id": bson.M{"$in": userIDs}}).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	if err = PreloadUserAvatar(ctx, users...); err != nil {
+		return err
+	}
+	userMap := make(map[uint64]*User)
+	for _, user := range users {
+		userMap[user.UID] = user
+	}
+	for _, relation := range relations {
+		relation.ToUser = userMap[relation.ToUID]
+	}
+	return nil
+}
@@ -6,15 +6,76 @@ import (
 	"strings"
 
 	"github.com/mises-id/sns/app/models"
-
 	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/codes"
 )
 
-func CreateAttachment(ctx context.Context, fileType, filename string, file multipart.File) (*models.Attachment, error) {
-	filenames := strings.Split(filename, "/")
-	tp, err := enum.FileTypeFromString(fileType)
+type CreateAttachmentParams struct {
+	UID      uint64
+	FileType string
+	Filename string
+	File     multipart.File
+	// the poster of a video is an image attachment uploaded before, or an image file uploaded with the video
+	PosterID       uint64
+	PosterFilename string
+	PosterFile     multipart.File
+}
+
+func CreateAttachment(ctx context.Context, params *CreateAttachmentParams) (*models.Attachment, error) {
+	tp, err := enum.FileTypeFromString(params.FileType)
+	if err != nil {
+		return nil, err
+	}
+	hasPoster := params.PosterID != 0 || params.PosterFile != nil
+	if hasPoster && tp != enum.VideoFile {
+		return nil, codes.ErrInvalidArgument.New("only videos have posters")
+	}
+	var poster *models.Attachment
+	if params.PosterID != 0 {
+		if poster, err = findPoster(ctx, params.UID, params.PosterID); err != nil {
+			return nil, err
+		}
+	}
+	attachment, err := models.CreateAttachment(ctx, params.UID, tp, baseFilename(params.Filename), params.File)
 	if err != nil {
 		return nil, err
 	}
-	return models.CreateAttachment(ctx, tp, filenames[len(filenames)-1], file)
+	// a duplicated video keeps the poster linked before
+	if attachment.PosterID != 0 {
+		return attachment, models.PreloadPosters(ctx, attachment)
+	}
+	if params.PosterFile != nil {
+		poster, err = models.CreateAttachment(ctx, params.UID, enum.ImageFile, baseFilename(params.PosterFilename), params.PosterFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if poster != nil {
+		return attachment, attachment.SetPoster(ctx, poster)
+	}
+	return attachment, models.PreloadPosters(ctx, attachment)
+}
+
+// findPoster checks the poster is an image uploaded by the user
+func findPoster(ctx context.Context, uid, posterID uint64) (*models.Attachment, error) {
+	attachmentMap, err := models.FindAttachmentMap(ctx, []uint64{posterID})
+	if err != nil {
+		return nil, err
+	}
+	poster := attachmentMap[posterID]
+	if poster == nil {
+		return nil, codes.ErrInvalidArgument.Newf("attachment %d not found", posterID)
+	}
+	if poster.FileType != enum.ImageFile {
+		return nil, codes.ErrInvalidArgument.Newf("attachment %d is not a %s file", posterID, enum.ImageFile)
+	}
+	if poster.UID != uid {
+		return nil, codes.ErrForbidden.Newf("attachment %d is not uploaded by current user", posterID)
+	}
+	return poster, nil
+}
+
+func baseFilename(filename string) string {
+	filenames := strings.Split(filename, "/")
+	return filenames[len(filenames)-1]
 }
@@ -2,77 +2,264 @@ package follow
 
 import (
 	"context"
+	"errors"
 
 	"github.com/mises-id/sns/app/models"
 	"github.com/mises-id/sns/app/models/enum"
+	notificationSVC "github.com/mises-id/sns/app/services/notification"
+	"github.com/mises-id/sns/config/env"
 	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
 	"github.com/mises-id/sns/lib/pagination"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func ListFriendship(ctx context.Context, uid uint64, relationType enum.RelationType, pageParams *pagination.QuickPagination) ([]*models.Follow, pagination.Pagination, error) {
+// ListFriendship lists the follows of the user, users in a block relation with the current user are filtered out.
+// The follows of a private user are only listed to the followers.
+func ListFriendship(ctx context.Context, currentUID, uid uint64, relationType enum.RelationType, pageParams *pagination.QuickPagination) ([]*models.Follow, pagination.Pagination, error) {
 	// check user exsit
-	_, err := models.FindUser(ctx, uid)
+	user, err := models.FindUser(ctx, uid)
 	if err != nil {
 		return nil, nil, err
 	}
-	return models.ListFollow(ctx, uid, relationType, pageParams)
+	if err = CheckPrivacy(ctx, currentUID, user); err != nil {
+		return nil, nil, err
+	}
+	var excludeUIDs []uint64
+	if currentUID != 0 {
+		if excludeUIDs, err = models.ListBlockUIDs(ctx, currentUID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return models.ListFollow(ctx, uid, relationType, excludeUIDs, pageParams)
 }
 
-func Follow(ctx context.Context, fromUID, toUID uint64) (*models.Follow, error) {
+// Follow follows the user, a follow request is created instead if the user is private
+func Follow(ctx context.Context, fromUID, toUID uint64) (*models.Follow, *models.FollowRequest, error) {
 	if fromUID == toUID {
-		return nil, codes.ErrInvalidArgument
+		return nil, nil, codes.ErrInvalidArgument
 	}
 	fromUser, err := models.FindUser(ctx, fromUID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	toUser, err := models.FindUser(ctx, toUID)
 	if err != nil {
+		return nil, nil, err
+	}
+	blocked, err := models.IsBlocked(ctx, fromUID, toUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if blocked {
+		return nil, nil, codes.ErrForbidden.New("blocked")
+	}
+	_, err = models.GetFollow(ctx, fromUID, toUID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, nil, err
+	}
+	if err == mongo.ErrNoDocuments && toUser.IsPrivate {
+		request, err := requestFollow(ctx, fromUID, toUID)
+		return nil, request, err
+	}
+	follow, created, err := createFollow(ctx, fromUser, toUser, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if created {
+		notificationSVC.NotifyFollow(ctx, fromUID, toUID)
+	}
+	// the request sent while the user was private is fulfilled
+	if _, err = models.DeleteFollowRequest(ctx, fromUID, toUID); err != nil {
+		return nil, nil, err
+	}
+	return follow, nil, nil
+}
+
+// Unfollow deletes the follow and decreases the counters in one transaction, unfollowing twice changes nothing
+func Unfollow(ctx context.Context, fromUID, toUID uint64) error {
+	deleted := false
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if deleted, err = models.DeleteFollow(ctx, fromUID, toUID); err != nil || !deleted {
+			return err
+		}
+		fansFollow, err := models.GetFollow(ctx, toUID, fromUID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if err == nil && fansFollow.IsFriend {
+			if err = fansFollow.SetFriend(ctx, false); err != nil {
+				return err
+			}
+		}
+		return models.DecFollowCount(ctx, fromUID, toUID)
+	})
+	if err != nil || !deleted {
+		return err
+	}
+	return models.RemoveTimelineAuthor(ctx, fromUID, toUID)
+}
+
+func ListFollowRequest(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.FollowRequest, pagination.Pagination, error) {
+	return models.ListFollowRequest(ctx, uid, pageParams)
+}
+
+// ApproveFollowRequest makes the requester a fan of the user, counters are only changed here.
+// The request is deleted in the transaction of the follow, so a failed approval keeps it pending and
+// a concurrent block, which deletes the same request, either finds no request or conflicts with it.
+func ApproveFollowRequest(ctx context.Context, uid, fromUID uint64) (*models.Follow, error) {
+	fromUser, err := models.FindUser(ctx, fromUID)
+	if err != nil {
+		return nil, err
+	}
+	toUser, err := models.FindUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	follow, _, err := createFollow(ctx, fromUser, toUser, func(ctx context.Context) error {
+		// a request sent before a block is not approved after it
+		blocked, err := models.IsBlocked(ctx, uid, fromUID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return codes.ErrForbidden.New("blocked")
+		}
+		deleted, err := models.DeleteFollowRequest(ctx, fromUID, uid)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return codes.ErrNotFound.New("follow request not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	notificationSVC.NotifyFollowApproved(ctx, uid, fromUID)
+	return follow, nil
+}
+
+func RejectFollowRequest(ctx context.Context, uid, fromUID uint64) error {
+	return deleteFollowRequest(ctx, fromUID, uid)
+}
+
+func CancelFollowRequest(ctx context.Context, uid, toUID uint64) error {
+	return deleteFollowRequest(ctx, uid, toUID)
+}
+
+// CheckPrivacy refuses the statuses and friendships of a private user to the users who do not follow the user
+func CheckPrivacy(ctx context.Context, currentUID uint64, user *models.User) error {
+	if !user.IsPrivate || currentUID == user.UID {
+		return nil
+	}
+	if currentUID != 0 {
+		_, err := models.GetFollow(ctx, currentUID, user.UID)
+		if err == nil {
+			return nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+	}
+	return codes.ErrForbidden.New("private user")
+}
+
+func requestFollow(ctx context.Context, fromUID, toUID uint64) (*models.FollowRequest, error) {
+	request, err := models.GetFollowRequest(ctx, fromUID, toUID)
+	if err == nil {
+		return request, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if request, err = models.CreateFollowRequest(ctx, fromUID, toUID); err != nil {
 		return nil, err
 	}
+	notificationSVC.NotifyFollowRequest(ctx, fromUID, toUID)
+	return request, nil
+}
+
+func deleteFollowRequest(ctx context.Context, fromUID, toUID uint64) error {
+	deleted, err := models.DeleteFollowRequest(ctx, fromUID, toUID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return codes.ErrNotFound.New("follow request not found")
+	}
+	return nil
+}
+
+// createFollow reports whether the follow is newly created, an existing follow only gets its friend state synced.
+// The follow, the friend flags and the counters are written in one transaction, a concurrent follow of the
+// other direction conflicts on the user counters and is retried, so the friend flags are always consistent.
+// The check runs first in the transaction, the follow is not saved if it fails.
+func createFollow(ctx context.Context, fromUser, toUser *models.User, check func(ctx context.Context) error) (*models.Follow, bool, error) {
+	var follow *models.Follow
+	created := false
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		if check != nil {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+		var err error
+		follow, created, err = saveFollow(ctx, fromUser, toUser)
+		return err
+	})
+	if err == errFollowExists {
+		follow, err = models.GetFollow(ctx, fromUser.UID, toUser.UID)
+		return follow, false, err
+	}
+	if err != nil || !created {
+		return follow, false, err
+	}
+	return follow, true, models.BackfillTimeline(ctx, fromUser.UID, toUser.UID, env.Envs.TimelineBackfill)
+}
+
+// errFollowExists aborts the transaction when a concurrent request has created the same follow
+var errFollowExists = errors.New("follow exists")
+
+func saveFollow(ctx context.Context, fromUser, toUser *models.User) (*models.Follow, bool, error) {
+	fromUID, toUID := fromUser.UID, toUser.UID
 	isFriend := false
 	follow, err := models.GetFollow(ctx, fromUID, toUID)
 	if err != nil && err != mongo.ErrNoDocuments {
-		return nil, err
+		return nil, false, err
 	}
 	fansFollow, err := models.GetFollow(ctx, toUID, fromUID)
 	if err != nil && err != mongo.ErrNoDocuments {
-		return nil, err
+		return nil, false, err
 	}
 	if err == nil {
 		isFriend = true
 		if !fansFollow.IsFriend {
 			if err = fansFollow.SetFriend(ctx, true); err != nil {
-				return nil, err
+				return nil, false, err
 			}
 		}
 	}
 	if follow != nil {
-		return follow, follow.SetFriend(ctx, isFriend)
+		if follow.IsFriend == isFriend {
+			return follow, false, nil
+		}
+		return follow, false, follow.SetFriend(ctx, isFriend)
 	}
-	if err = fromUser.IncFollowingCount(ctx); err != nil {
-		return nil, err
+	follow, err = models.CreateFollow(ctx, fromUID, toUID, isFriend)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, false, errFollowExists
 	}
-	if err = toUser.IncFansCount(ctx); err != nil {
-		return nil, err
-	}
-
-	return models.CreateFollow(ctx, fromUID, toUID, isFriend)
-}
-
-func Unfollow(ctx context.Context, fromUID, toUID uint64) error {
-	_, err := models.GetFollow(ctx, fromUID, toUID)
 	if err != nil {
-		return nil
+		return nil, false, err
 	}
-	fansFollow, err := models.GetFollow(ctx, toUID, fromUID)
-	if err == nil && fansFollow.IsFriend {
-		if err = fansFollow.SetFriend(ctx, false); err != nil {
-			return err
-		}
-	} else if err != mongo.ErrNoDocuments {
-		return err
+	if err = fromUser.IncFollowingCount(ctx); err != nil {
+		return nil, false, err
+	}
+	if err = toUser.IncFansCount(ctx); err != nil {
+		return nil, false, err
 	}
-	return models.DeleteFollow(ctx, fromUID, toUID)
+	return follow, true, nil
 }
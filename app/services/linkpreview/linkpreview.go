@@ -0,0 +1,110 @@
+package linkpreview
+
+import (
+	"bytes"
+	"context"
+	"mime"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/linkpreview"
+	"github.com/sirupsen/logrus"
+)
+
+var client *linkpreview.Client
+
+func init() {
+	client = linkpreview.New(env.Envs.LinkPreviewTimeout)
+}
+
+// MockFetcher replaces the http fetcher, so tests could unfurl links of a local server
+func MockFetcher(fetcher linkpreview.Fetcher) {
+	client = linkpreview.NewWithFetcher(fetcher)
+}
+
+// Lookup returns the cached preview of the link, only the link and host are filled if it is not cached
+func Lookup(ctx context.Context, link string) (*meta.LinkMeta, bool, error) {
+	u, err := linkpreview.ParseURL(link)
+	if err != nil {
+		return nil, false, codes.ErrInvalidArgument.New("invalid link")
+	}
+	link = u.String()
+	cached, err := models.FindLinkPreview(ctx, link)
+	if err != nil {
+		return nil, false, err
+	}
+	if cached == nil {
+		return &meta.LinkMeta{Link: link, Host: u.Hostname()}, false, nil
+	}
+	return buildLinkMeta(cached), true, nil
+}
+
+// Unfurl builds the link meta from the page of the link, the preview is cached by url.
+// Only the host is kept if the page can not be fetched, the failure is cached until the retry interval.
+func Unfurl(ctx context.Context, link string) (*meta.LinkMeta, error) {
+	linkMeta, cached, err := Lookup(ctx, link)
+	if err != nil || cached {
+		return linkMeta, err
+	}
+	preview, err := fetchPreview(ctx, linkMeta.Link)
+	if err != nil {
+		logrus.Warnf("unfurl link %s error: %v", linkMeta.Link, err)
+		preview = &models.LinkPreview{URL: linkMeta.Link, Host: linkMeta.Host, Failed: true}
+		if err = models.SaveLinkPreview(ctx, preview); err != nil {
+			return nil, err
+		}
+	}
+	return buildLinkMeta(preview), nil
+}
+
+func buildLinkMeta(preview *models.LinkPreview) *meta.LinkMeta {
+	return &meta.LinkMeta{
+		Link:         preview.URL,
+		Title:        preview.Title,
+		Description:  preview.Description,
+		Host:         preview.Host,
+		AttachmentID: preview.AttachmentID,
+	}
+}
+
+func fetchPreview(ctx context.Context, link string) (*models.LinkPreview, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, env.Envs.LinkPreviewTimeout)
+	defer cancel()
+	preview, err := client.Fetch(fetchCtx, link)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.LinkPreview{
+		URL:         link,
+		Title:       preview.Title,
+		Description: preview.Description,
+		Host:        preview.Host,
+	}
+	if preview.ImageURL != "" {
+		attachment, err := downloadImage(fetchCtx, preview.ImageURL)
+		if err != nil {
+			logrus.Warnf("download preview image %s error: %v", preview.ImageURL, err)
+		} else {
+			result.AttachmentID = attachment.ID
+		}
+	}
+	return result, models.SaveLinkPreview(ctx, result)
+}
+
+// downloadImage stores the preview image as an attachment without owner, it is shared by all statuses of the link
+func downloadImage(ctx context.Context, imageURL string) (*models.Attachment, error) {
+	image, err := client.FetchImage(ctx, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	filename := "preview"
+	if image.ContentType == "image/jpeg" {
+		filename += ".jpg"
+	} else if exts, _ := mime.ExtensionsByType(image.ContentType); len(exts) > 0 {
+		filename += exts[0]
+	}
+	return models.CreateAttachment(ctx, 0, enum.ImageFile, filename, bytes.NewReader(image.Data))
+}
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/pagination"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func ListNotification(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.Notification, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", uid)
+	return models.ListNotification(ctxWithUID, uid, pageParams)
+}
+
+func ReadNotifications(ctx context.Context, uid uint64, ids []primitive.ObjectID) error {
+	return models.ReadNotifications(ctx, uid, ids)
+}
+
+func CountUnread(ctx context.Context, uid uint64) (int64, error) {
+	return models.CountUnreadNotification(ctx, uid)
+}
+
+// NotifyStatus notifies the author of the status that the actor acted on it
+func NotifyStatus(ctx context.Context, actorUID uint64, notifyType enum.NotificationType, status *models.Status, sourceID primitive.ObjectID) {
+	notify(ctx, &models.CreateNotificationParams{
+		UID:        status.UID,
+		ActorUID:   actorUID,
+		NotifyType: notifyType,
+		StatusID:   status.ID,
+		SourceID:   sourceID,
+	})
+}
+
+// NotifyMention notifies the user who is mentioned in the status
+func NotifyMention(ctx context.Context, status *models.Status, uid uint64) {
+	notify(ctx, &models.CreateNotificationParams{
+		UID:        uid,
+		ActorUID:   status.UID,
+		NotifyType: enum.NotifyMention,
+		StatusID:   status.ID,
+	})
+}
+
+// NotifyFollow notifies the user of the new fan
+func NotifyFollow(ctx context.Context, actorUID, uid uint64) {
+	notify(ctx, &models.CreateNotificationParams{
+		UID:        uid,
+		ActorUID:   actorUID,
+		NotifyType: enum.NotifyFollow,
+	})
+}
+
+// NotifyFollowRequest notifies the private user of the new follow request
+func NotifyFollowRequest(ctx context.Context, actorUID, uid uint64) {
+	notify(ctx, &models.CreateNotificationParams{
+		UID:        uid,
+		ActorUID:   actorUID,
+		NotifyType: enum.NotifyFollowRequest,
+	})
+}
+
+// NotifyFollowApproved notifies the requester that the private user approved the request
+func NotifyFollowApproved(ctx context.Context, actorUID, uid uint64) {
+	notify(ctx, &models.CreateNotificationParams{
+		UID:        uid,
+		ActorUID:   actorUID,
+		NotifyType: enum.NotifyFollowApproved,
+	})
+}
+
+// notifications are side effects, failures are logged instead of failing the action
+func notify(ctx context.Context, params *models.CreateNotificationParams) {
+	if params.UID == 0 || params.UID == params.ActorUID {
+		return
+	}
+	if err := models.CreateNotification(ctx, params); err != nil {
+		logrus.Errorf("create %s notification error: %v", params.NotifyType, err)
+	}
+}
@@ -0,0 +1,94 @@
+package relation
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	followSVC "github.com/mises-id/sns/app/services/follow"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+)
+
+func ListRelation(ctx context.Context, uid uint64, relationType enum.RelationType, pageParams *pagination.PageQuickParams) ([]*models.UserRelation, pagination.Pagination, error) {
+	if relationType != enum.Block && relationType != enum.Mute {
+		return nil, nil, codes.ErrInvalidArgument.Newf("invalid relation type %s", relationType)
+	}
+	return models.ListUserRelation(ctx, uid, relationType, pageParams)
+}
+
+// Block removes the follows and follow requests between the users in both directions
+func Block(ctx context.Context, fromUID, toUID uint64) (*models.UserRelation, error) {
+	if err := checkTarget(ctx, fromUID, toUID); err != nil {
+		return nil, err
+	}
+	relation, err := models.CreateUserRelation(ctx, fromUID, toUID, enum.Block)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = models.DeleteFollowRequest(ctx, fromUID, toUID); err != nil {
+		return nil, err
+	}
+	if _, err = models.DeleteFollowRequest(ctx, toUID, fromUID); err != nil {
+		return nil, err
+	}
+	if err = followSVC.Unfollow(ctx, fromUID, toUID); err != nil {
+		return nil, err
+	}
+	return relation, followSVC.Unfollow(ctx, toUID, fromUID)
+}
+
+func Unblock(ctx context.Context, fromUID, toUID uint64) error {
+	return models.DeleteUserRelation(ctx, fromUID, toUID, enum.Block)
+}
+
+// Mute hides the statuses of the user from the feeds of the muter
+func Mute(ctx context.Context, fromUID, toUID uint64) (*models.UserRelation, error) {
+	if err := checkTarget(ctx, fromUID, toUID); err != nil {
+		return nil, err
+	}
+	return models.CreateUserRelation(ctx, fromUID, toUID, enum.Mute)
+}
+
+func Unmute(ctx context.Context, fromUID, toUID uint64) error {
+	return models.DeleteUserRelation(ctx, fromUID, toUID, enum.Mute)
+}
+
+// CheckBlocked forbids the user to interact with users in a block relation with the user
+func CheckBlocked(ctx context.Context, uid uint64, targetUIDs ...uint64) error {
+	blocked, err := models.IsBlocked(ctx, uid, targetUIDs...)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return codes.ErrForbidden.New("blocked")
+	}
+	return nil
+}
+
+// HiddenUIDs returns the users hidden from the user, muted users are only hidden from feeds
+func HiddenUIDs(ctx context.Context, uid uint64, includeMuted bool) ([]uint64, error) {
+	if uid == 0 {
+		return nil, nil
+	}
+	uids, err := models.ListBlockUIDs(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !includeMuted {
+		return uids, nil
+	}
+	mutedUIDs, err := models.ListMuteUIDs(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return append(uids, mutedUIDs...), nil
+}
+
+func checkTarget(ctx context.Context, fromUID, toUID uint64) error {
+	if fromUID == toUID {
+		return codes.ErrInvalidArgument
+	}
+	_, err := models.FindUser(ctx, toUID)
+	return err
+}
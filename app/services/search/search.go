@@ -0,0 +1,55 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mises-id/sns/app/models"
+	relationSVC "github.com/mises-id/sns/app/services/relation"
+	statusSVC "github.com/mises-id/sns/app/services/status"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+)
+
+const maxQueryLength = 100
+
+func SearchStatus(ctx context.Context, currentUID uint64, query string, pageParams *pagination.TraditionalParams) ([]*models.Status, pagination.Pagination, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, currentUID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses, page, err := models.SearchStatus(ctxWithUID, query, excludeUIDs, pageParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, statusSVC.BatchSetIsLiked(ctx, currentUID, statuses...)
+}
+
+func SearchUser(ctx context.Context, currentUID uint64, query string, pageParams *pagination.TraditionalParams) ([]*models.User, pagination.Pagination, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, currentUID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return models.SearchUser(ctxWithUID, query, excludeUIDs, pageParams)
+}
+
+func normalizeQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", codes.ErrInvalidArgument.New("empty search query")
+	}
+	if len([]rune(query)) > maxQueryLength {
+		return "", codes.ErrInvalidArgument.Newf("search query is longer than %d characters", maxQueryLength)
+	}
+	return query, nil
+}
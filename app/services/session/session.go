@@ -2,59 +2,248 @@ package session
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/mises-id/sns/app/models"
 	"github.com/mises-id/sns/config/env"
 	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/jwks"
 	"github.com/mises-id/sns/lib/mises"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
-	secret      = env.Envs.JWTSecret
 	misesClient mises.Client
+	keySet      *jwks.KeySet
 )
 
+// Token is the access token of a session and the refresh token to renew it
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// accessClaims are the claims of the access token, all of them are required
+type accessClaims struct {
+	UID       uint64 `json:"uid"`
+	Misesid   string `json:"misesid"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
+	jwt.StandardClaims
+}
+
+func (c *accessClaims) Valid() error {
+	if err := c.StandardClaims.Valid(); err != nil {
+		return err
+	}
+	if c.UID == 0 || c.SessionID == "" || c.ExpiresAt == 0 || !c.VerifyIssuer(env.Envs.JWTIssuer, true) {
+		return jwt.NewValidationError("missing or invalid claims", jwt.ValidationErrorClaimsInvalid)
+	}
+	return nil
+}
+
+// legacyClaims are the claims of the HS256 tokens issued before the sessions, they are accepted until they expire
+// within the window after the cutoff
+type legacyClaims struct {
+	UID      uint64 `json:"uid"`
+	Misesid  string `json:"misesid"`
+	Username string `json:"username"`
+	jwt.StandardClaims
+}
+
+func (c *legacyClaims) Valid() error {
+	if err := c.StandardClaims.Valid(); err != nil {
+		return err
+	}
+	if c.UID == 0 || c.ExpiresAt == 0 {
+		return jwt.NewValidationError("missing or invalid claims", jwt.ValidationErrorClaimsInvalid)
+	}
+	return nil
+}
+
 func init() {
 	misesClient = mises.New()
+	var err error
+	if keySet, err = loadKeySet(); err != nil {
+		logrus.Fatalf("load jwt keys error: %v", err)
+	}
+	if _, _, err = legacyTokenDeadline(); err != nil {
+		logrus.Fatalf("invalid LEGACY_TOKEN_CUTOFF: %v", err)
+	}
+}
+
+// legacyTokenDeadline is the latest expiry of the legacy tokens, which were issued before LEGACY_TOKEN_CUTOFF
+// and lived for LEGACY_TOKEN_DURATION. The legacy tokens are rejected without JWT_SECRET or the cutoff.
+func legacyTokenDeadline() (time.Time, bool, error) {
+	if env.Envs.JWTSecret == "" || env.Envs.LegacyTokenCutoff == "" {
+		return time.Time{}, false, nil
+	}
+	cutoff, err := time.Parse(time.RFC3339, env.Envs.LegacyTokenCutoff)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return cutoff.Add(env.Envs.LegacyTokenDuration), true, nil
+}
+
+// loadKeySet loads the keys of the dir, only development and test use a temporary key without the dir
+func loadKeySet() (*jwks.KeySet, error) {
+	if env.Envs.JWTKeysDir == "" {
+		if env.Envs.AppEnv != "development" && env.Envs.AppEnv != "test" {
+			return nil, jwks.ErrNoKey
+		}
+		logrus.Warn("JWT_KEYS_DIR is not set, access tokens are signed by a temporary key")
+		key, err := jwks.GenerateKey("temporary")
+		if err != nil {
+			return nil, err
+		}
+		return jwks.NewKeySet("", key)
+	}
+	keys, err := jwks.LoadDir(env.Envs.JWTKeysDir)
+	if err != nil {
+		return nil, err
+	}
+	return jwks.NewKeySet(env.Envs.JWTSigningKeyID, keys...)
 }
 
-func SignIn(ctx context.Context, auth string) (string, error) {
+// KeySet is the keys signing and verifying the access tokens
+func KeySet() *jwks.KeySet {
+	return keySet
+}
+
+// SignIn creates a session of the device
+func SignIn(ctx context.Context, auth string, device *models.Device) (*Token, error) {
 	misesid, err := misesClient.Auth(auth)
 	if err != nil {
 		logrus.Errorf("mises verify error: %v", err)
-		return "", codes.ErrAuthorizeFailed
+		return nil, codes.ErrAuthorizeFailed
 	}
 	user, err := models.FindOrCreateUserByMisesid(ctx, misesid)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"uid":      user.UID,
-		"misesid":  user.Misesid,
-		"username": user.Username,
-		"exp":      time.Now().Add(env.Envs.TokenDuration).Unix(),
+	session, refreshToken, err := models.CreateSession(ctx, user.UID, device)
+	if err != nil {
+		return nil, err
+	}
+	return issueToken(user, session, refreshToken)
+}
+
+// Refresh rotates the refresh token and issues a new access token of the session
+func Refresh(ctx context.Context, refreshToken string, device *models.Device) (*Token, error) {
+	if refreshToken == "" {
+		return nil, codes.ErrInvalidArgument.New("refresh token is required")
+	}
+	session, refreshToken, err := models.RefreshSession(ctx, refreshToken, device)
+	if err != nil {
+		return nil, err
+	}
+	user, err := models.FindUser(ctx, session.UID)
+	if err != nil {
+		return nil, err
+	}
+	return issueToken(user, session, refreshToken)
+}
+
+// Auth returns the user and the session of the access token, the token of a revoked session is rejected.
+// A legacy token has no session, nil is returned as the session of it.
+func Auth(ctx context.Context, authToken string) (*models.User, *models.Session, error) {
+	claims := &accessClaims{}
+	if _, err := keySet.Parse(authToken, claims); err != nil {
+		if isExpired(err) {
+			return nil, nil, codes.ErrTokenExpired
+		}
+		// every legacy token is expired after the deadline, the fallback is skipped then
+		deadline, ok, _ := legacyTokenDeadline()
+		if !ok || time.Now().After(deadline) {
+			return nil, nil, codes.ErrUnauthorized.New("invalid auth token")
+		}
+		return authLegacyToken(authToken, deadline)
+	}
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return nil, nil, codes.ErrUnauthorized.New("invalid session")
+	}
+	session, err := models.FindActiveSession(ctx, claims.UID, sessionID)
+	if codes.ErrNotFound.Equal(err) {
+		return nil, nil, codes.ErrUnauthorized.New("session is revoked")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &models.User{
+		UID:      claims.UID,
+		Misesid:  claims.Misesid,
+		Username: claims.Username,
+	}, session, nil
+}
+
+// authLegacyToken verifies the HS256 token signed by JWT_SECRET without a kid, a token expiring after the deadline
+// was not issued before the cutoff
+func authLegacyToken(authToken string, deadline time.Time) (*models.User, *models.Session, error) {
+	claims := &legacyClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+	_, err := parser.ParseWithClaims(authToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Header["kid"]; ok {
+			return nil, errors.New("legacy token has no kid")
+		}
+		return []byte(env.Envs.JWTSecret), nil
 	})
-	return at.SignedString([]byte(secret))
+	if isExpired(err) {
+		return nil, nil, codes.ErrTokenExpired
+	}
+	if err != nil || claims.ExpiresAt > deadline.Unix() {
+		return nil, nil, codes.ErrUnauthorized.New("invalid auth token")
+	}
+	return &models.User{
+		UID:      claims.UID,
+		Misesid:  claims.Misesid,
+		Username: claims.Username,
+	}, nil, nil
+}
+
+func isExpired(err error) bool {
+	validationErr, ok := err.(*jwt.ValidationError)
+	return ok && validationErr.Errors == jwt.ValidationErrorExpired
 }
 
-func Auth(ctx context.Context, authToken string) (*models.User, error) {
-	claim, err := jwt.Parse(authToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+func ListSessions(ctx context.Context, uid uint64) ([]*models.Session, error) {
+	return models.ListActiveSessions(ctx, uid)
+}
+
+// RevokeSession signs out the session of the user
+func RevokeSession(ctx context.Context, uid uint64, id primitive.ObjectID) error {
+	session, err := models.FindActiveSession(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	return session.Revoke(ctx)
+}
+
+func issueToken(user *models.User, session *models.Session, refreshToken string) (*Token, error) {
+	expiresAt := time.Now().Add(env.Envs.TokenDuration)
+	accessToken, err := keySet.Sign(&accessClaims{
+		UID:       user.UID,
+		Misesid:   user.Misesid,
+		Username:  user.Username,
+		SessionID: session.ID.Hex(),
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    env.Envs.JWTIssuer,
+			Subject:   strconv.FormatUint(user.UID, 10),
+			ExpiresAt: expiresAt.Unix(),
+		},
 	})
 	if err != nil {
-		if err.Error() == "Token is expired" {
-			return nil, codes.ErrTokenExpired
-		}
 		return nil, err
 	}
-	mapClaims := claim.Claims.(jwt.MapClaims)
-	return &models.User{
-		UID:      uint64(mapClaims["uid"].(float64)),
-		Misesid:  mapClaims["misesid"].(string),
-		Username: mapClaims["username"].(string),
+	return &Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
 	}, nil
 }
 
@@ -0,0 +1,134 @@
+package status
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	relationSVC "github.com/mises-id/sns/app/services/relation"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// how many latest replies are listed with each top level comment
+const commentRepliesPreload = 3
+
+type CreateCommentParams struct {
+	StatusID primitive.ObjectID
+	// CommentID is the comment replied to, empty for a top level comment
+	CommentID primitive.ObjectID
+	Content   string
+}
+
+func ListComment(ctx context.Context, currentUID uint64, statusID primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	status, err := models.FindStatusIncludeDeleted(ctx, statusID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, nil, err
+	}
+	if status != nil {
+		if err = checkVisible(ctx, currentUID, status); err != nil {
+			return nil, nil, err
+		}
+	}
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, currentUID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	comments, page, err := models.ListCommentStatus(ctxWithUID, statusID, commentRepliesPreload, excludeUIDs, pageParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses := make([]*models.Status, 0, len(comments))
+	for _, comment := range comments {
+		statuses = append(statuses, comment)
+		statuses = append(statuses, comment.Replies...)
+	}
+	return comments, page, BatchSetIsLiked(ctx, currentUID, statuses...)
+}
+
+// ListCommentReplies lists the replies of a top level comment, the comment may have been deleted
+func ListCommentReplies(ctx context.Context, currentUID uint64, commentID primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	comment, err := models.FindStatusIncludeDeleted(ctx, commentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment.FromType != enum.FromComment || comment.IsReply() {
+		return nil, nil, codes.ErrNotFound
+	}
+	if err = checkVisible(ctx, currentUID, comment); err != nil {
+		return nil, nil, err
+	}
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, currentUID, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	replies, page, err := models.ListCommentReplies(ctxWithUID, commentID, excludeUIDs, pageParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	return replies, page, BatchSetIsLiked(ctx, currentUID, replies...)
+}
+
+func CreateComment(ctx context.Context, uid uint64, params *CreateCommentParams) (*models.Status, error) {
+	createParams := &CreateStatusParams{
+		StatusType: enum.TextStatus.String(),
+		Content:    params.Content,
+		ParentID:   params.StatusID,
+		FromType:   enum.FromComment,
+	}
+	if !params.CommentID.IsZero() {
+		comment, err := findComment(ctx, params.CommentID)
+		if err != nil {
+			return nil, err
+		}
+		if !params.StatusID.IsZero() && params.StatusID != commentRootID(comment) {
+			return nil, codes.ErrInvalidArgument.New("comment does not belong to the status")
+		}
+		createParams.ReplyTo = comment
+	}
+	return CreateStatus(ctx, uid, createParams)
+}
+
+// setReplyThread flattens the reply into the thread of the top level comment
+func setReplyThread(params *models.CreateStatusParams, replyTo *models.Status) {
+	params.RootID = commentRootID(replyTo)
+	params.ParentID = replyTo.ID
+	if replyTo.IsReply() {
+		params.ParentID = replyTo.ParentID
+	}
+	params.ReplyToUID = replyTo.UID
+}
+
+// checkBlocked forbids commenting, replying or forwarding when the user and the authors of the thread block each other
+func checkBlocked(ctx context.Context, uid uint64, params *models.CreateStatusParams) error {
+	if params.ParentID.IsZero() {
+		return nil
+	}
+	targetUIDs := make([]uint64, 0)
+	for _, id := range []primitive.ObjectID{params.ParentID, params.RootID} {
+		if id.IsZero() {
+			continue
+		}
+		status, err := models.FindStatusIncludeDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		targetUIDs = append(targetUIDs, status.UID)
+	}
+	if params.ReplyToUID != 0 {
+		targetUIDs = append(targetUIDs, params.ReplyToUID)
+	}
+	return relationSVC.CheckBlocked(ctx, uid, targetUIDs...)
+}
+
+// comments created before threading have no root id, their parent is the root
+func commentRootID(comment *models.Status) primitive.ObjectID {
+	if comment.RootID.IsZero() {
+		return comment.ParentID
+	}
+	return comment.RootID
+}
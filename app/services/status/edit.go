@@ -0,0 +1,84 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mises-id/sns/app/jobs"
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/pagination"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EditStatusParams struct {
+	Content string
+	// Meta keeps the current meta if empty
+	Meta json.RawMessage
+}
+
+// EditStatus replaces the content and meta of the status within the edit window after it is created
+func EditStatus(ctx context.Context, uid uint64, id primitive.ObjectID, params *EditStatusParams) (*models.Status, error) {
+	status, err := models.FindStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status.UID != uid {
+		return nil, codes.ErrForbidden
+	}
+	if time.Since(status.CreatedAt) > env.Envs.StatusEditWindow {
+		return nil, codes.ErrForbidden.New("status can not be edited any more")
+	}
+	updateParams := &models.UpdateStatusParams{Content: params.Content}
+	unfurled := true
+	if len(params.Meta) > 0 {
+		metaData, err := meta.BuildStatusMeta(status.StatusType, params.Meta)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateStatusMeta(ctx, uid, status.StatusType, metaData); err != nil {
+			return nil, err
+		}
+		if status.StatusType == enum.LinkStatus {
+			if unfurled, err = lookupLinkMeta(ctx, metaData.(*meta.LinkMeta)); err != nil {
+				return nil, err
+			}
+		}
+		updateParams.MetaData = metaData
+	}
+	if updateParams.Mentions, err = resolveMentions(ctx, params.Content); err != nil {
+		return nil, err
+	}
+	// only the users newly mentioned by the edit are notified
+	mentionedUIDs := make([]uint64, len(status.Mentions))
+	for i, mention := range status.Mentions {
+		mentionedUIDs[i] = mention.UID
+	}
+	if err = models.UpdateStatus(ctx, status, updateParams); err != nil {
+		return nil, err
+	}
+	if !unfurled {
+		if err = jobs.EnqueueUnfurlLink(ctx, status.ID); err != nil {
+			logrus.Errorf("enqueue unfurl link of status %s error: %v", status.ID.Hex(), err)
+		}
+	}
+	notifyMentions(ctx, status, mentionedUIDs...)
+	return GetStatus(ctx, uid, id)
+}
+
+// ListStatusRevision lists the prior versions of the status visible to the user
+func ListStatusRevision(ctx context.Context, currentUID uint64, id primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*models.StatusRevision, pagination.Pagination, error) {
+	status, err := models.FindStatus(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = checkVisible(ctx, currentUID, status); err != nil {
+		return nil, nil, err
+	}
+	return models.ListStatusRevision(ctx, id, pageParams)
+}
@@ -0,0 +1,90 @@
+package status
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	notificationSVC "github.com/mises-id/sns/app/services/notification"
+	relationSVC "github.com/mises-id/sns/app/services/relation"
+	"github.com/mises-id/sns/lib/pagination"
+	"github.com/mises-id/sns/lib/textparse"
+)
+
+// at most this many users are mentioned in a status, the rest are kept as plain text
+const maxStatusMentions = 10
+
+// ListMentionStatus lists the statuses and comments which mention the user
+func ListMentionStatus(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", uid)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, uid, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses, page, err := models.ListStatus(ctxWithUID, &models.ListStatusParams{
+		MentionUID:  uid,
+		ExcludeUIDs: excludeUIDs,
+		PageParams:  pageParams,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if statuses, err = filterVisible(ctx, uid, statuses...); err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, BatchSetIsLiked(ctx, uid, statuses...)
+}
+
+// resolveMentions finds the users of the @usernames in the content, unknown usernames are skipped.
+// Usernames are unique case-sensitively, so they are matched exactly, @Carol does not mention carol.
+func resolveMentions(ctx context.Context, content string) ([]*models.Mention, error) {
+	entities := textparse.Mentions(content)
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	usernames := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, entity := range entities {
+		if !seen[entity.Text] && len(usernames) < maxStatusMentions {
+			seen[entity.Text] = true
+			usernames = append(usernames, entity.Text)
+		}
+	}
+	users, err := models.FindUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+	userMap := make(map[string]*models.User)
+	for _, user := range users {
+		userMap[user.Username] = user
+	}
+	mentions := make([]*models.Mention, 0)
+	for _, entity := range entities {
+		if user := userMap[entity.Text]; user != nil {
+			mentions = append(mentions, &models.Mention{
+				UID:      user.UID,
+				Username: user.Username,
+				Start:    entity.Start,
+				End:      entity.End,
+			})
+		}
+	}
+	return mentions, nil
+}
+
+// notifyMentions skips the users already notified
+func notifyMentions(ctx context.Context, status *models.Status, notifiedUIDs ...uint64) {
+	notified := make(map[uint64]bool)
+	for _, uid := range notifiedUIDs {
+		notified[uid] = true
+	}
+	for _, mention := range status.Mentions {
+		if notified[mention.UID] {
+			continue
+		}
+		notified[mention.UID] = true
+		if relationSVC.CheckBlocked(ctx, status.UID, mention.UID) != nil || checkVisible(ctx, mention.UID, status) != nil {
+			continue
+		}
+		notificationSVC.NotifyMention(ctx, status, mention.UID)
+	}
+}
@@ -4,21 +4,36 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/mises-id/sns/app/jobs"
 	"github.com/mises-id/sns/app/models"
 	"github.com/mises-id/sns/app/models/enum"
 	"github.com/mises-id/sns/app/models/meta"
+	followSVC "github.com/mises-id/sns/app/services/follow"
+	linkpreviewSVC "github.com/mises-id/sns/app/services/linkpreview"
+	notificationSVC "github.com/mises-id/sns/app/services/notification"
+	relationSVC "github.com/mises-id/sns/app/services/relation"
 	"github.com/mises-id/sns/lib/codes"
 	"github.com/mises-id/sns/lib/pagination"
+	"github.com/mises-id/sns/lib/textparse"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const (
+	maxStatusImages = 9
+	maxStatusVideos = 1
+)
+
 type CreateStatusParams struct {
 	StatusType string
 	ParentID   primitive.ObjectID
 	Content    string
 	Meta       json.RawMessage
 	FromType   enum.FromType
+	Visibility enum.Visibility
+	// ReplyTo is the comment replied to, the reply is put into the thread of its top level comment
+	ReplyTo *models.Status
 }
 
 type ListStatusParams struct {
@@ -35,64 +50,112 @@ func GetStatus(ctx context.Context, currentUID uint64, id primitive.ObjectID) (*
 	if err != nil {
 		return nil, err
 	}
-	return status, batchSetIsLiked(ctx, currentUID, status)
+	if err = checkVisible(ctx, currentUID, status); err != nil {
+		return nil, err
+	}
+	return status, BatchSetIsLiked(ctx, currentUID, status)
 }
 
 func ListStatus(ctx context.Context, params *ListStatusParams) ([]*models.Status, pagination.Pagination, error) {
 	ctxWithUID := context.WithValue(ctx, "CurrentUID", params.CurrentUID)
 
 	uids := make([]uint64, 0)
+	var hidden []enum.Visibility
 	if params.UID != 0 {
 		uids = append(uids, params.UID)
+		user, err := models.FindUser(ctx, params.UID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, nil, err
+		}
+		if user != nil {
+			if err = followSVC.CheckPrivacy(ctx, params.CurrentUID, user); err != nil {
+				return nil, nil, err
+			}
+		}
+		if hidden, err = hiddenVisibilities(ctx, params.CurrentUID, params.UID); err != nil {
+			return nil, nil, err
+		}
+	}
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, params.CurrentUID, false)
+	if err != nil {
+		return nil, nil, err
 	}
 	listParams := &models.ListStatusParams{
 		UIDs:           uids,
 		ParentStatusID: params.ParentID,
 		PageParams:     params.PageQuickParams,
 		FromTypes:      params.FromTypes,
+		ExcludeUIDs:    excludeUIDs,
+		// statuses of the other users are filtered by visibility in listing the statuses of a user
+		ExcludeVisibilities: hidden,
 	}
 	statues, page, err := models.ListStatus(ctxWithUID, listParams)
 	if err != nil {
 		return nil, nil, err
 	}
-	return statues, page, batchSetIsLiked(ctx, params.CurrentUID, statues...)
+	return statues, page, BatchSetIsLiked(ctx, params.CurrentUID, statues...)
+}
+
+// ListTagStatus lists the posts and forwards with the hashtag
+func ListTagStatus(ctx context.Context, currentUID uint64, tag string, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
+	normalized := textparse.NormalizeHashtag(tag)
+	if normalized == "" {
+		return nil, nil, codes.ErrInvalidArgument.Newf("invalid tag %s", tag)
+	}
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, currentUID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	statuses, page, err := models.ListStatus(ctxWithUID, &models.ListStatusParams{
+		Tag:                 normalized,
+		FromTypes:           []enum.FromType{enum.FromPost, enum.FromForward},
+		ExcludeUIDs:         excludeUIDs,
+		ExcludeVisibilities: enum.NonPublicVisibilities,
+		PageParams:          pageParams,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, BatchSetIsLiked(ctx, currentUID, statuses...)
 }
 
 func UserTimeline(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
 	ctxWithUID := context.WithValue(ctx, "CurrentUID", uid)
-	friendIDs, err := models.ListFollowingUserIDs(ctx, uid)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, uid, true)
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(friendIDs) == 0 {
-		return []*models.Status{}, &pagination.QuickPagination{
-			Limit: pageParams.Limit,
-		}, nil
+	// the follow may no longer be a friend since the status is fanned out
+	friendUIDs, err := models.ListFriendUIDs(ctx, uid)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	statues, page, err := models.ListStatus(ctxWithUID, &models.ListStatusParams{
-		UIDs:           friendIDs,
-		ParentStatusID: primitive.NilObjectID,
-		PageParams:     pageParams,
-	})
+	statues, page, err := models.ListTimelineStatus(ctxWithUID, uid, excludeUIDs, friendUIDs, pageParams)
 	if err != nil {
 		return nil, nil, err
 	}
-	return statues, page, batchSetIsLiked(ctx, uid, statues...)
+	return statues, page, BatchSetIsLiked(ctx, uid, statues...)
 }
 
 func RecommendStatus(ctx context.Context, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
 	ctxWithUID := context.WithValue(ctx, "CurrentUID", uid)
+	excludeUIDs, err := relationSVC.HiddenUIDs(ctx, uid, true)
+	if err != nil {
+		return nil, nil, err
+	}
 	statues, page, err := models.ListStatus(ctxWithUID, &models.ListStatusParams{
-		UIDs:           nil,
-		ParentStatusID: primitive.NilObjectID,
-		FromTypes:      []enum.FromType{enum.FromPost, enum.FromForward},
-		PageParams:     pageParams,
+		UIDs:                nil,
+		ParentStatusID:      primitive.NilObjectID,
+		FromTypes:           []enum.FromType{enum.FromPost, enum.FromForward},
+		ExcludeUIDs:         excludeUIDs,
+		ExcludeVisibilities: enum.NonPublicVisibilities,
+		PageParams:          pageParams,
 	})
 	if err != nil {
 		return nil, nil, err
 	}
-	return statues, page, batchSetIsLiked(ctx, uid, statues...)
+	return statues, page, BatchSetIsLiked(ctx, uid, statues...)
 }
 
 func CreateStatus(ctx context.Context, uid uint64, params *CreateStatusParams) (*models.Status, error) {
@@ -104,14 +167,150 @@ func CreateStatus(ctx context.Context, uid uint64, params *CreateStatusParams) (
 	if err != nil {
 		return nil, err
 	}
-	return models.CreateStatus(ctx, &models.CreateStatusParams{
+	if err = validateStatusMeta(ctx, uid, statusType, metaData); err != nil {
+		return nil, err
+	}
+	unfurled := true
+	if statusType == enum.LinkStatus {
+		if unfurled, err = lookupLinkMeta(ctx, metaData.(*meta.LinkMeta)); err != nil {
+			return nil, err
+		}
+	}
+	createParams := &models.CreateStatusParams{
 		UID:        uid,
 		StatusType: statusType,
 		Content:    params.Content,
 		ParentID:   params.ParentID,
 		FromType:   params.FromType,
 		MetaData:   metaData,
-	})
+	}
+	if params.FromType != enum.FromComment {
+		createParams.Visibility = params.Visibility
+	}
+	if params.ReplyTo != nil {
+		setReplyThread(createParams, params.ReplyTo)
+	}
+	if err = checkParentVisible(ctx, uid, createParams); err != nil {
+		return nil, err
+	}
+	if err = checkBlocked(ctx, uid, createParams); err != nil {
+		return nil, err
+	}
+	if createParams.Mentions, err = resolveMentions(ctx, params.Content); err != nil {
+		return nil, err
+	}
+	status, err := models.CreateStatus(ctx, createParams)
+	if err != nil {
+		return nil, err
+	}
+	if !unfurled {
+		if err = jobs.EnqueueUnfurlLink(ctx, status.ID); err != nil {
+			logrus.Errorf("enqueue unfurl link of status %s error: %v", status.ID.Hex(), err)
+		}
+	}
+	if (status.FromType == enum.FromPost || status.FromType == enum.FromForward) && status.Visibility != enum.VisibilityOnlyMe {
+		if err = jobs.EnqueueFanOut(ctx, status.ID); err != nil {
+			logrus.Errorf("enqueue fan out status %s error: %v", status.ID.Hex(), err)
+		}
+	}
+	// the author who is notified of the reply, comment or forward is not notified of the mention again
+	var notifiedUID uint64
+	switch {
+	case params.ReplyTo != nil:
+		notificationSVC.NotifyStatus(ctx, uid, enum.NotifyReply, params.ReplyTo, status.ID)
+		notifiedUID = params.ReplyTo.UID
+	case status.ParentStatus != nil && status.FromType == enum.FromComment:
+		notificationSVC.NotifyStatus(ctx, uid, enum.NotifyComment, status.ParentStatus, status.ID)
+		notifiedUID = status.ParentStatus.UID
+	case status.ParentStatus != nil && status.FromType == enum.FromForward:
+		notificationSVC.NotifyStatus(ctx, uid, enum.NotifyForward, status.ParentStatus, status.ID)
+		notifiedUID = status.ParentStatus.UID
+	}
+	notifyMentions(ctx, status, notifiedUID)
+	return status, nil
+}
+
+// checkParentVisible requires the status commented or forwarded to be visible to the user,
+// a forward never widens the audience of the forwarded status
+func checkParentVisible(ctx context.Context, uid uint64, params *models.CreateStatusParams) error {
+	if params.ParentID.IsZero() {
+		return nil
+	}
+	parent, err := models.FindStatusIncludeDeleted(ctx, params.ParentID)
+	if err != nil {
+		return err
+	}
+	if err = checkVisible(ctx, uid, parent); err != nil {
+		return err
+	}
+	if params.FromType == enum.FromForward {
+		params.Visibility, err = forwardVisibility(ctx, uid, params.Visibility, parent)
+	}
+	return err
+}
+
+func validateStatusMeta(ctx context.Context, uid uint64, statusType enum.StatusType, metaData meta.MetaData) error {
+	switch statusType {
+	case enum.LinkStatus:
+		linkMeta, ok := metaData.(*meta.LinkMeta)
+		if !ok || linkMeta.Link == "" {
+			return codes.ErrInvalidArgument.New("link status requires a link")
+		}
+	case enum.ImageStatus:
+		imageMeta, ok := metaData.(*meta.ImageMeta)
+		if !ok || len(imageMeta.Images) == 0 || len(imageMeta.Images) > maxStatusImages {
+			return codes.ErrInvalidArgument.Newf("image status requires 1 to %d images", maxStatusImages)
+		}
+		return validateMediaItems(ctx, uid, enum.ImageFile, imageMeta.Images)
+	case enum.VideoStatus:
+		videoMeta, ok := metaData.(*meta.VideoMeta)
+		if !ok || len(videoMeta.Videos) == 0 || len(videoMeta.Videos) > maxStatusVideos {
+			return codes.ErrInvalidArgument.Newf("video status requires 1 to %d videos", maxStatusVideos)
+		}
+		return validateMediaItems(ctx, uid, enum.VideoFile, videoMeta.Videos)
+	}
+	return nil
+}
+
+// lookupLinkMeta replaces the preview sent by client with the one cached by server,
+// a link not cached yet is unfurled by a job after the status is created
+func lookupLinkMeta(ctx context.Context, linkMeta *meta.LinkMeta) (bool, error) {
+	cached, ok, err := linkpreviewSVC.Lookup(ctx, linkMeta.Link)
+	if err != nil {
+		return false, err
+	}
+	*linkMeta = *cached
+	return ok, nil
+}
+
+// validateMediaItems checks the attachments exist, match the file type and are uploaded by the user
+func validateMediaItems(ctx context.Context, uid uint64, fileType enum.FileType, items []*meta.MediaItem) error {
+	ids := make([]uint64, len(items))
+	seen := make(map[uint64]bool)
+	for i, item := range items {
+		if seen[item.AttachmentID] {
+			return codes.ErrInvalidArgument.Newf("duplicate attachment %d", item.AttachmentID)
+		}
+		seen[item.AttachmentID] = true
+		ids[i] = item.AttachmentID
+	}
+	attachmentMap, err := models.FindAttachmentMap(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		attachment := attachmentMap[item.AttachmentID]
+		if attachment == nil {
+			return codes.ErrInvalidArgument.Newf("attachment %d not found", item.AttachmentID)
+		}
+		if attachment.FileType != fileType {
+			return codes.ErrInvalidArgument.Newf("attachment %d is not a %s file", item.AttachmentID, fileType)
+		}
+		if attachment.UID != uid {
+			return codes.ErrForbidden.Newf("attachment %d is not uploaded by current user", item.AttachmentID)
+		}
+	}
+	return nil
 }
 
 func LikeStatus(ctx context.Context, uid uint64, statusID primitive.ObjectID) (*models.Like, error) {
@@ -119,26 +318,105 @@ func LikeStatus(ctx context.Context, uid uint64, statusID primitive.ObjectID) (*
 	if err != nil {
 		return nil, err
 	}
-	like, err := models.FindLike(ctx, uid, statusID, enum.LikeStatus)
+	return likeStatus(ctx, uid, status)
+}
+
+func UnlikeStatus(ctx context.Context, uid uint64, statusID primitive.ObjectID) error {
+	status, err := models.FindStatus(ctx, statusID)
+	if err != nil {
+		return err
+	}
+	return unlikeStatus(ctx, uid, status)
+}
+
+func LikeComment(ctx context.Context, uid uint64, commentID primitive.ObjectID) (*models.Like, error) {
+	comment, err := findComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return likeStatus(ctx, uid, comment)
+}
+
+func UnlikeComment(ctx context.Context, uid uint64, commentID primitive.ObjectID) error {
+	comment, err := findComment(ctx, commentID)
+	if err != nil {
+		return err
+	}
+	return unlikeStatus(ctx, uid, comment)
+}
+
+// ListStatusLike lists the likes of a status or a comment with the liked users
+func ListStatusLike(ctx context.Context, currentUID uint64, statusID primitive.ObjectID, pageParams *pagination.PageQuickParams) ([]*models.Like, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	status, err := models.FindStatus(ctx, statusID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = checkVisible(ctx, currentUID, status); err != nil {
+		return nil, nil, err
+	}
+	return models.ListLike(ctxWithUID, &models.ListLikeParams{
+		TargetID:   statusID,
+		PageParams: pageParams,
+	})
+}
+
+// ListUserLikedStatus lists the statuses liked by the user, paginated by likes
+func ListUserLikedStatus(ctx context.Context, currentUID, uid uint64, pageParams *pagination.PageQuickParams) ([]*models.Status, pagination.Pagination, error) {
+	ctxWithUID := context.WithValue(ctx, "CurrentUID", currentUID)
+	if _, err := models.FindUser(ctx, uid); err != nil {
+		return nil, nil, err
+	}
+	likes, page, err := models.ListLike(ctx, &models.ListLikeParams{
+		UID:         uid,
+		TargetTypes: []enum.LikeTargetType{enum.LikeStatus},
+		PageParams:  pageParams,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	statusIDs := make([]primitive.ObjectID, len(likes))
+	for i, like := range likes {
+		statusIDs[i] = like.TargetID
+	}
+	statuses, err := models.ListStatusByIDs(ctxWithUID, statusIDs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if statuses, err = filterVisible(ctx, currentUID, statuses...); err != nil {
+		return nil, nil, err
+	}
+	return statuses, page, BatchSetIsLiked(ctx, currentUID, statuses...)
+}
+
+func likeStatus(ctx context.Context, uid uint64, status *models.Status) (*models.Like, error) {
+	if err := relationSVC.CheckBlocked(ctx, uid, status.UID); err != nil {
+		return nil, err
+	}
+	if err := checkVisible(ctx, uid, status); err != nil {
+		return nil, err
+	}
+	targetType := likeTargetType(status)
+	like, err := models.FindLike(ctx, uid, status.ID, targetType)
 	if err != nil && err != mongo.ErrNoDocuments {
 		return nil, err
 	}
 	if err == nil {
 		return like, nil
 	}
-	like, err = models.CreateLike(ctx, uid, statusID, enum.LikeStatus)
+	like, err = models.CreateLike(ctx, uid, status.ID, targetType)
 	if err != nil {
 		return nil, err
 	}
-	return like, status.IncStatusCounter(ctx, "likes_count")
+	if err = status.IncStatusCounter(ctx, "likes_count"); err != nil {
+		return nil, err
+	}
+	notificationSVC.NotifyStatus(ctx, uid, enum.NotifyLike, status, primitive.NilObjectID)
+	return like, nil
 }
 
-func UnlikeStatus(ctx context.Context, uid uint64, statusID primitive.ObjectID) error {
-	like, err := models.FindLike(ctx, uid, statusID, enum.LikeStatus)
-	if err != nil {
-		return err
-	}
-	status, err := models.FindStatus(ctx, statusID)
+func unlikeStatus(ctx context.Context, uid uint64, status *models.Status) error {
+	like, err := models.FindLike(ctx, uid, status.ID, likeTargetType(status))
 	if err != nil {
 		return err
 	}
@@ -148,6 +426,24 @@ func UnlikeStatus(ctx context.Context, uid uint64, statusID primitive.ObjectID)
 	return status.IncStatusCounter(ctx, "likes_count", -1)
 }
 
+func likeTargetType(status *models.Status) enum.LikeTargetType {
+	if status.FromType == enum.FromComment {
+		return enum.LikeComment
+	}
+	return enum.LikeStatus
+}
+
+func findComment(ctx context.Context, id primitive.ObjectID) (*models.Status, error) {
+	comment, err := models.FindStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.FromType != enum.FromComment {
+		return nil, codes.ErrNotFound
+	}
+	return comment, nil
+}
+
 func DeleteStatus(ctx context.Context, uid uint64, id primitive.ObjectID) error {
 	status, err := models.FindStatus(ctx, id)
 	if err != nil {
@@ -159,7 +455,8 @@ func DeleteStatus(ctx context.Context, uid uint64, id primitive.ObjectID) error
 	return models.DeleteStatus(ctx, id)
 }
 
-func batchSetIsLiked(ctx context.Context, uid uint64, statuses ...*models.Status) error {
+// BatchSetIsLiked marks the statuses liked by the user
+func BatchSetIsLiked(ctx context.Context, uid uint64, statuses ...*models.Status) error {
 	if uid == 0 {
 		return nil
 	}
@@ -0,0 +1,120 @@
+package status
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/codes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// filterVisible keeps the statuses visible to the user, comments follow the visibility of the status commented
+func filterVisible(ctx context.Context, uid uint64, statuses ...*models.Status) ([]*models.Status, error) {
+	rootIDs := make([]primitive.ObjectID, 0)
+	for _, status := range statuses {
+		if status.FromType == enum.FromComment {
+			rootIDs = append(rootIDs, commentRootID(status))
+		}
+	}
+	rootMap, err := models.FindStatusMap(ctx, rootIDs)
+	if err != nil {
+		return nil, err
+	}
+	governing := make([]*models.Status, len(statuses))
+	authorUIDs := make([]uint64, 0)
+	for i, status := range statuses {
+		governing[i] = status
+		if status.FromType == enum.FromComment {
+			governing[i] = rootMap[commentRootID(status)]
+		}
+		if governing[i] != nil && governing[i].Visibility != enum.VisibilityPublic && governing[i].UID != uid {
+			authorUIDs = append(authorUIDs, governing[i].UID)
+		}
+	}
+	followMap := make(map[uint64]*models.Follow)
+	if uid != 0 && len(authorUIDs) > 0 {
+		if followMap, err = models.GetFollowMap(ctx, uid, authorUIDs); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]*models.Status, 0, len(statuses))
+	for i, status := range statuses {
+		if governing[i] != nil && canView(uid, governing[i], followMap[governing[i].UID]) {
+			result = append(result, status)
+		}
+	}
+	return result, nil
+}
+
+// checkVisible hides the status as not found if the user can not view it
+func checkVisible(ctx context.Context, uid uint64, status *models.Status) error {
+	visible, err := filterVisible(ctx, uid, status)
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return codes.ErrNotFound
+	}
+	return nil
+}
+
+// hiddenVisibilities returns the visibilities of the author's statuses the user can not view
+func hiddenVisibilities(ctx context.Context, uid, authorUID uint64) ([]enum.Visibility, error) {
+	if uid != 0 && uid == authorUID {
+		return nil, nil
+	}
+	if uid == 0 {
+		return enum.NonPublicVisibilities, nil
+	}
+	follow, err := models.GetFollow(ctx, uid, authorUID)
+	if err == mongo.ErrNoDocuments {
+		return enum.NonPublicVisibilities, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if follow.IsFriend {
+		return []enum.Visibility{enum.VisibilityOnlyMe}, nil
+	}
+	return []enum.Visibility{enum.VisibilityFriends, enum.VisibilityOnlyMe}, nil
+}
+
+// forwardVisibility narrows the visibility of a forward to the one of the forwarded statuses,
+// only the author could forward a status which is not public
+func forwardVisibility(ctx context.Context, uid uint64, visibility enum.Visibility, parent *models.Status) (enum.Visibility, error) {
+	forwarded := []*models.Status{parent}
+	if !parent.OriginID.IsZero() {
+		origin, err := models.FindStatusIncludeDeleted(ctx, parent.OriginID)
+		if err != nil {
+			return visibility, err
+		}
+		forwarded = append(forwarded, origin)
+	}
+	for _, status := range forwarded {
+		if status.Visibility == enum.VisibilityPublic {
+			continue
+		}
+		if status.UID != uid {
+			return visibility, codes.ErrForbidden.New("only public statuses can be forwarded")
+		}
+		if status.Visibility > visibility {
+			visibility = status.Visibility
+		}
+	}
+	return visibility, nil
+}
+
+func canView(uid uint64, status *models.Status, follow *models.Follow) bool {
+	if status.Visibility == enum.VisibilityPublic || (uid != 0 && status.UID == uid) {
+		return true
+	}
+	switch status.Visibility {
+	case enum.VisibilityFollowers:
+		return follow != nil
+	case enum.VisibilityFriends:
+		return follow != nil && follow.IsFriend
+	}
+	return false
+}
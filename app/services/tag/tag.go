@@ -0,0 +1,35 @@
+package tag
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/lib/codes"
+)
+
+const (
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+var trendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// ListTrendingTags lists the most used tags in the window, 24h by default
+func ListTrendingTags(ctx context.Context, window string, limit int64) ([]*models.TrendingTag, error) {
+	if window == "" {
+		window = "24h"
+	}
+	duration, ok := trendingWindows[window]
+	if !ok {
+		return nil, codes.ErrInvalidArgument.Newf("invalid trending window %s", window)
+	}
+	if limit <= 0 || limit > maxTrendingLimit {
+		limit = defaultTrendingLimit
+	}
+	return models.ListTrendingTags(ctx, time.Now().Add(-duration), limit)
+}
@@ -0,0 +1,84 @@
+package upload
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSession starts a resumable upload, the metadata has the filename and the file_type, or the mime type as filetype
+func CreateSession(ctx context.Context, uid uint64, length int64, metadata map[string]string) (*models.UploadSession, error) {
+	fileType := metadata["file_type"]
+	if fileType == "" {
+		fileType = strings.SplitN(metadata["filetype"], "/", 2)[0]
+	}
+	tp, err := enum.FileTypeFromString(fileType)
+	if err != nil {
+		return nil, err
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = metadata["name"]
+	}
+	return models.CreateUploadSession(ctx, uid, tp, filename, length)
+}
+
+func FindSession(ctx context.Context, uid uint64, id primitive.ObjectID) (*models.UploadSession, error) {
+	return models.FindUploadSession(ctx, uid, id)
+}
+
+// AppendChunk stages the chunk, the attachment is created when the last chunk is received.
+// A session whose file is rejected by the validation of attachments is deleted.
+func AppendChunk(ctx context.Context, uid uint64, id primitive.ObjectID, offset int64, chunk storage.File) (*models.UploadSession, error) {
+	session, err := models.FindUploadSession(ctx, uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = session.AppendChunk(ctx, offset, chunk); err != nil {
+		return nil, err
+	}
+	if session.IsComplete() {
+		if _, err = completeSession(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// Complete returns the attachment of the session, it is created if the last chunk failed to be assembled
+func Complete(ctx context.Context, uid uint64, id primitive.ObjectID) (*models.Attachment, error) {
+	session, err := models.FindUploadSession(ctx, uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return completeSession(ctx, session)
+}
+
+func completeSession(ctx context.Context, session *models.UploadSession) (*models.Attachment, error) {
+	attachment, err := session.Complete(ctx)
+	if _, invalid := err.(codes.Code); invalid && session.IsComplete() {
+		if deleteErr := session.Delete(ctx); deleteErr != nil {
+			logrus.Warnf("delete upload session %s error: %v", session.ID.Hex(), deleteErr)
+		}
+	}
+	return attachment, err
+}
+
+func Terminate(ctx context.Context, uid uint64, id primitive.ObjectID) error {
+	session, err := models.FindUploadSession(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	return session.Delete(ctx)
+}
+
+// CleanExpiredSessions deletes the expired sessions with their staged chunks
+func CleanExpiredSessions(ctx context.Context) (int, error) {
+	return models.DeleteExpiredUploadSessions(ctx)
+}
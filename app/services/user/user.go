@@ -50,6 +50,19 @@ func UpdateUserAvatar(ctx context.Context, uid, attachmentID uint64) (*models.Us
 	return user, preloadAvatar(ctx, user)
 }
 
+// UpdateUserPrivacy sets whether following the user requires approval
+func UpdateUserPrivacy(ctx context.Context, uid uint64, isPrivate bool) (*models.User, error) {
+	user, err := models.FindUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	user.IsPrivate = isPrivate
+	if err = models.UpdateUserPrivacy(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, preloadAvatar(ctx, user)
+}
+
 func UpdateUsername(ctx context.Context, uid uint64, username string) (*models.User, error) {
 	user, err := models.FindUser(ctx, uid)
 	if err != nil {
@@ -5,8 +5,14 @@ import (
 	"os"
 	"time"
 
+	"github.com/mises-id/sns/app/jobs"
 	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/cmd/migrate"
+	"github.com/mises-id/sns/cmd/reconcile"
 	"github.com/mises-id/sns/cmd/rest"
+	"github.com/mises-id/sns/cmd/sweep"
+	"github.com/mises-id/sns/cmd/worker"
+	"github.com/mises-id/sns/config/env"
 	"github.com/mises-id/sns/lib/db"
 	_ "github.com/mises-id/sns/lib/mises"
 	"github.com/sirupsen/logrus"
@@ -18,6 +24,7 @@ func main() {
 	defer cancel()
 	db.SetupMongo(ctx)
 	models.EnsureIndex()
+	jobs.EnsureIndex()
 	app := cli.NewApp()
 	app.Action = func(c *cli.Context) error {
 		return rest.Start(ctx)
@@ -31,6 +38,42 @@ func main() {
 				return rest.Start(ctx)
 			},
 		},
+		{
+			Name:  "worker",
+			Usage: "run the background jobs",
+			Action: func(c *cli.Context) error {
+				return worker.Start(context.Background())
+			},
+		},
+		{
+			Name:  "reconcile",
+			Usage: "recompute the follow, like, comment and forward counters and the storage usage",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "dry-run", Usage: "only report the drifted counters"},
+			},
+			Action: func(c *cli.Context) error {
+				return reconcile.Run(context.Background(), c.Bool("dry-run"))
+			},
+		},
+		{
+			Name:      "migrate",
+			Usage:     "fill the data of the existing documents for new features",
+			ArgsUsage: "[timeline] [timeline-visibility] [comment-likes] [link-titles]",
+			Action: func(c *cli.Context) error {
+				return migrate.Run(context.Background(), c.Args())
+			},
+		},
+		{
+			Name:  "sweep",
+			Usage: "delete the attachments which are not referenced by any user or status",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "dry-run", Usage: "only report the orphaned attachments"},
+				cli.DurationFlag{Name: "grace", Value: env.Envs.AttachmentGracePeriod, Usage: "keep the attachments created within the period"},
+			},
+			Action: func(c *cli.Context) error {
+				return sweep.Run(context.Background(), c.Duration("grace"), c.Bool("dry-run"))
+			},
+		},
 	}
 	if err := app.Run(os.Args); err != nil {
 		logrus.Fatal(err)
@@ -0,0 +1,40 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/sirupsen/logrus"
+)
+
+// migrations fill the data of the existing documents for the new features, each one is safe to run again
+var migrations = map[string]func(ctx context.Context) (int, error){
+	"timeline":            models.BackfillFollowTimelines,
+	"timeline-visibility": models.BackfillTimelineVisibility,
+	"comment-likes":       models.RetypeCommentLikes,
+	"link-titles":         models.BackfillLinkTitles,
+}
+
+// Run runs the migrations of the names, or all of them without names
+func Run(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		for name := range migrations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		migration, ok := migrations[name]
+		if !ok {
+			return codes.ErrInvalidArgument.Newf("unknown migration %s", name)
+		}
+		count, err := migration(ctx)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("migration %s: %d documents migrated", name, count)
+	}
+	return nil
+}
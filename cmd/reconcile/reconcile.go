@@ -0,0 +1,31 @@
+package reconcile
+
+import (
+	"context"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Run recomputes the denormalized counters of users and statuses and repairs the drifted ones,
+// with dryRun the drifts are only logged
+func Run(ctx context.Context, dryRun bool) error {
+	userDrifts, err := models.ReconcileUserCounters(ctx, dryRun)
+	if err != nil {
+		return err
+	}
+	statusDrifts, err := models.ReconcileStatusCounters(ctx, dryRun)
+	if err != nil {
+		return err
+	}
+	drifts := append(userDrifts, statusDrifts...)
+	for _, drift := range drifts {
+		logrus.Infof("%s %v %s: stored %d, actual %d", drift.Collection, drift.ID, drift.Key, drift.Stored, drift.Actual)
+	}
+	if dryRun {
+		logrus.Infof("found %d drifted counters, dry run", len(drifts))
+	} else {
+		logrus.Infof("repaired %d drifted counters", len(drifts))
+	}
+	return nil
+}
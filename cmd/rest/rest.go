@@ -27,9 +27,16 @@ func Start(ctx context.Context) error {
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		// an OPTIONS request which is not a preflight is the discovery of the upload protocol
+		Skipper: func(c echo.Context) bool {
+			return c.Request().Method == http.MethodOptions && c.Request().Header.Get(echo.HeaderAccessControlRequestMethod) == ""
+		},
 		AllowOrigins: strings.Split(env.Envs.AllowOrigins, ","),
 		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderXRequestedWith, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Device-Id",
+			"Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset"},
+		ExposeHeaders: []string{echo.HeaderLocation, "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size",
+			"Upload-Length", "Upload-Offset", "Upload-Expires"},
 	}))
 	route.SetRoutes(e)
 	go func() {
@@ -37,7 +44,7 @@ func Start(ctx context.Context) error {
 			log.Fatal(err)
 		}
 	}()
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	return e.Shutdown(ctx)
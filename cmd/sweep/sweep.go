@@ -0,0 +1,30 @@
+package sweep
+
+import (
+	"context"
+	"time"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Run deletes the orphaned attachments created before the grace period from the database and the storage,
+// with dryRun the orphans are only logged
+func Run(ctx context.Context, grace time.Duration, dryRun bool) error {
+	orphans, err := models.SweepOrphanAttachments(ctx, grace, dryRun)
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, attachment := range orphans {
+		size += attachment.Size
+		logrus.Infof("attachment %d of user %d: %s, %d bytes, created at %s",
+			attachment.ID, attachment.UID, attachment.Filename, attachment.Size, attachment.CreatedAt.Format(time.RFC3339))
+	}
+	if dryRun {
+		logrus.Infof("found %d orphaned attachments of %d bytes, dry run", len(orphans), size)
+	} else {
+		logrus.Infof("deleted %d orphaned attachments of %d bytes", len(orphans), size)
+	}
+	return nil
+}
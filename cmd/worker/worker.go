@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mises-id/sns/app/jobs"
+	uploadSVC "github.com/mises-id/sns/app/services/upload"
+	"github.com/mises-id/sns/config/env"
+	"github.com/sirupsen/logrus"
+)
+
+// Start runs the jobs until SIGINT or SIGTERM, the running jobs are finished before it returns
+func Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-quit
+		logrus.Info("worker shutting down")
+		cancel()
+	}()
+	go cleanUploadSessions(ctx, env.Envs.UploadGCInterval)
+	worker := jobs.NewWorker()
+	logrus.Infof("worker %s started", worker.ID)
+	return worker.Run(ctx)
+}
+
+// cleanUploadSessions deletes the expired upload sessions periodically, it is safe to run in every worker
+func cleanUploadSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := uploadSVC.CleanExpiredSessions(ctx)
+			if err != nil {
+				logrus.Errorf("clean upload sessions error: %v", err)
+			}
+			if count > 0 {
+				logrus.Infof("%d expired upload sessions cleaned", count)
+			}
+		}
+	}
+}
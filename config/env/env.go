@@ -14,21 +14,63 @@ import (
 var Envs *Env
 
 type Env struct {
-	Port              int           `env:"PORT" envDefault:"8080"`
-	AppEnv            string        `env:"APP_ENV" envDefault:"development"`
-	MisesTestEndpoint string        `env:"MISES_TEST_ENDPOINT" envDefault:""`
-	LogLevel          string        `env:"LOG_LEVEL" envDefault:"INFO"`
-	MongoURI          string        `env:"MONGO_URI,required"`
-	DBUser            string        `env:"DB_USER"`
-	DBPass            string        `env:"DB_PASS"`
-	DBName            string        `env:"DB_NAME" envDefault:"mises"`
-	AssetHost         string        `env:"ASSET_HOST" envDefault:"http://localhost/"`
-	StorageProvider   string        `env:"STORAGE_PROVIDER" envDefault:"local"`
-	JWTSecret         string        `env:"JWT_SECRET,required"`
-	TokenDuration     time.Duration `env:"TOKEN_DURATION" envDefault:"24h"`
-	AllowOrigins      string        `env:"ALLOW_ORIGINS" envDefault:""`
-	DebugMisesPrefix  string        `env:"DEBUG_MISES_PREFIX" envDefault:""`
-	RootPath          string
+	Port               int           `env:"PORT" envDefault:"8080"`
+	AppEnv             string        `env:"APP_ENV" envDefault:"development"`
+	MisesTestEndpoint  string        `env:"MISES_TEST_ENDPOINT" envDefault:""`
+	LogLevel           string        `env:"LOG_LEVEL" envDefault:"INFO"`
+	MongoURI           string        `env:"MONGO_URI,required"`
+	DBUser             string        `env:"DB_USER"`
+	DBPass             string        `env:"DB_PASS"`
+	DBName             string        `env:"DB_NAME" envDefault:"mises"`
+	AssetHost          string        `env:"ASSET_HOST" envDefault:"http://localhost/"`
+	StorageProvider    string        `env:"STORAGE_PROVIDER" envDefault:"local"`
+	JWTSecret          string        `env:"JWT_SECRET"`
+	TokenDuration      time.Duration `env:"TOKEN_DURATION" envDefault:"15m"`
+	AllowOrigins       string        `env:"ALLOW_ORIGINS" envDefault:""`
+	DebugMisesPrefix   string        `env:"DEBUG_MISES_PREFIX" envDefault:""`
+	TimelineBackfill   int64         `env:"TIMELINE_BACKFILL" envDefault:"20"`
+	LinkPreviewTimeout time.Duration `env:"LINK_PREVIEW_TIMEOUT" envDefault:"10s"`
+	LinkPreviewTTL     time.Duration `env:"LINK_PREVIEW_TTL" envDefault:"24h"`
+	LinkPreviewRetry   time.Duration `env:"LINK_PREVIEW_RETRY" envDefault:"10m"`
+	TagBucketRetention time.Duration `env:"TAG_BUCKET_RETENTION" envDefault:"168h"`
+	StatusEditWindow   time.Duration `env:"STATUS_EDIT_WINDOW" envDefault:"1h"`
+	WorkerConcurrency  int           `env:"WORKER_CONCURRENCY" envDefault:"4"`
+
+	// the object storage of the s3 and oss providers, signed urls of the local storage are served by the api
+	StorageEndpoint        string `env:"STORAGE_ENDPOINT"`
+	StorageRegion          string `env:"STORAGE_REGION" envDefault:"us-east-1"`
+	StorageBucket          string `env:"STORAGE_BUCKET"`
+	StorageAccessKeyID     string `env:"STORAGE_ACCESS_KEY_ID"`
+	StorageAccessKeySecret string `env:"STORAGE_ACCESS_KEY_SECRET"`
+	StoragePathStyle       bool   `env:"STORAGE_PATH_STYLE" envDefault:"false"`
+	StoragePartSize        int64  `env:"STORAGE_PART_SIZE" envDefault:"8388608"`
+	StorageBaseURL         string `env:"STORAGE_BASE_URL"`
+	StorageSignedBaseURL   string `env:"STORAGE_SIGNED_BASE_URL" envDefault:"http://localhost:8080/api/v1/files/"`
+	StorageSigningSecret   string `env:"STORAGE_SIGNING_SECRET"`
+
+	// the limits of the uploaded files, a quota of 0 is unlimited
+	ImageMaxSize     int64         `env:"IMAGE_MAX_SIZE" envDefault:"10485760"`
+	VideoMaxSize     int64         `env:"VIDEO_MAX_SIZE" envDefault:"104857600"`
+	UserStorageQuota int64         `env:"USER_STORAGE_QUOTA" envDefault:"1073741824"`
+	VideoMaxDuration time.Duration `env:"VIDEO_MAX_DURATION" envDefault:"10m"`
+	UploadSessionTTL time.Duration `env:"UPLOAD_SESSION_TTL" envDefault:"24h"`
+	UploadGCInterval time.Duration `env:"UPLOAD_GC_INTERVAL" envDefault:"1h"`
+	// unreferenced attachments are kept for the grace period, so new uploads are not swept before being used
+	AttachmentGracePeriod time.Duration `env:"ATTACHMENT_GRACE_PERIOD" envDefault:"72h"`
+
+	// the access token is short lived, the refresh token is rotated and expires after being unused for the duration
+	RefreshTokenDuration time.Duration `env:"REFRESH_TOKEN_DURATION" envDefault:"720h"`
+	// the pem keys signing the access tokens, named <kid>.pem, the last private key signs unless the kid is set.
+	// The dir is required unless APP_ENV is development or test, which use a temporary key without it.
+	JWTKeysDir      string `env:"JWT_KEYS_DIR"`
+	JWTSigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+	JWTIssuer       string `env:"JWT_ISSUER" envDefault:"mises-sns"`
+	// the HS256 tokens signed by JWT_SECRET before the deploy at the cutoff (RFC 3339) are accepted until
+	// the duration after it, without the secret or the cutoff they are rejected
+	LegacyTokenCutoff   string        `env:"LEGACY_TOKEN_CUTOFF"`
+	LegacyTokenDuration time.Duration `env:"LEGACY_TOKEN_DURATION" envDefault:"24h"`
+
+	RootPath string
 }
 
 func init() {
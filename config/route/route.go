@@ -12,27 +12,68 @@ import (
 func SetRoutes(e *echo.Echo) {
 	e.GET("/", rest.Probe)
 	e.GET("/healthz", rest.Probe)
+	e.GET("/.well-known/jwks.json", v1.JWKS)
 
 	groupV1 := e.Group("/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware)
 	groupV1.POST("/attachment", v1.Upload)
+	groupV1.GET("/files/*", v1.ServeFile)
+	groupV1.OPTIONS("/uploads", v1.TusOptions)
 	groupV1.GET("/user/:uid", v1.FindUser)
 	groupV1.POST("/signin", v1.SignIn)
 	groupV1.GET("/user/:uid/friendship", v1.ListFriendship)
 
+	// the refresh token is used when the access token is expired, so the access token is not checked
+	tokenGroup := e.Group("/api/v1", mw.ErrorResponseMiddleware)
+	tokenGroup.POST("/token/refresh", v1.RefreshToken)
+
 	userGroup := e.Group("/api/v1", mw.ErrorResponseMiddleware, appmw.SetCurrentUserMiddleware, appmw.RequireCurrentUserMiddleware)
+	userGroup.POST("/uploads", v1.CreateUploadSession)
+	userGroup.HEAD("/uploads/:id", v1.UploadSessionStatus)
+	userGroup.PATCH("/uploads/:id", v1.UploadChunk)
+	userGroup.PUT("/uploads/:id", v1.UploadChunk)
+	userGroup.DELETE("/uploads/:id", v1.TerminateUploadSession)
+	userGroup.POST("/uploads/:id/complete", v1.CompleteUploadSession)
+	userGroup.GET("/session", v1.ListSession)
+	userGroup.DELETE("/session", v1.DeleteSession)
 	userGroup.GET("/user/me", v1.MyProfile)
+	userGroup.GET("/user/me/mentions", v1.ListMyMentions)
 	userGroup.PATCH("/user/me", v1.UpdateUser)
 	userGroup.POST("/user/follow", v1.Follow)
 	userGroup.DELETE("/user/follow", v1.Unfollow)
+	userGroup.GET("/user/follow_request", v1.ListFollowRequest)
+	userGroup.POST("/user/follow_request/approve", v1.ApproveFollowRequest)
+	userGroup.POST("/user/follow_request/reject", v1.RejectFollowRequest)
+	userGroup.DELETE("/user/follow_request", v1.CancelFollowRequest)
+	userGroup.GET("/user/block", v1.ListBlock)
+	userGroup.POST("/user/block", v1.Block)
+	userGroup.DELETE("/user/block", v1.Unblock)
+	userGroup.GET("/user/mute", v1.ListMute)
+	userGroup.POST("/user/mute", v1.Mute)
+	userGroup.DELETE("/user/mute", v1.Unmute)
 	groupV1.GET("/user/:uid/status", v1.ListUserStatus)
+	groupV1.GET("/user/:uid/likes", v1.ListUserLikedStatus)
 	groupV1.GET("/status/recommend", v1.RecommendStatus)
 	userGroup.GET("/timeline/me", v1.Timeline)
 	userGroup.POST("/status", v1.CreateStatus)
 	groupV1.GET("/status/:id", v1.GetStatus)
+	userGroup.PATCH("/status/:id", v1.EditStatus)
 	userGroup.DELETE("/status/:id", v1.DeleteStatus)
+	groupV1.GET("/status/:id/revisions", v1.ListStatusRevision)
 	userGroup.POST("/status/:id/like", v1.LikeStatus)
 	userGroup.DELETE("/status/:id/like", v1.UnlikeStatus)
+	groupV1.GET("/status/:id/likes", v1.ListStatusLike)
 
 	groupV1.GET("/comment", v1.ListComment)
+	groupV1.GET("/comment/:id/replies", v1.ListCommentReplies)
 	userGroup.POST("/comment", v1.CreateComment)
+	userGroup.POST("/comment/:id/like", v1.LikeComment)
+	userGroup.DELETE("/comment/:id/like", v1.UnlikeComment)
+
+	userGroup.GET("/notification", v1.ListNotification)
+	userGroup.POST("/notification/read", v1.ReadNotification)
+	userGroup.GET("/notification/unread_count", v1.UnreadNotificationCount)
+
+	groupV1.GET("/search", v1.Search)
+	groupV1.GET("/tag/trending", v1.TrendingTags)
+	groupV1.GET("/tag/:name/status", v1.ListTagStatus)
 }
@@ -56,7 +56,11 @@ const (
 	ForbiddenCode           = 403000
 	UsernameExistedCode     = 403001
 	TokenExpiredCode        = 403002
+	QuotaExceededCode       = 403003
 	NotFoundCode            = 404000
+	ConflictCode            = 409000
+	PayloadTooLargeCode     = 413000
+	UnsupportedMediaCode    = 415000
 	UnprocessableEntityCode = 422000
 	UsernameDuplicateCode   = 422001
 	InternalCode            = 500000
@@ -73,8 +77,12 @@ var (
 	ErrAuthorizeFailed     = Code{HTTPStatus: http.StatusUnauthorized, Code: AuthorizeFailedCode, Msg: "authorize failed"}
 	ErrForbidden           = Code{HTTPStatus: http.StatusForbidden, Code: ForbiddenCode, Msg: "forbidden"}
 	ErrTokenExpired        = Code{HTTPStatus: http.StatusForbidden, Code: TokenExpiredCode, Msg: "authorization expired"}
+	ErrQuotaExceeded       = Code{HTTPStatus: http.StatusForbidden, Code: QuotaExceededCode, Msg: "quota exceeded"}
 	ErrUsernameExisted     = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameExistedCode, Msg: "username had existed"}
 	ErrNotFound            = Code{HTTPStatus: http.StatusNotFound, Code: NotFoundCode, Msg: "not found"}
+	ErrConflict            = Code{HTTPStatus: http.StatusConflict, Code: ConflictCode, Msg: "conflict"}
+	ErrPayloadTooLarge     = Code{HTTPStatus: http.StatusRequestEntityTooLarge, Code: PayloadTooLargeCode, Msg: "payload too large"}
+	ErrUnsupportedMedia    = Code{HTTPStatus: http.StatusUnsupportedMediaType, Code: UnsupportedMediaCode, Msg: "unsupported media type"}
 	ErrUnprocessableEntity = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UnprocessableEntityCode, Msg: "unprocessable entity"}
 	ErrUsernameDuplicate   = Code{HTTPStatus: http.StatusUnprocessableEntity, Code: UsernameDuplicateCode, Msg: "username duplicate"}
 	ErrInternal            = Code{HTTPStatus: http.StatusInternalServerError, Code: InternalCode, Msg: "internal error"}
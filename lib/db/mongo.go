@@ -5,13 +5,18 @@ import (
 
 	"github.com/mises-id/sns/config/env"
 	"github.com/mises-id/sns/lib/db/odm"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
-	mongoDB   *mongo.Database
-	odmClient *odm.Client
+	mongoClient *mongo.Client
+	mongoDB     *mongo.Database
+	odmClient   *odm.Client
+	// transactions are only supported by replica sets and sharded clusters
+	transactionSupported bool
 )
 
 func SetupMongo(ctx context.Context) {
@@ -26,8 +31,44 @@ func SetupMongo(ctx context.Context) {
 	if err != nil {
 		panic(err)
 	}
+	mongoClient = client
 	mongoDB = client.Database(env.Envs.DBName)
 	odmClient = odm.NewClient(mongoDB)
+	transactionSupported = supportsTransaction(ctx)
+	if !transactionSupported {
+		logrus.Warn("mongo server is standalone, transactions are disabled")
+	}
+}
+
+// WithTransaction runs fn in a multi-document transaction, which is retried on transient errors.
+// The ctx passed to fn carries the session, fn must use it for all operations in the transaction.
+// On a standalone server fn is run without transaction.
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !transactionSupported {
+		return fn(ctx)
+	}
+	session, err := mongoClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+func supportsTransaction(ctx context.Context) bool {
+	result := struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}{}
+	err := mongoDB.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	if err != nil {
+		logrus.Warnf("check mongo topology error: %v", err)
+		return false
+	}
+	return result.SetName != "" || result.Msg == "isdbgrid"
 }
 
 func DB() *mongo.Database {
@@ -101,6 +101,11 @@ func (db *DB) Sort(sort interface{}) *DB {
 	return db
 }
 
+func (db *DB) Projection(projection interface{}) *DB {
+	db.options.Projection = projection
+	return db
+}
+
 func (db *DB) Limit(limit int64) *DB {
 	db.options.Limit = &limit
 	return db
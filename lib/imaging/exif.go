@@ -0,0 +1,153 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+const (
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+	// APP13 holds the IPTC and Photoshop metadata
+	markerAPP13     = 0xed
+	orientationTag  = 0x0112
+	exifHeader      = "Exif\x00\x00"
+	tiffHeaderSize  = 8
+	ifdEntrySize    = 12
+	littleEndianTag = "II"
+)
+
+type segment struct {
+	marker byte
+	start  int
+	end    int
+}
+
+// jpegSegments returns the segments before the scan data, ok is false if the data is not a valid jpeg
+func jpegSegments(data []byte) (segments []segment, scanStart int, ok bool) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, 0, false
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return nil, 0, false
+		}
+		marker := data[i+1]
+		// fill bytes before a marker
+		if marker == 0xff {
+			i++
+			continue
+		}
+		if marker == markerSOS {
+			return segments, i, true
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, 0, false
+		}
+		segments = append(segments, segment{marker: marker, start: i, end: i + 2 + length})
+		i += 2 + length
+	}
+	return nil, 0, false
+}
+
+// Orientation reads the EXIF orientation of the jpeg, it is 1 if the jpeg has none
+func Orientation(data []byte) int {
+	segments, _, ok := jpegSegments(data)
+	if !ok {
+		return 1
+	}
+	for _, seg := range segments {
+		payload := data[seg.start+4 : seg.end]
+		if seg.marker != markerAPP1 || !bytes.HasPrefix(payload, []byte(exifHeader)) {
+			continue
+		}
+		if orientation := tiffOrientation(payload[len(exifHeader):]); orientation >= 1 && orientation <= 8 {
+			return orientation
+		}
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < tiffHeaderSize {
+		return 0
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if string(tiff[:2]) == littleEndianTag {
+		order = binary.LittleEndian
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < tiffHeaderSize || offset+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*ifdEntrySize
+		if entry+ifdEntrySize > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// StripMetadata removes the EXIF, XMP and IPTC segments of the jpeg without re-encoding,
+// the color profile is kept. Data which is not a valid jpeg is returned as is.
+func StripMetadata(data []byte) []byte {
+	segments, scanStart, ok := jpegSegments(data)
+	if !ok {
+		return data
+	}
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, data[:2]...)
+	for _, seg := range segments {
+		if seg.marker == markerAPP1 || seg.marker == markerAPP13 {
+			continue
+		}
+		stripped = append(stripped, data[seg.start:seg.end]...)
+	}
+	return append(stripped, data[scanStart:]...)
+}
+
+// Orient transforms the image as described by the EXIF orientation, so it is displayed upright
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	src := toRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	// orientations 5 to 8 swap the axes
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// decoding larger images takes too much memory
+	maxPixels   = 50 * 1000 * 1000
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported image format")
+	ErrTooLarge    = errors.New("image is too large")
+)
+
+// Size is a variant whose longest side is at most MaxSide, smaller images are never upscaled
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+var DefaultSizes = []Size{
+	{Name: "small", MaxSide: 128},
+	{Name: "medium", MaxSide: 512},
+	{Name: "large", MaxSide: 1280},
+}
+
+type Variant struct {
+	Name   string
+	Ext    string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+type Result struct {
+	// Original is the uploaded image without metadata, rotated upright if it had an EXIF orientation
+	Original []byte
+	Format   string
+	Width    int
+	Height   int
+	Variants []*Variant
+}
+
+// Process decodes the jpeg, png or gif image and encodes a variant for each size,
+// gif variants are encoded as png from the first frame
+func Process(data []byte, sizes []Size) (*Result, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Original: data, Format: format}
+	if format == "jpeg" {
+		orientation := Orientation(data)
+		if orientation > 1 {
+			img = Orient(img, orientation)
+			if result.Original, err = encode(img, format); err != nil {
+				return nil, err
+			}
+		} else {
+			result.Original = StripMetadata(data)
+		}
+	} else if format == "png" {
+		result.Original = StripPNGMetadata(data)
+	}
+	bounds := img.Bounds()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+	for _, size := range sizes {
+		variant := img
+		if width, height := fit(result.Width, result.Height, size.MaxSide); width != result.Width || height != result.Height {
+			variant = Resize(img, width, height)
+		}
+		encoded, err := encode(variant, format)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, &Variant{
+			Name:   size.Name,
+			Ext:    extension(format),
+			Data:   encoded,
+			Width:  variant.Bounds().Dx(),
+			Height: variant.Bounds().Dy(),
+		})
+	}
+	return result, nil
+}
+
+func fit(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, max(1, height*maxSide/width)
+	}
+	return max(1, width*maxSide/height), maxSide
+}
+
+func encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
+
+func extension(format string) string {
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
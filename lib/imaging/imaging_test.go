@@ -0,0 +1,159 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifJPEG encodes a jpeg whose left half is red and right half is blue, tagged with the orientation
+func exifJPEG(t *testing.T, width, height, orientation int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:], uint16(orientation))
+	payload := append([]byte(exifHeader), tiff...)
+	app1 := []byte{0xff, markerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	data := buf.Bytes()
+	result := append([]byte{}, data[:2]...)
+	result = append(result, app1...)
+	result = append(result, payload...)
+	return append(result, data[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	data := exifJPEG(t, 40, 20, 6)
+	if orientation := Orientation(data); orientation != 6 {
+		t.Fatalf("orientation = %d; expected 6", orientation)
+	}
+	stripped := StripMetadata(data)
+	if bytes.Contains(stripped, []byte(exifHeader)) {
+		t.Error("exif is not stripped")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped jpeg is invalid: %v", err)
+	}
+	if orientation := Orientation([]byte("not a jpeg")); orientation != 1 {
+		t.Errorf("orientation = %d; expected 1", orientation)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	result, err := Process(exifJPEG(t, 40, 20, 6), []Size{{Name: "small", MaxSide: 10}, {Name: "large", MaxSide: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Width != 20 || result.Height != 40 {
+		t.Errorf("size = %dx%d; expected 20x40", result.Width, result.Height)
+	}
+	if bytes.Contains(result.Original, []byte(exifHeader)) {
+		t.Error("exif is not stripped from the original")
+	}
+	original, err := jpeg.Decode(bytes.NewReader(result.Original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotated clockwise, the red half is on the top
+	if r, _, b, _ := original.At(10, 5).RGBA(); r < b {
+		t.Errorf("top is not red")
+	}
+	expected := map[string][2]int{"small": {5, 10}, "large": {20, 40}}
+	for _, variant := range result.Variants {
+		img, err := jpeg.Decode(bytes.NewReader(variant.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := [2]int{img.Bounds().Dx(), img.Bounds().Dy()}
+		if size != expected[variant.Name] || variant.Width != size[0] || variant.Height != size[1] || variant.Ext != ".jpg" {
+			t.Errorf("variant %s is %v %s; expected %v", variant.Name, size, variant.Ext, expected[variant.Name])
+		}
+	}
+	if _, err = Process([]byte("GIF89a invalid"), DefaultSizes); err == nil {
+		t.Error("invalid image is processed")
+	}
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	// the chunks follow the signature and IHDR
+	ihdrEnd := len(pngSignature) + 12 + 13
+	data := append([]byte{}, encoded[:ihdrEnd]...)
+	data = append(data, pngChunk("gAMA", []byte{0, 0, 0xb1, 0x8f})...)
+	data = append(data, pngChunk("tEXt", []byte("Author\x00secret author"))...)
+	data = append(data, pngChunk("eXIf", []byte("MM\x00\x2agps"))...)
+	data = append(data, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta/>"))...)
+	data = append(data, encoded[ihdrEnd:]...)
+
+	stripped := StripPNGMetadata(data)
+	for _, text := range []string{"secret author", "gps", "xmpmeta"} {
+		if bytes.Contains(stripped, []byte(text)) {
+			t.Errorf("%s is not stripped", text)
+		}
+	}
+	if !bytes.Contains(stripped, []byte("gAMA")) {
+		t.Error("gAMA is stripped")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped png is invalid: %v", err)
+	}
+	result, err := Process(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(result.Original, stripped) {
+		t.Error("metadata is not stripped from the original")
+	}
+	truncated := data[:len(data)-20]
+	if !bytes.Equal(StripPNGMetadata(truncated), truncated) {
+		t.Error("truncated png is changed")
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 9))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	resized := Resize(img, 10, 3)
+	if resized.Rect.Dx() != 10 || resized.Rect.Dy() != 3 {
+		t.Fatalf("size = %v; expected 10x3", resized.Rect.Size())
+	}
+	for _, v := range resized.Pix {
+		// premultiplied 200 * 200 / 255
+		if v != 157 && v != 200 {
+			t.Fatalf("pixel value %d; expected a uniform color", v)
+		}
+	}
+}
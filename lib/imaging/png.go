@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngKeptChunks are the ancillary chunks changing how the image is rendered, the animation chunks of apng included
+var pngKeptChunks = map[string]bool{
+	"tRNS": true,
+	"gAMA": true,
+	"cHRM": true,
+	"sRGB": true,
+	"iCCP": true,
+	"sBIT": true,
+	"acTL": true,
+	"fcTL": true,
+	"fdAT": true,
+}
+
+// StripPNGMetadata removes the text, EXIF, time and other ancillary chunks of the png without re-encoding,
+// the critical chunks and the ones for rendering are kept. Data which is not a valid png is returned as is.
+func StripPNGMetadata(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return data
+	}
+	stripped := make([]byte, 0, len(data))
+	stripped = append(stripped, pngSignature...)
+	i := len(pngSignature)
+	for i < len(data) {
+		// length, type, data and crc
+		if len(data)-i < 12 {
+			return data
+		}
+		length := binary.BigEndian.Uint32(data[i:])
+		if uint64(length) > uint64(len(data)-i-12) {
+			return data
+		}
+		end := i + 12 + int(length)
+		chunkType := string(data[i+4 : i+8])
+		// the chunk is critical if the first letter of its type is upper case
+		if chunkType[0] < 'a' || pngKeptChunks[chunkType] {
+			stripped = append(stripped, data[i:end]...)
+		}
+		if chunkType == "IEND" {
+			return stripped
+		}
+		i = end
+	}
+	return data
+}
@@ -0,0 +1,92 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Resize scales the image down to width x height by averaging the covered source pixels,
+// the two passes work on premultiplied colors so transparent edges do not darken
+func Resize(img image.Image, width, height int) *image.RGBA {
+	src := toRGBA(img)
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	// horizontal pass, srcHeight rows of width pixels
+	tmp := make([]float32, width*srcHeight*4)
+	columns := weights(srcWidth, width)
+	for y := 0; y < srcHeight; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, ws := range columns {
+			var c [4]float32
+			for _, w := range ws {
+				p := row[w.index*4:]
+				for i := 0; i < 4; i++ {
+					c[i] += float32(p[i]) * w.weight
+				}
+			}
+			copy(tmp[(y*width+x)*4:], c[:])
+		}
+	}
+	// vertical pass
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	rows := weights(srcHeight, height)
+	for y, ws := range rows {
+		for x := 0; x < width; x++ {
+			var c [4]float32
+			for _, w := range ws {
+				p := tmp[(w.index*width+x)*4:]
+				for i := 0; i < 4; i++ {
+					c[i] += p[i] * w.weight
+				}
+			}
+			o := dst.Pix[y*dst.Stride+x*4:]
+			for i := 0; i < 4; i++ {
+				o[i] = clamp(c[i])
+			}
+		}
+	}
+	return dst
+}
+
+type weight struct {
+	index  int
+	weight float32
+}
+
+// weights maps each destination pixel to the source pixels it covers, weighted by the covered fraction
+func weights(srcSize, dstSize int) [][]weight {
+	scale := float64(srcSize) / float64(dstSize)
+	result := make([][]weight, dstSize)
+	for i := range result {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		ws := make([]weight, 0, int(scale)+2)
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			covered := math.Min(float64(j+1), end) - math.Max(float64(j), start)
+			if covered > 0 {
+				ws = append(ws, weight{index: j, weight: float32(covered / scale)})
+			}
+		}
+		result[i] = ws
+	}
+	return result
+}
+
+func clamp(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,104 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KeySet signs tokens with one key and verifies tokens of all its keys by the kid header,
+// so a key is rotated by signing with a new key while the old one verifies the tokens issued before.
+type KeySet struct {
+	keys    map[string]*Key
+	ordered []*Key
+	signing *Key
+}
+
+// JSONWebKey is the public key of RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// NewKeySet signs with the key of signingKID, or the last private key when it is empty
+func NewKeySet(signingKID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, fmt.Errorf("%w: empty kid", ErrInvalidKey)
+		}
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate kid %s", ErrInvalidKey, key.ID)
+		}
+		set.keys[key.ID] = key
+		set.ordered = append(set.ordered, key)
+		if key.PrivateKey != nil && (signingKID == "" || key.ID == signingKID) {
+			set.signing = key
+		}
+	}
+	if set.signing == nil {
+		return nil, ErrNoKey
+	}
+	return set, nil
+}
+
+// SigningKeyID is the kid of the new tokens
+func (s *KeySet) SigningKeyID() string {
+	return s.signing.ID
+}
+
+// Sign signs the claims with the signing key and sets the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.PrivateKey)
+}
+
+// Parse verifies the token with the key of its kid and decodes the claims, the algorithm must be the one of the key
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg(), SigningMethodEdDSA.Alg()}}
+	return parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s of kid %q", token.Method.Alg(), kid)
+		}
+		return key.PublicKey, nil
+	})
+}
+
+// JWKS returns the public keys to publish
+func (s *KeySet) JWKS() *JSONWebKeySet {
+	set := &JSONWebKeySet{Keys: make([]*JSONWebKey, 0, len(s.ordered))}
+	for _, key := range s.ordered {
+		jwk := &JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch publicKey := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
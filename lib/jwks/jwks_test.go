@@ -0,0 +1,203 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func generateRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+func pemBlock(t *testing.T, tp string, der []byte, err error) []byte {
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: tp, Bytes: der})
+}
+
+func claims(exp time.Time) *jwt.StandardClaims {
+	return &jwt.StandardClaims{Subject: "1001", ExpiresAt: exp.Unix()}
+}
+
+func TestParseKey(t *testing.T) {
+	rsaKey := generateRSAKey(t, 2048)
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	pkcs8RSA, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	pkcs8RSAPem := pemBlock(t, "PRIVATE KEY", pkcs8RSA, err)
+	pkixRSA, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	pkixRSAPem := pemBlock(t, "PUBLIC KEY", pkixRSA, err)
+	pkcs8Ed, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	pkcs8EdPem := pemBlock(t, "PRIVATE KEY", pkcs8Ed, err)
+	pkixEd, err := x509.MarshalPKIXPublicKey(publicKey)
+	pkixEdPem := pemBlock(t, "PUBLIC KEY", pkixEd, err)
+	shortKey := generateRSAKey(t, 1024)
+
+	cases := []struct {
+		name    string
+		data    []byte
+		alg     string
+		private bool
+	}{
+		{"pkcs1 rsa", pemBlock(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil), "RS256", true},
+		{"pkcs8 rsa", pkcs8RSAPem, "RS256", true},
+		{"pkix rsa", pkixRSAPem, "RS256", false},
+		{"pkcs8 ed25519", pkcs8EdPem, "EdDSA", true},
+		{"pkix ed25519", pkixEdPem, "EdDSA", false},
+	}
+	for _, c := range cases {
+		key, err := ParseKey(c.name, c.data)
+		if err != nil {
+			t.Errorf("%s: err = %v", c.name, err)
+			continue
+		}
+		if key.Method.Alg() != c.alg || (key.PrivateKey != nil) != c.private || key.PublicKey == nil {
+			t.Errorf("%s: key = %+v", c.name, key)
+		}
+	}
+
+	invalid := [][]byte{
+		[]byte("not a pem"),
+		pemBlock(t, "CERTIFICATE", []byte("abc"), nil),
+		pemBlock(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(shortKey), nil),
+	}
+	for i, data := range invalid {
+		if _, err := ParseKey("invalid", data); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("invalid key %d: err = %v; expected %v", i, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := newKey("2021-01", generateRSAKey(t, 2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := GenerateKey("2021-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldSet, err := NewKeySet("", rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldSet.Sign(claims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key signs after the rotation, the old tokens are still valid
+	set, err := NewKeySet("", rsaKey, edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.SigningKeyID() != "2021-02" {
+		t.Errorf("signing kid = %s; expected 2021-02", set.SigningKeyID())
+	}
+	newToken, err := set.Sign(claims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tokenString := range map[string]string{"old": oldToken, "new": newToken} {
+		parsed := &jwt.StandardClaims{}
+		if _, err := set.Parse(tokenString, parsed); err != nil || parsed.Subject != "1001" {
+			t.Errorf("parse %s token: claims = %+v, err = %v", name, parsed, err)
+		}
+	}
+	if _, err := oldSet.Parse(newToken, &jwt.StandardClaims{}); err == nil {
+		t.Error("parse token of unknown kid: expected error")
+	}
+
+	expired, _ := set.Sign(claims(time.Now().Add(-time.Minute)))
+	_, err = set.Parse(expired, &jwt.StandardClaims{})
+	if validationErr, ok := err.(*jwt.ValidationError); !ok || validationErr.Errors != jwt.ValidationErrorExpired {
+		t.Errorf("parse expired token: err = %v", err)
+	}
+
+	// a token of the kid signed by another algorithm, the public key as a hmac secret or without signature
+	rs256Token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims(time.Now().Add(time.Minute)))
+	rs256Token.Header["kid"] = "2021-02"
+	mismatched, _ := rs256Token.SignedString(rsaKey.PrivateKey)
+	hs256Token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(time.Now().Add(time.Minute)))
+	hs256Token.Header["kid"] = "2021-02"
+	hmac, _ := hs256Token.SignedString([]byte(edKey.PublicKey.(ed25519.PublicKey)))
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims(time.Now().Add(time.Minute)))
+	noneToken.Header["kid"] = "2021-02"
+	none, _ := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	for name, tokenString := range map[string]string{"mismatched": mismatched, "hmac": hmac, "none": none} {
+		if _, err := set.Parse(tokenString, &jwt.StandardClaims{}); err == nil {
+			t.Errorf("parse %s token: expected error", name)
+		}
+	}
+
+	if _, err = NewKeySet("", &Key{ID: "public", Method: SigningMethodEdDSA, PublicKey: edKey.PublicKey}); err != ErrNoKey {
+		t.Errorf("key set without private key: err = %v; expected %v", err, ErrNoKey)
+	}
+	if _, err = NewKeySet("", rsaKey, rsaKey); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("key set of duplicate kid: err = %v; expected %v", err, ErrInvalidKey)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := newKey("rsa", generateRSAKey(t, 2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _ := GenerateKey("ed")
+	set, err := NewKeySet("rsa", rsaKey, edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.SigningKeyID() != "rsa" {
+		t.Errorf("signing kid = %s; expected rsa", set.SigningKeyID())
+	}
+	keys := set.JWKS().Keys
+	if len(keys) != 2 {
+		t.Fatalf("keys = %d; expected 2", len(keys))
+	}
+	if k := keys[0]; k.Kid != "rsa" || k.Kty != "RSA" || k.Alg != "RS256" || k.Use != "sig" || k.E != "AQAB" || k.N == "" {
+		t.Errorf("rsa jwk = %+v", k)
+	}
+	if k := keys[1]; k.Kid != "ed" || k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != "EdDSA" || len(k.X) != 43 {
+		t.Errorf("ed25519 jwk = %+v", k)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err = ioutil.WriteFile(filepath.Join(dir, "2021-03.pem"), pemBlock(t, "PRIVATE KEY", der, err), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].ID != "2021-03" || keys[0].Method != SigningMethodEdDSA {
+		t.Errorf("keys = %+v", keys)
+	}
+}
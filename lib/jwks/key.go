@@ -0,0 +1,108 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const minRSABits = 2048
+
+var (
+	ErrInvalidKey = errors.New("invalid jwt key")
+	ErrNoKey      = errors.New("no jwt signing key")
+)
+
+// Key is a key of the set, a key without the private key only verifies tokens
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// ParseKey parses a pem encoded RSA or Ed25519 key, which is a PKCS #8 or PKCS #1 private key or a PKIX public key
+func ParseKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w %s: no pem block", ErrInvalidKey, kid)
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w %s: unsupported pem type %s", ErrInvalidKey, kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidKey, kid, err)
+	}
+	return newKey(kid, parsed)
+}
+
+// GenerateKey generates an Ed25519 key
+func GenerateKey(kid string) (*Key, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(kid, privateKey)
+}
+
+// LoadDir loads the keys of the pem files in the dir, the kid of a key is the name of its file without the extension
+func LoadDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func newKey(kid string, parsed interface{}) (*Key, error) {
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%w %s: unsupported key type %T", ErrInvalidKey, kid, parsed)
+	}
+	if publicKey, ok := key.PublicKey.(*rsa.PublicKey); ok && publicKey.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("%w %s: rsa key is shorter than %d bits", ErrInvalidKey, kid, minRSABits)
+	}
+	return key, nil
+}
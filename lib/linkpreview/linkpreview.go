@@ -0,0 +1,150 @@
+package linkpreview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxPageSize  = 1 << 20
+	DefaultMaxImageSize = 5 << 20
+	maxRedirects        = 5
+	userAgent           = "Mozilla/5.0 (compatible; MisesBot/1.0; +https://mises.site)"
+)
+
+var (
+	ErrInvalidURL      = errors.New("invalid link url")
+	ErrNotHTML         = errors.New("link is not a html page")
+	ErrNotImage        = errors.New("preview image is not an image")
+	ErrTooLarge        = errors.New("response body is too large")
+	ErrPrivateAddress  = errors.New("link resolves to a private address")
+	ErrTooManyRedirect = errors.New("too many redirects")
+)
+
+// Fetcher sends the http request of the link, *http.Client is a Fetcher
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Preview struct {
+	URL         string
+	Host        string
+	Title       string
+	Description string
+	SiteName    string
+	ImageURL    string
+}
+
+type Image struct {
+	ContentType string
+	Data        []byte
+}
+
+type Client struct {
+	Fetcher      Fetcher
+	MaxPageSize  int64
+	MaxImageSize int64
+}
+
+// New returns a client which refuses to connect to private addresses
+func New(timeout time.Duration) *Client {
+	return NewWithFetcher(NewSafeHTTPClient(timeout))
+}
+
+func NewWithFetcher(fetcher Fetcher) *Client {
+	return &Client{
+		Fetcher:      fetcher,
+		MaxPageSize:  DefaultMaxPageSize,
+		MaxImageSize: DefaultMaxImageSize,
+	}
+}
+
+// ParseURL validates the link is an absolute http(s) url
+func ParseURL(link string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+	return u, nil
+}
+
+// Fetch downloads the page of the link and parses the preview metadata
+func (c *Client) Fetch(ctx context.Context, link string) (*Preview, error) {
+	u, err := ParseURL(link)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.get(ctx, u.String(), c.MaxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType(resp) != "text/html" && mediaType(resp) != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+	// the final url after redirects is used to resolve relative urls
+	preview := Parse(bytes.NewReader(body), resp.Request.URL)
+	preview.URL = u.String()
+	preview.Host = u.Hostname()
+	return preview, nil
+}
+
+// FetchImage downloads the preview image
+func (c *Client) FetchImage(ctx context.Context, imageURL string) (*Image, error) {
+	if _, err := ParseURL(imageURL); err != nil {
+		return nil, err
+	}
+	resp, body, err := c.get(ctx, imageURL, c.MaxImageSize)
+	if err != nil {
+		return nil, err
+	}
+	contentType := mediaType(resp)
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(body)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, ErrNotImage
+	}
+	return &Image{ContentType: contentType, Data: body}, nil
+}
+
+func (c *Client) get(ctx context.Context, rawURL string, maxSize int64) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.Fetcher.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetch %s: unexpected status %d", rawURL, resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, nil, ErrTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, nil, ErrTooLarge
+	}
+	return resp, body, nil
+}
+
+func mediaType(resp *http.Response) string {
+	tp, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return tp
+}
@@ -0,0 +1,115 @@
+package linkpreview
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!DOCTYPE html>
+<html><head>
+<title>  Fallback
+ title </title>
+<meta property="og:title" content="OpenGraph &amp; title">
+<meta name="twitter:title" content="Twitter title">
+<meta name="description" content="A test page">
+<meta property="og:image" content="/images/cover.png">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/images/cover.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\x89PNG\r\n\x1a\n0000"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetch(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := NewWithFetcher(server.Client())
+
+	preview, err := client.Fetch(context.Background(), server.URL+"/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Title != "OpenGraph & title" {
+		t.Errorf("title = %q; expected %q", preview.Title, "OpenGraph & title")
+	}
+	if preview.Description != "A test page" {
+		t.Errorf("description = %q; expected %q", preview.Description, "A test page")
+	}
+	if preview.ImageURL != server.URL+"/images/cover.png" {
+		t.Errorf("image url = %q; expected %q", preview.ImageURL, server.URL+"/images/cover.png")
+	}
+	if preview.Host != "127.0.0.1" {
+		t.Errorf("host = %q; expected %q", preview.Host, "127.0.0.1")
+	}
+
+	image, err := client.FetchImage(context.Background(), preview.ImageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.ContentType != "image/png" {
+		t.Errorf("content type = %q; expected %q", image.ContentType, "image/png")
+	}
+
+	client.MaxPageSize = 1024
+	if _, err = client.Fetch(context.Background(), server.URL+"/large"); err != ErrTooLarge {
+		t.Errorf("err = %v; expected %v", err, ErrTooLarge)
+	}
+	if _, err = client.Fetch(context.Background(), server.URL+"/json"); err != ErrNotHTML {
+		t.Errorf("err = %v; expected %v", err, ErrNotHTML)
+	}
+	if _, err = client.Fetch(context.Background(), "ftp://example.com/"); err != ErrInvalidURL {
+		t.Errorf("err = %v; expected %v", err, ErrInvalidURL)
+	}
+}
+
+func TestParseTitleFallback(t *testing.T) {
+	preview := Parse(strings.NewReader("<html><head><title>Only\ttitle</title></head></html>"), nil)
+	if preview.Title != "Only title" {
+		t.Errorf("title = %q; expected %q", preview.Title, "Only title")
+	}
+	if preview.ImageURL != "" {
+		t.Errorf("image url = %q; expected empty", preview.ImageURL)
+	}
+}
+
+func TestSafeHTTPClient(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	client := New(time.Second)
+	if _, err := client.Fetch(context.Background(), server.URL+"/page"); err == nil || !strings.Contains(err.Error(), ErrPrivateAddress.Error()) {
+		t.Errorf("err = %v; expected %v", err, ErrPrivateAddress)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.1.1", "169.254.169.254", "::1", "::ffff:127.0.0.1", "fd00::1"} {
+		if !IsPrivateIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be private", ip)
+		}
+	}
+	for _, ip := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		if IsPrivateIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be public", ip)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package linkpreview
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const maxTitleLength = 256
+
+// Parse reads the OpenGraph, Twitter card and <title> metadata from the head of the page,
+// relative image urls are resolved against base
+func Parse(r io.Reader, base *url.URL) *Preview {
+	metas := make(map[string]string)
+	var title strings.Builder
+	inTitle := false
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				break loop
+			case atom.Title:
+				inTitle = title.Len() == 0
+			case atom.Meta:
+				if hasAttr {
+					key, content := metaAttrs(z)
+					if _, ok := metas[key]; key != "" && !ok {
+						metas[key] = content
+					}
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Head:
+				break loop
+			case atom.Title:
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		}
+	}
+	preview := &Preview{
+		Title:       firstOf(metas, "og:title", "twitter:title"),
+		Description: firstOf(metas, "og:description", "twitter:description", "description"),
+		SiteName:    firstOf(metas, "og:site_name"),
+	}
+	if preview.Title == "" {
+		preview.Title = title.String()
+	}
+	preview.Title = truncate(strings.Join(strings.Fields(preview.Title), " "), maxTitleLength)
+	preview.Description = truncate(strings.Join(strings.Fields(preview.Description), " "), maxTitleLength*4)
+	if image := firstOf(metas, "og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		preview.ImageURL = resolve(base, image)
+	}
+	return preview
+}
+
+func metaAttrs(z *html.Tokenizer) (key, content string) {
+	for {
+		attr, val, more := z.TagAttr()
+		switch strings.ToLower(string(attr)) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(string(val)))
+			}
+		case "content":
+			content = strings.TrimSpace(string(val))
+		}
+		if !more {
+			return key, content
+		}
+	}
+}
+
+func firstOf(metas map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := metas[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
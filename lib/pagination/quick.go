@@ -34,10 +34,16 @@ type QuickPagination struct {
 type QuickPaginator struct {
 	Limit  int64   `json:"-"`
 	NextID string  `json:"-"`
+	Key    string  `json:"-"`
 	DB     *odm.DB `json:"-"`
 }
 
 func NewQuickPaginator(limit int64, nextID string, db *odm.DB) Paginator {
+	return NewQuickPaginatorWithKey(limit, nextID, "_id", db)
+}
+
+// NewQuickPaginatorWithKey paginates by an object id field other than _id
+func NewQuickPaginatorWithKey(limit int64, nextID string, key string, db *odm.DB) Paginator {
 	if limit == 0 {
 		limit = 50
 	}
@@ -45,14 +51,11 @@ func NewQuickPaginator(limit int64, nextID string, db *odm.DB) Paginator {
 	return &QuickPaginator{
 		Limit:  limit,
 		NextID: nextID,
+		Key:    key,
 		DB:     db,
 	}
 }
 
-type nextItem struct {
-	ID string `bson:"_id,omitempty"`
-}
-
 func (p *QuickPaginator) Paginate(dataSource interface{}) (Pagination, error) {
 	db := p.DB
 	var err error
@@ -61,20 +64,22 @@ func (p *QuickPaginator) Paginate(dataSource interface{}) (Pagination, error) {
 		if err != nil {
 			return nil, err
 		}
-		db = db.Where(bson.M{"_id": bson.M{"$lte": hex}})
+		db = db.Where(bson.M{p.Key: bson.M{"$lte": hex}})
 	}
-	err = db.Sort(bson.M{"_id": -1}).Limit(p.Limit).Find(dataSource).Error
+	err = db.Sort(bson.M{p.Key: -1}).Limit(p.Limit).Find(dataSource).Error
 	if err != nil {
 		return nil, err
 	}
 
-	items := make([]*nextItem, 0)
+	items := make([]bson.M, 0)
 	if err = db.Skip(p.Limit).Limit(1).Find(&items).Error; err != nil {
 		return nil, err
 	}
 	nextID := ""
 	if len(items) > 0 {
-		nextID = items[0].ID
+		if id, ok := items[0][p.Key].(primitive.ObjectID); ok {
+			nextID = id.Hex()
+		}
 	}
 	return &QuickPagination{
 		Limit:  p.Limit,
@@ -20,10 +20,11 @@ type TraditionalPagination struct {
 }
 
 type TraditionalPaginator struct {
-	PageNum  int64   `json:"-"`
-	PageSize int64   `json:"-"`
-	Offset   int64   `json:"-"`
-	DB       *odm.DB `json:"-"`
+	PageNum  int64       `json:"-"`
+	PageSize int64       `json:"-"`
+	Offset   int64       `json:"-"`
+	Sort     interface{} `json:"-"`
+	DB       *odm.DB     `json:"-"`
 }
 
 func DefaultTraditionalParams() *TraditionalParams {
@@ -47,6 +48,11 @@ func NewTraditionalParams(pageNum, pageSize int64) *TraditionalParams {
 }
 
 func NewTraditionalPaginator(pageNum, pageSize int64, db *odm.DB) Paginator {
+	return NewTraditionalPaginatorWithSort(pageNum, pageSize, bson.M{"_id": -1}, db)
+}
+
+// NewTraditionalPaginatorWithSort paginates with a sort other than the latest first
+func NewTraditionalPaginatorWithSort(pageNum, pageSize int64, sort interface{}, db *odm.DB) Paginator {
 	if pageNum <= 0 {
 		pageNum = 1
 	}
@@ -59,6 +65,7 @@ func NewTraditionalPaginator(pageNum, pageSize int64, db *odm.DB) Paginator {
 		PageNum:  pageNum,
 		PageSize: pageSize,
 		Offset:   offset,
+		Sort:     sort,
 		DB:       db,
 	}
 }
@@ -71,7 +78,7 @@ func (p *TraditionalPaginator) Paginate(dataSource interface{}) (Pagination, err
 	if err != nil {
 		return nil, err
 	}
-	err = db.Sort(bson.M{"_id": -1}).Limit(p.PageSize).Skip(p.Offset).Find(dataSource).Error
+	err = db.Sort(p.Sort).Limit(p.PageSize).Skip(p.Offset).Find(dataSource).Error
 	if err != nil {
 		return nil, err
 	}
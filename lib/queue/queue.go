@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	// a dead job has used up its attempts, it is kept for inspection and never run again
+	JobDead JobStatus = "dead"
+)
+
+var ErrLeaseLost = errors.New("job lease lost")
+
+type Job struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Name        string             `bson:"name"`
+	Payload     bson.Raw           `bson:"payload,omitempty"`
+	Status      JobStatus          `bson:"status"`
+	Attempts    int                `bson:"attempts"`
+	MaxAttempts int                `bson:"max_attempts"`
+	RunAt       time.Time          `bson:"run_at"`
+	LockedBy    string             `bson:"locked_by,omitempty"`
+	LockedUntil time.Time          `bson:"locked_until,omitempty"`
+	LastError   string             `bson:"last_error,omitempty"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
+
+// Decode unmarshals the payload of the job into v
+func (j *Job) Decode(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return bson.Unmarshal(j.Payload, v)
+}
+
+type EnqueueOption func(job *Job)
+
+// RunAt schedules the job to run not before t
+func RunAt(t time.Time) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = t
+	}
+}
+
+// Delay schedules the job to run after d
+func Delay(d time.Duration) EnqueueOption {
+	return func(job *Job) {
+		job.RunAt = time.Now().Add(d)
+	}
+}
+
+func MaxAttempts(n int) EnqueueOption {
+	return func(job *Job) {
+		job.MaxAttempts = n
+	}
+}
+
+// Queue stores the jobs in a mongo collection, a dequeued job is leased to the worker
+// and is run again by another worker if the lease expires before it is completed
+type Queue struct {
+	LeaseTime   time.Duration
+	MaxAttempts int
+	// DoneRetention is how long the done jobs are kept
+	DoneRetention time.Duration
+	Backoff       func(attempts int) time.Duration
+	collection    *mongo.Collection
+}
+
+func New(collection *mongo.Collection) *Queue {
+	return &Queue{
+		LeaseTime:     time.Minute,
+		MaxAttempts:   5,
+		DoneRetention: 24 * time.Hour,
+		Backoff:       ExponentialBackoff(10*time.Second, time.Hour),
+		collection:    collection,
+	}
+}
+
+// ExponentialBackoff doubles the delay of each retry up to max, with a random jitter of up to 20%
+func ExponentialBackoff(base, max time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+	}
+}
+
+func (q *Queue) EnsureIndex(ctx context.Context) error {
+	_, err := q.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "name", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}},
+		},
+		{
+			Keys:    bson.M{"finished_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(q.DoneRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+// Enqueue adds a job to run as soon as possible, the payload must marshal to a bson document
+func (q *Queue) Enqueue(ctx context.Context, name string, payload interface{}, opts ...EnqueueOption) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		Name:        name,
+		Status:      JobPending,
+		MaxAttempts: q.MaxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if payload != nil {
+		data, err := bson.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		job.Payload = data
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	result, err := q.collection.InsertOne(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return job, nil
+}
+
+// Dequeue leases the earliest due job of the names to the worker, it returns nil if no job is due.
+// A job whose lease expired on its last attempt is dead-lettered instead.
+func (q *Queue) Dequeue(ctx context.Context, workerID string, names ...string) (*Job, error) {
+	for {
+		now := time.Now()
+		job := &Job{}
+		err := q.collection.FindOneAndUpdate(ctx, bson.M{
+			"name": bson.M{"$in": names},
+			"$or": bson.A{
+				bson.M{"status": JobPending, "run_at": bson.M{"$lte": now}},
+				bson.M{"status": JobRunning, "locked_until": bson.M{"$lte": now}},
+			},
+		}, bson.M{
+			"$set": bson.M{
+				"status":       JobRunning,
+				"locked_by":    workerID,
+				"locked_until": now.Add(q.LeaseTime),
+				"updated_at":   now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		}, options.FindOneAndUpdate().SetSort(bson.M{"run_at": 1}).SetReturnDocument(options.After)).Decode(job)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if job.Attempts <= job.MaxAttempts {
+			return job, nil
+		}
+		if err = q.finish(ctx, job, JobDead, "lease expired"); err != nil && err != ErrLeaseLost {
+			return nil, err
+		}
+	}
+}
+
+// Extend renews the lease of a running job
+func (q *Queue) Extend(ctx context.Context, job *Job) error {
+	return q.updateLeased(ctx, job, bson.M{"$set": bson.M{"locked_until": time.Now().Add(q.LeaseTime)}})
+}
+
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	return q.finish(ctx, job, JobDone, "")
+}
+
+// Fail schedules the job to retry with backoff, or dead-letters it if it has used up its attempts
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error) error {
+	if job.Attempts >= job.MaxAttempts {
+		return q.finish(ctx, job, JobDead, cause.Error())
+	}
+	now := time.Now()
+	return q.updateLeased(ctx, job, bson.M{
+		"$set": bson.M{
+			"status":     JobPending,
+			"run_at":     now.Add(q.Backoff(job.Attempts)),
+			"last_error": cause.Error(),
+			"updated_at": now,
+		},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	})
+}
+
+// Retry puts a dead job back to the queue with fresh attempts
+func (q *Queue) Retry(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	result, err := q.collection.UpdateOne(ctx, bson.M{"_id": id, "status": JobDead}, bson.M{
+		"$set":   bson.M{"status": JobPending, "attempts": 0, "run_at": now, "updated_at": now},
+		"$unset": bson.M{"finished_at": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (q *Queue) finish(ctx context.Context, job *Job, status JobStatus, lastError string) error {
+	now := time.Now()
+	set := bson.M{"status": status, "updated_at": now}
+	if status == JobDone {
+		// only done jobs expire
+		set["finished_at"] = now
+	}
+	if lastError != "" {
+		set["last_error"] = lastError
+	}
+	return q.updateLeased(ctx, job, bson.M{"$set": set, "$unset": bson.M{"locked_by": "", "locked_until": ""}})
+}
+
+// updateLeased only updates the job if it is still leased to the worker of this attempt
+func (q *Queue) updateLeased(ctx context.Context, job *Job, update bson.M) error {
+	result, err := q.collection.UpdateOne(ctx, bson.M{
+		"_id":       job.ID,
+		"status":    JobRunning,
+		"locked_by": job.LockedBy,
+		"attempts":  job.Attempts,
+	}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+	cases := map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	}
+	for attempts, expected := range cases {
+		delay := backoff(attempts)
+		if delay < expected || delay > expected+expected/5 {
+			t.Errorf("backoff(%d) = %v; expected %v with 20%% jitter", attempts, delay, expected)
+		}
+	}
+}
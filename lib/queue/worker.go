@@ -0,0 +1,138 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler runs a job, the job is retried if an error is returned
+type Handler func(ctx context.Context, job *Job) error
+
+// Worker runs the jobs of the registered handlers
+type Worker struct {
+	ID           string
+	Concurrency  int
+	PollInterval time.Duration
+	queue        *Queue
+	handlers     map[string]Handler
+}
+
+func NewWorker(queue *Queue) *Worker {
+	hostname, _ := os.Hostname()
+	return &Worker{
+		ID:           fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Concurrency:  4,
+		PollInterval: time.Second,
+		queue:        queue,
+		handlers:     make(map[string]Handler),
+	}
+}
+
+func (w *Worker) Register(name string, handler Handler) {
+	w.handlers[name] = handler
+}
+
+// Run polls the queue until ctx is done, then waits for the running jobs to finish.
+// The jobs are not cancelled by ctx, a job interrupted by a crash is run again after its lease expires.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < w.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Drain runs the due jobs one by one until none is left, it returns the number of jobs run
+func (w *Worker) Drain(ctx context.Context) (int, error) {
+	count := 0
+	for {
+		ran, err := w.runNext(ctx)
+		if err != nil || !ran {
+			return count, err
+		}
+		count++
+	}
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		ran, err := w.runNext(context.Background())
+		if err != nil {
+			logrus.Errorf("worker %s dequeue error: %v", w.ID, err)
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+func (w *Worker) runNext(ctx context.Context) (bool, error) {
+	job, err := w.queue.Dequeue(ctx, w.ID, w.names()...)
+	if err != nil || job == nil {
+		return false, err
+	}
+	if err = w.handle(ctx, job); err != nil {
+		logrus.Warnf("job %s %s attempt %d error: %v", job.Name, job.ID.Hex(), job.Attempts, err)
+		err = w.queue.Fail(ctx, job, err)
+	} else {
+		err = w.queue.Complete(ctx, job)
+	}
+	if err != nil {
+		logrus.Errorf("finish job %s %s error: %v", job.Name, job.ID.Hex(), err)
+	}
+	return true, nil
+}
+
+// handle runs the handler while the lease is renewed in the background
+func (w *Worker) handle(ctx context.Context, job *Job) (err error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(w.queue.LeaseTime / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := w.queue.Extend(ctx, job); err != nil {
+					logrus.Warnf("extend job %s lease error: %v", job.ID.Hex(), err)
+				}
+			}
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return w.handlers[job.Name](ctx, job)
+}
+
+func (w *Worker) names() []string {
+	names := make([]string, 0, len(w.handlers))
+	for name := range w.handlers {
+		names = append(names, name)
+	}
+	return names
+}
@@ -2,14 +2,29 @@ package storage
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type FileStore struct{}
+// FileStore stores the files in the local file system under Root
+type FileStore struct {
+	Root string
+	// BaseURL is where the files are served from, usually a web server of Root
+	BaseURL string
+	// SignedBaseURL is the api serving the files of the signed urls, they are signed with Secret
+	SignedBaseURL string
+	Secret        []byte
+}
 
 func (s *FileStore) Upload(ctx context.Context, filePath, filename string, file File) error {
+	filePath = path.Join(s.Root, filePath)
 	var err error
 	if _, err = os.Stat(filePath); os.IsNotExist(err) {
 		err := os.MkdirAll(filePath, os.ModePerm)
@@ -27,3 +42,70 @@ func (s *FileStore) Upload(ctx context.Context, filePath, filename string, file
 	}
 	return nil
 }
+
+func (s *FileStore) Delete(ctx context.Context, filePath, filename string) error {
+	err := os.Remove(path.Join(s.Root, filePath, filename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) Stat(ctx context.Context, filePath, filename string) (*FileInfo, error) {
+	info, err := os.Stat(path.Join(s.Root, filePath, filename))
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfo{
+		Size:         info.Size(),
+		ContentType:  contentTypeOf(filename),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (s *FileStore) Open(ctx context.Context, filePath, filename string) (io.ReadCloser, error) {
+	file, err := os.Open(path.Join(s.Root, filePath, filename))
+	if os.IsNotExist(err) {
+		return nil, ErrFileNotFound
+	}
+	return file, err
+}
+
+func (s *FileStore) URL(filePath, filename string) string {
+	return joinURL(s.BaseURL, path.Join(filePath, filename))
+}
+
+// SignedURL points to the api, which checks the signature and the expiry before serving the file
+func (s *FileStore) SignedURL(ctx context.Context, filePath, filename string, expires time.Duration) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", ErrNoSigningSecret
+	}
+	key := strings.TrimPrefix(path.Join(filePath, filename), Prefix)
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	return joinURL(s.SignedBaseURL, key) + "?expires=" + expiresAt + "&signature=" + s.signature(key, expiresAt), nil
+}
+
+// verify checks the signature of a signed url, the key is the path under the prefix
+func (s *FileStore) verify(key, expires, signature string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > expiresAt || len(s.Secret) == 0 {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *FileStore) signature(key, expires string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte("file:" + key + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + escapePath(key)
+}
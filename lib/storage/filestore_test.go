@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	store := &FileStore{
+		Root:          root,
+		BaseURL:       "http://localhost/",
+		SignedBaseURL: "http://localhost/api/v1/files/",
+		Secret:        []byte("secret"),
+	}
+	ctx := context.Background()
+	if err = store.Upload(ctx, "upload/a", "b c.jpg", strings.NewReader("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if u := store.URL("upload/a", "b c.jpg"); u != "http://localhost/upload/a/b%20c.jpg" {
+		t.Errorf("url = %q", u)
+	}
+	info, err := store.Stat(ctx, "upload/a", "b c.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 3 || info.ContentType != "image/jpeg" {
+		t.Errorf("stat = %+v", info)
+	}
+	file, err := store.Open(ctx, "upload/a", "b c.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(file)
+	file.Close()
+	if string(data) != "abc" {
+		t.Errorf("open = %q; expected %q", data, "abc")
+	}
+	if err = store.Delete(ctx, "upload/a", "b c.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(ctx, "upload/a", "b c.jpg"); err != nil {
+		t.Errorf("delete of missing file: err = %v", err)
+	}
+	if _, err = store.Stat(ctx, "upload/a", "b c.jpg"); err != ErrFileNotFound {
+		t.Errorf("stat of deleted file: err = %v; expected %v", err, ErrFileNotFound)
+	}
+}
+
+func TestFileStoreSignedURL(t *testing.T) {
+	store := &FileStore{SignedBaseURL: "http://localhost/api/v1/files/", Secret: []byte("secret")}
+	signed, err := store.SignedURL(context.Background(), "upload/a", "b.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/v1/files/a/b.jpg" {
+		t.Errorf("path = %q", u.Path)
+	}
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+	now := time.Now()
+	if err = store.verify("a/b.jpg", expires, signature, now); err != nil {
+		t.Errorf("verify: err = %v", err)
+	}
+	if err = store.verify("a/c.jpg", expires, signature, now); err != ErrInvalidSignature {
+		t.Errorf("verify of another file: err = %v; expected %v", err, ErrInvalidSignature)
+	}
+	if err = store.verify("a/b.jpg", expires, signature, now.Add(2*time.Minute)); err != ErrInvalidSignature {
+		t.Errorf("verify after expiry: err = %v; expected %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestFileStoreWithoutSecret(t *testing.T) {
+	store := &FileStore{SignedBaseURL: "http://localhost/api/v1/files/"}
+	if _, err := store.SignedURL(context.Background(), "upload/a", "b.jpg", time.Minute); err != ErrNoSigningSecret {
+		t.Errorf("signed url: err = %v; expected %v", err, ErrNoSigningSecret)
+	}
+	expires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	if err := store.verify("a/b.jpg", expires, store.signature("a/b.jpg", expires), time.Now()); err != ErrInvalidSignature {
+		t.Errorf("verify: err = %v; expected %v", err, ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+const defaultPartSize = 8 << 20
+
+// objectBackend is the object api of a S3 compatible storage, S3 and OSS share the multipart upload flow
+type objectBackend interface {
+	putObject(ctx context.Context, key, contentType string, data []byte) error
+	initiateMultipart(ctx context.Context, key, contentType string) (string, error)
+	uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error)
+	completeMultipart(ctx context.Context, key, uploadID string, parts []*completedPart) error
+	abortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []*completedPart `xml:"Part"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+// ObjectError is the error response of the object storage
+type ObjectError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("object storage error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// uploadObject puts a file smaller than a part in one request, larger files are uploaded in parts
+func uploadObject(ctx context.Context, backend objectBackend, key string, file File, partSize int64) error {
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	contentType := contentTypeOf(key)
+	data, err := readPart(file, partSize)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) < partSize {
+		return backend.putObject(ctx, key, contentType, data)
+	}
+	uploadID, err := backend.initiateMultipart(ctx, key, contentType)
+	if err != nil {
+		return err
+	}
+	parts := make([]*completedPart, 0)
+	for number := 1; len(data) > 0; number++ {
+		etag, err := backend.uploadPart(ctx, key, uploadID, number, data)
+		if err == nil {
+			parts = append(parts, &completedPart{PartNumber: number, ETag: etag})
+			data, err = readPart(file, partSize)
+		}
+		if err != nil {
+			_ = backend.abortMultipart(ctx, key, uploadID)
+			return err
+		}
+	}
+	if err = backend.completeMultipart(ctx, key, uploadID, parts); err != nil {
+		_ = backend.abortMultipart(ctx, key, uploadID)
+		return err
+	}
+	return nil
+}
+
+func readPart(file File, partSize int64) ([]byte, error) {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(file, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+func contentTypeOf(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// doObjectRequest returns the response body, an error response is decoded as ObjectError.
+// A complete multipart upload may fail with status 200, so the body is checked as well.
+func doObjectRequest(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices || bytes.Contains(body[:min(len(body), 128)], []byte("<Error>")) {
+		objectErr := &ObjectError{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(body, objectErr)
+		return nil, nil, objectErr
+	}
+	return resp, body, nil
+}
+
+// openObject returns the response of a GET or HEAD request with its body unread, the caller closes the body
+func openObject(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		objectErr := &ObjectError{StatusCode: resp.StatusCode}
+		if body, err := ioutil.ReadAll(resp.Body); err == nil {
+			_ = xml.Unmarshal(body, objectErr)
+		}
+		return nil, objectErr
+	}
+	return resp, nil
+}
+
+func objectFileInfo(resp *http.Response) *FileInfo {
+	info := &FileInfo{
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	info.LastModified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return info
+}
+
+// escapePath encodes the key as the object storages expect, the slashes are kept
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if isUnreserved(c) || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+func newObjectURL(endpoint *url.URL, host, p string, query url.Values) *url.URL {
+	return &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     host,
+		Path:     p,
+		RawPath:  escapePath(p),
+		RawQuery: encodeQuery(query),
+	}
+}
+
+// encodeQuery sorts the query by key, a key without value like ?uploads is kept without =
+func encodeQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			if value == "" {
+				pairs = append(pairs, escapeQuery(key))
+			} else {
+				pairs = append(pairs, escapeQuery(key)+"="+escapeQuery(value))
+			}
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -1,13 +1,220 @@
 package storage
 
 import (
+	"bytes"
 	"context"
-
-	"github.com/mises-id/sns/lib/codes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type OSSStorage struct{}
+type OSSConfig struct {
+	// Endpoint is the endpoint of the region, like https://oss-cn-hangzhou.aliyuncs.com
+	Endpoint        string
+	Bucket          string
+	AccessKeyID     string
+	AccessKeySecret string
+	// BaseURL serves the public files, like a CDN of the bucket
+	BaseURL string
+	// files larger than PartSize are uploaded in parts
+	PartSize int64
+}
+
+// OSSStorage stores the files in Aliyun OSS, the requests are signed with the OSS signature version 1
+type OSSStorage struct {
+	OSSConfig
+	Client   *http.Client
+	endpoint *url.URL
+}
+
+func NewOSSStorage(config OSSConfig) (*OSSStorage, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &OSSStorage{
+		OSSConfig: config,
+		Client:    http.DefaultClient,
+		endpoint:  endpoint,
+	}, nil
+}
 
 func (s *OSSStorage) Upload(ctx context.Context, filePath, filename string, file File) error {
-	return codes.ErrUnimplemented
+	return uploadObject(ctx, s, path.Join(filePath, filename), file, s.PartSize)
+}
+
+// SignedURL returns a presigned url to get the file, it is valid for expires
+func (s *OSSStorage) SignedURL(ctx context.Context, filePath, filename string, expires time.Duration) (string, error) {
+	key := path.Join(filePath, filename)
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	signature := s.signature(strings.Join([]string{http.MethodGet, "", "", expiresAt, s.canonicalResource(key, nil)}, "\n"))
+	return s.objectURL(key, url.Values{
+		"OSSAccessKeyId": {s.AccessKeyID},
+		"Expires":        {expiresAt},
+		"Signature":      {signature},
+	}).String(), nil
+}
+
+func (s *OSSStorage) Delete(ctx context.Context, filePath, filename string) error {
+	_, _, err := s.do(ctx, http.MethodDelete, path.Join(filePath, filename), nil, "", nil)
+	return err
+}
+
+func (s *OSSStorage) Stat(ctx context.Context, filePath, filename string) (*FileInfo, error) {
+	resp, err := s.open(ctx, http.MethodHead, path.Join(filePath, filename))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectFileInfo(resp), nil
+}
+
+func (s *OSSStorage) Open(ctx context.Context, filePath, filename string) (io.ReadCloser, error) {
+	resp, err := s.open(ctx, http.MethodGet, path.Join(filePath, filename))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// URL is the url of the file under BaseURL, or the url of the object in the bucket
+func (s *OSSStorage) URL(filePath, filename string) string {
+	key := path.Join(filePath, filename)
+	if s.BaseURL != "" {
+		return joinURL(s.BaseURL, key)
+	}
+	return s.objectURL(key, nil).String()
+}
+
+func (s *OSSStorage) objectURL(key string, query url.Values) *url.URL {
+	return newObjectURL(s.endpoint, s.Bucket+"."+s.endpoint.Host, "/"+key, query)
+}
+
+func (s *OSSStorage) newRequest(ctx context.Context, method, key string, query url.Values, contentType string, data []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, key, query, time.Now())
+	return req, nil
+}
+
+func (s *OSSStorage) do(ctx context.Context, method, key string, query url.Values, contentType string, data []byte) (*http.Response, []byte, error) {
+	req, err := s.newRequest(ctx, method, key, query, contentType, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doObjectRequest(s.Client, req)
+}
+
+func (s *OSSStorage) open(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := s.newRequest(ctx, method, key, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return openObject(s.Client, req)
+}
+
+func (s *OSSStorage) sign(req *http.Request, key string, query url.Values, now time.Time) {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("Authorization", "OSS "+s.AccessKeyID+":"+s.signature(s.stringToSign(req, key, query)))
+}
+
+func (s *OSSStorage) stringToSign(req *http.Request, key string, query url.Values) string {
+	ossHeaders := make([]string, 0)
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-oss-") {
+			ossHeaders = append(ossHeaders, name+":"+strings.TrimSpace(strings.Join(values, ","))+"\n")
+		}
+	}
+	sort.Strings(ossHeaders)
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		req.Header.Get("Date"),
+		strings.Join(ossHeaders, "") + s.canonicalResource(key, query),
+	}, "\n")
+}
+
+// canonicalResource is the bucket and key with the sub resources of the query, values are not escaped
+func (s *OSSStorage) canonicalResource(key string, query url.Values) string {
+	resource := "/" + s.Bucket + "/" + key
+	if len(query) == 0 {
+		return resource
+	}
+	keys := make([]string, 0, len(query))
+	for name := range query {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, name := range keys {
+		pairs[i] = name
+		if value := query.Get(name); value != "" {
+			pairs[i] += "=" + value
+		}
+	}
+	return resource + "?" + strings.Join(pairs, "&")
+}
+
+func (s *OSSStorage) signature(stringToSign string) string {
+	mac := hmac.New(sha1.New, []byte(s.AccessKeySecret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *OSSStorage) putObject(ctx context.Context, key, contentType string, data []byte) error {
+	_, _, err := s.do(ctx, http.MethodPut, key, nil, contentType, data)
+	return err
+}
+
+func (s *OSSStorage) initiateMultipart(ctx context.Context, key, contentType string) (string, error) {
+	_, body, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, contentType, nil)
+	if err != nil {
+		return "", err
+	}
+	result := &initiateMultipartUploadResult{}
+	if err = xml.Unmarshal(body, result); err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+func (s *OSSStorage) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	resp, _, err := s.do(ctx, http.MethodPut, key, url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadID},
+	}, "", data)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (s *OSSStorage) completeMultipart(ctx context.Context, key, uploadID string, parts []*completedPart) error {
+	data, err := xml.Marshal(&completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	_, _, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, "application/xml", data)
+	return err
+}
+
+func (s *OSSStorage) abortMultipart(ctx context.Context, key, uploadID string) error {
+	_, _, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, "", nil)
+	return err
 }
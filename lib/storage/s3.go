@@ -1,13 +1,173 @@
 package storage
 
 import (
+	"bytes"
 	"context"
-
-	"github.com/mises-id/sns/lib/codes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
 )
 
-type S3Storage struct{}
+type S3Config struct {
+	// Endpoint defaults to the AWS endpoint of the region, set it to use a S3 compatible storage like MinIO
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle puts the bucket in the path instead of the host
+	PathStyle bool
+	// BaseURL serves the public files, like a CDN of the bucket
+	BaseURL string
+	// files larger than PartSize are uploaded in parts
+	PartSize int64
+}
+
+type S3Storage struct {
+	S3Config
+	Client   *http.Client
+	endpoint *url.URL
+	signer   *sigV4Signer
+}
+
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{
+		S3Config: config,
+		Client:   http.DefaultClient,
+		endpoint: endpoint,
+		signer: &sigV4Signer{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			region:          config.Region,
+			service:         "s3",
+		},
+	}, nil
+}
 
 func (s *S3Storage) Upload(ctx context.Context, filePath, filename string, file File) error {
-	return codes.ErrUnimplemented
+	return uploadObject(ctx, s, path.Join(filePath, filename), file, s.PartSize)
+}
+
+// SignedURL returns a presigned url to get the file, it is valid for expires
+func (s *S3Storage) SignedURL(ctx context.Context, filePath, filename string, expires time.Duration) (string, error) {
+	return s.signer.presign(http.MethodGet, s.objectURL(path.Join(filePath, filename), nil), expires, time.Now()), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, filePath, filename string) error {
+	_, _, err := s.do(ctx, http.MethodDelete, path.Join(filePath, filename), nil, "", nil)
+	return err
+}
+
+func (s *S3Storage) Stat(ctx context.Context, filePath, filename string) (*FileInfo, error) {
+	resp, err := s.open(ctx, http.MethodHead, path.Join(filePath, filename))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectFileInfo(resp), nil
+}
+
+func (s *S3Storage) Open(ctx context.Context, filePath, filename string) (io.ReadCloser, error) {
+	resp, err := s.open(ctx, http.MethodGet, path.Join(filePath, filename))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// URL is the url of the file under BaseURL, or the url of the object in the bucket
+func (s *S3Storage) URL(filePath, filename string) string {
+	key := path.Join(filePath, filename)
+	if s.BaseURL != "" {
+		return joinURL(s.BaseURL, key)
+	}
+	return s.objectURL(key, nil).String()
+}
+
+func (s *S3Storage) objectURL(key string, query url.Values) *url.URL {
+	if s.PathStyle {
+		return newObjectURL(s.endpoint, s.endpoint.Host, "/"+s.Bucket+"/"+key, query)
+	}
+	return newObjectURL(s.endpoint, s.Bucket+"."+s.endpoint.Host, "/"+key, query)
+}
+
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, query url.Values, contentType string, data []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer.sign(req, payloadHash(data), time.Now())
+	return req, nil
+}
+
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, contentType string, data []byte) (*http.Response, []byte, error) {
+	req, err := s.newRequest(ctx, method, key, query, contentType, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doObjectRequest(s.Client, req)
+}
+
+func (s *S3Storage) open(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := s.newRequest(ctx, method, key, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return openObject(s.Client, req)
+}
+
+func (s *S3Storage) putObject(ctx context.Context, key, contentType string, data []byte) error {
+	_, _, err := s.do(ctx, http.MethodPut, key, nil, contentType, data)
+	return err
+}
+
+func (s *S3Storage) initiateMultipart(ctx context.Context, key, contentType string) (string, error) {
+	_, body, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, contentType, nil)
+	if err != nil {
+		return "", err
+	}
+	result := &initiateMultipartUploadResult{}
+	if err = xml.Unmarshal(body, result); err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3Storage) uploadPart(ctx context.Context, key, uploadID string, number int, data []byte) (string, error) {
+	resp, _, err := s.do(ctx, http.MethodPut, key, url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadID},
+	}, "", data)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (s *S3Storage) completeMultipart(ctx context.Context, key, uploadID string, parts []*completedPart) error {
+	data, err := xml.Marshal(&completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+	_, _, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, "application/xml", data)
+	return err
+}
+
+func (s *S3Storage) abortMultipart(ctx context.Context, key, uploadID string) error {
+	_, _, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, "", nil)
+	return err
 }
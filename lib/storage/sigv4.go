@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sigV4Signer signs the requests with AWS signature version 4
+type sigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	region          string
+	service         string
+}
+
+// sign adds the authorization header, all the headers of the request are signed
+func (s *sigV4Signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := map[string]string{"host": requestHost(req)}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	scope := s.scope(now)
+	signature := s.signature(now, req.Method, req.URL, canonicalHeaders.String(), signedHeaders, payloadHash)
+	req.Header.Set("Authorization", sigV4Algorithm+" Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign returns the url with the signature in the query, it is valid for expires
+func (s *sigV4Signer) presign(method string, u *url.URL, expires time.Duration, now time.Time) string {
+	signed := *u
+	query := signed.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.accessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.UTC().Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	signed.RawQuery = canonicalQuery(query)
+	signature := s.signature(now, method, &signed, "host:"+signed.Host+"\n", "host", unsignedPayload)
+	signed.RawQuery += "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+func (s *sigV4Signer) scope(now time.Time) string {
+	return now.UTC().Format("20060102") + "/" + s.region + "/" + s.service + "/aws4_request"
+}
+
+func (s *sigV4Signer) signature(now time.Time, method string, u *url.URL, canonicalHeaders, signedHeaders, payloadHash string) string {
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapePath(u.Path),
+		canonicalQuery(u.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.UTC().Format(amzDateFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery sorts the query by key, keys without value are kept as key=
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, escapeQuery(key)+"="+escapeQuery(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func payloadHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
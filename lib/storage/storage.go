@@ -2,34 +2,137 @@ package storage
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"io"
 	"path"
+	"time"
 
 	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/sirupsen/logrus"
 )
 
 var (
 	storageService IStorageService
 	Prefix         = "upload/"
+
+	ErrFileNotFound     = codes.ErrNotFound.New("file not found")
+	ErrInvalidSignature = codes.ErrForbidden.New("invalid signature")
+	ErrNoSigningSecret  = errors.New("STORAGE_SIGNING_SECRET is required by the signed urls of the local storage")
 )
 
 func init() {
+	var err error
 	switch env.Envs.StorageProvider {
 	default:
-		storageService = &FileStore{}
+		storageService, err = newFileStore()
 	case "local":
-		storageService = &FileStore{}
+		storageService, err = newFileStore()
+	case "s3":
+		storageService, err = NewS3Storage(S3Config{
+			Endpoint:        env.Envs.StorageEndpoint,
+			Region:          env.Envs.StorageRegion,
+			Bucket:          env.Envs.StorageBucket,
+			AccessKeyID:     env.Envs.StorageAccessKeyID,
+			SecretAccessKey: env.Envs.StorageAccessKeySecret,
+			BaseURL:         env.Envs.StorageBaseURL,
+			PathStyle:       env.Envs.StoragePathStyle,
+			PartSize:        env.Envs.StoragePartSize,
+		})
 	case "oss":
-		storageService = &OSSStorage{}
+		storageService, err = NewOSSStorage(OSSConfig{
+			Endpoint:        env.Envs.StorageEndpoint,
+			Bucket:          env.Envs.StorageBucket,
+			AccessKeyID:     env.Envs.StorageAccessKeyID,
+			AccessKeySecret: env.Envs.StorageAccessKeySecret,
+			BaseURL:         env.Envs.StorageBaseURL,
+			PartSize:        env.Envs.StoragePartSize,
+		})
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// newFileStore signs the urls with a secret of its own, so it never shares a key with the tokens.
+// Without the secret development and test sign with a temporary one, other environments have no signed urls.
+func newFileStore() (*FileStore, error) {
+	secret := []byte(env.Envs.StorageSigningSecret)
+	if len(secret) == 0 && (env.Envs.AppEnv == "development" || env.Envs.AppEnv == "test") {
+		logrus.Warn("STORAGE_SIGNING_SECRET is not set, file urls are signed by a temporary secret")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
 	}
+	return &FileStore{
+		Root:          env.Envs.RootPath,
+		BaseURL:       env.Envs.AssetHost,
+		SignedBaseURL: env.Envs.StorageSignedBaseURL,
+		Secret:        secret,
+	}, nil
 }
 
 func UploadFile(ctx context.Context, filePath, filename string, file File) error {
-	realPath := path.Join(env.Envs.RootPath, Prefix, filePath)
-	return storageService.Upload(ctx, realPath, filename, file)
+	return storageService.Upload(ctx, path.Join(Prefix, filePath), filename, file)
+}
+
+// DeleteFile deletes the file, deleting a missing file is not an error
+func DeleteFile(ctx context.Context, filePath, filename string) error {
+	return storageService.Delete(ctx, path.Join(Prefix, filePath), filename)
+}
+
+func StatFile(ctx context.Context, filePath, filename string) (*FileInfo, error) {
+	return storageService.Stat(ctx, path.Join(Prefix, filePath), filename)
+}
+
+func OpenFile(ctx context.Context, filePath, filename string) (io.ReadCloser, error) {
+	return storageService.Open(ctx, path.Join(Prefix, filePath), filename)
+}
+
+// FileURL is the public url of the file
+func FileURL(filePath, filename string) string {
+	return storageService.URL(path.Join(Prefix, filePath), filename)
+}
+
+// SignedFileURL is an url of the file which expires, private files are only accessible with it
+func SignedFileURL(ctx context.Context, filePath, filename string, expires time.Duration) (string, error) {
+	return storageService.SignedURL(ctx, path.Join(Prefix, filePath), filename, expires)
+}
+
+// OpenSignedFile opens the file of a signed url of the local file store, the key is the path under the prefix
+func OpenSignedFile(ctx context.Context, key, expires, signature string) (io.ReadCloser, *FileInfo, error) {
+	store, ok := storageService.(*FileStore)
+	if !ok {
+		return nil, nil, ErrFileNotFound
+	}
+	if err := store.verify(key, expires, signature, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	filePath, filename := path.Split(path.Join(Prefix, key))
+	info, err := store.Stat(ctx, filePath, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := store.Open(ctx, filePath, filename)
+	return file, info, err
 }
 
 type IStorageService interface {
 	Upload(ctx context.Context, filePath, filename string, file File) error
+	Delete(ctx context.Context, filePath, filename string) error
+	// Stat returns ErrFileNotFound if the file does not exist
+	Stat(ctx context.Context, filePath, filename string) (*FileInfo, error)
+	Open(ctx context.Context, filePath, filename string) (io.ReadCloser, error)
+	URL(filePath, filename string) string
+	SignedURL(ctx context.Context, filePath, filename string, expires time.Duration) (string, error)
+}
+
+type FileInfo struct {
+	Size         int64
+	ContentType  string
+	LastModified time.Time
 }
 
 type File interface {
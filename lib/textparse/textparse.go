@@ -0,0 +1,152 @@
+package textparse
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MaxHashtagLength  = 64
+	minUsernameLength = 2
+	maxUsernameLength = 20
+)
+
+var urlRegexp = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.\-]*://|www\.)[^\s<>"]+`)
+
+// Entity is a mention in the text, start and end are offsets counted in runes including the @ sign
+type Entity struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Mentions returns the @usernames of the text, mentions inside urls and emails are ignored
+func Mentions(text string) []*Entity {
+	entities := make([]*Entity, 0)
+	for _, tk := range scan(text, isMentionSign, isUsernameRune) {
+		length := len(tk.Body)
+		if length < minUsernameLength || length > maxUsernameLength {
+			continue
+		}
+		// a longer word of letters is not a username, like @名字
+		if next, _ := utf8.DecodeRuneInString(text[tk.End:]); isWordRune(next) {
+			continue
+		}
+		start := utf8.RuneCountInString(text[:tk.Start])
+		entities = append(entities, &Entity{
+			Text:  tk.Body,
+			Start: start,
+			End:   start + utf8.RuneCountInString(text[tk.Start:tk.End]),
+		})
+	}
+	return entities
+}
+
+// token is a #tag or @name found in the text, start and end are byte offsets including the sign
+type token struct {
+	Body  string
+	Start int
+	End   int
+}
+
+// Hashtags returns the normalized hashtags of the text without duplicates, in order of appearance.
+// Hashtags inside urls, like the fragment of a link, are ignored.
+func Hashtags(text string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tk := range scan(text, isHashtagSign, isHashtagRune) {
+		tag := NormalizeHashtag(tk.Body)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeHashtag folds the width and case of the tag, returns empty string if it is not a valid tag
+func NormalizeHashtag(tag string) string {
+	tag = strings.ToLower(norm.NFKC.String(strings.TrimLeft(tag, "#＃")))
+	length := utf8.RuneCountInString(tag)
+	if length == 0 || length > MaxHashtagLength {
+		return ""
+	}
+	hasNonDigit := false
+	for _, r := range tag {
+		if !isHashtagRune(r) {
+			return ""
+		}
+		if !unicode.IsDigit(r) {
+			hasNonDigit = true
+		}
+	}
+	// #1 is more likely a number than a topic
+	if !hasNonDigit {
+		return ""
+	}
+	return tag
+}
+
+// scan finds the tokens starting with a sign which is not preceded by a word character
+func scan(text string, isSign, isBody func(r rune) bool) []*token {
+	tokens := make([]*token, 0)
+	urls := urlRegexp.FindAllStringIndex(text, -1)
+	prev := rune(0)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isSign(r) || isWordRune(prev) || prev == '&' || inRanges(i, urls) {
+			prev = r
+			i += size
+			continue
+		}
+		end := i + size
+		for end < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[end:])
+			if !isBody(next) {
+				break
+			}
+			end += nextSize
+		}
+		if end > i+size {
+			tokens = append(tokens, &token{Body: text[i+size : end], Start: i, End: end})
+		}
+		prev, _ = utf8.DecodeLastRuneInString(text[:end])
+		i = end
+	}
+	return tokens
+}
+
+func inRanges(i int, ranges [][]int) bool {
+	for _, r := range ranges {
+		if i >= r[0] && i < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isHashtagSign(r rune) bool {
+	return r == '#' || r == '＃'
+}
+
+func isMentionSign(r rune) bool {
+	return r == '@' || r == '＠'
+}
+
+// usernames only contain ascii word characters
+func isUsernameRune(r rune) bool {
+	return r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isHashtagRune(r rune) bool {
+	return isWordRune(r) || r == '\u200c' || r == '\u200d'
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
@@ -0,0 +1,60 @@
+package textparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected []string
+	}{
+		{"hello #Mises and #mises again", []string{"mises"}},
+		{"#go_lang,#Golang.", []string{"go_lang", "golang"}},
+		{"中文话题 #区块链 和 ＃Ｗｅｂ３", []string{"区块链", "web3"}},
+		{"#café #नमस्ते", []string{"café", "नमस्ते"}},
+		{"issue#1 and #123 are not tags, but #2021年 is", []string{"2021年"}},
+		{"see https://example.com/page#section and www.test.com/#/home #real", []string{"real"}},
+		{"&#39; is an entity", []string{}},
+		{"## #", []string{}},
+	}
+	for _, c := range cases {
+		if tags := Hashtags(c.text); !reflect.DeepEqual(tags, c.expected) {
+			t.Errorf("Hashtags(%q) = %q; expected %q", c.text, tags, c.expected)
+		}
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	cases := map[string]string{
+		"#Mises":   "mises",
+		"ＭＩＳＥＳ":    "mises",
+		"a b":      "",
+		"42":       "",
+		"":         "",
+		"tag-name": "",
+	}
+	for tag, expected := range cases {
+		if normalized := NormalizeHashtag(tag); normalized != expected {
+			t.Errorf("NormalizeHashtag(%q) = %q; expected %q", tag, normalized, expected)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected []*Entity
+	}{
+		{"hi @alice and @bob_2!", []*Entity{{"alice", 3, 9}, {"bob_2", 14, 20}}},
+		{"你好 @alice", []*Entity{{"alice", 3, 9}}},
+		{"mail me@example.com or see https://x.com/@carol", []*Entity{}},
+		{"@a is too short, @名字 and @dave名字 are not usernames", []*Entity{}},
+	}
+	for _, c := range cases {
+		if entities := Mentions(c.text); !reflect.DeepEqual(entities, c.expected) {
+			t.Errorf("Mentions(%q) = %v; expected %v", c.text, entities, c.expected)
+		}
+	}
+}
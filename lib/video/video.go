@@ -0,0 +1,242 @@
+package video
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// the moov atom is read into memory, it is usually a few hundred kilobytes
+	maxMoovSize   = 64 << 20
+	boxHeaderSize = 8
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported video container")
+	ErrInvalid     = errors.New("invalid video file")
+)
+
+// Info is the metadata of the container, the codecs are the sample entry types like avc1 or mp4a
+type Info struct {
+	Container  string
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+}
+
+// the first atoms of a quicktime file without ftyp
+var quicktimeAtoms = map[string]bool{"moov": true, "mdat": true, "wide": true, "free": true, "skip": true}
+
+// ContentType sniffs the mp4 or quicktime container from the head of the file, it is empty for other formats
+func ContentType(head []byte) string {
+	if len(head) < boxHeaderSize {
+		return ""
+	}
+	boxType := string(head[4:8])
+	switch {
+	case boxType == "ftyp" && len(head) >= 12 && string(head[8:12]) == "qt  ":
+		return "video/quicktime"
+	case boxType == "ftyp":
+		return "video/mp4"
+	case quicktimeAtoms[boxType]:
+		return "video/quicktime"
+	}
+	return ""
+}
+
+// Probe reads the container metadata of the mp4 or mov file, the media data is not read
+func Probe(r io.ReaderAt, size int64) (*Info, error) {
+	info := &Info{Container: "mov"}
+	var moov []byte
+	for offset := int64(0); offset < size; {
+		header := make([]byte, 16)
+		n, err := r.ReadAt(header, offset)
+		if n < boxHeaderSize {
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			return nil, ErrInvalid
+		}
+		boxSize, boxType := int64(binary.BigEndian.Uint32(header)), string(header[4:8])
+		headerSize := int64(boxHeaderSize)
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if n < 16 {
+				return nil, ErrInvalid
+			}
+			boxSize, headerSize = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if offset == 0 && boxType != "ftyp" && !quicktimeAtoms[boxType] {
+			return nil, ErrUnsupported
+		}
+		// offset+boxSize may overflow with a large 64-bit size
+		if boxSize < headerSize || boxSize > size-offset {
+			return nil, ErrInvalid
+		}
+		switch boxType {
+		case "ftyp":
+			if n >= 12 && string(header[8:12]) != "qt  " {
+				info.Container = "mp4"
+			}
+		case "moov":
+			if boxSize > maxMoovSize {
+				return nil, ErrInvalid
+			}
+			moov = make([]byte, boxSize-headerSize)
+			if _, err = r.ReadAt(moov, offset+headerSize); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		offset += boxSize
+	}
+	if moov == nil {
+		return nil, ErrInvalid
+	}
+	if err := info.parseMoov(moov); err != nil {
+		return nil, err
+	}
+	if info.VideoCodec == "" {
+		return nil, ErrInvalid
+	}
+	return info, nil
+}
+
+func (info *Info) parseMoov(moov []byte) error {
+	return eachBox(moov, func(boxType string, data []byte) error {
+		switch boxType {
+		case "mvhd":
+			return info.parseMvhd(data)
+		case "trak":
+			return info.parseTrak(data)
+		}
+		return nil
+	})
+}
+
+func (info *Info) parseMvhd(data []byte) error {
+	var timescale, duration uint64
+	switch {
+	case len(data) >= 20 && data[0] == 0:
+		timescale, duration = uint64(binary.BigEndian.Uint32(data[12:])), uint64(binary.BigEndian.Uint32(data[16:]))
+	case len(data) >= 32 && data[0] == 1:
+		timescale, duration = uint64(binary.BigEndian.Uint32(data[20:])), binary.BigEndian.Uint64(data[24:])
+	default:
+		return ErrInvalid
+	}
+	if timescale > 0 {
+		info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	return nil
+}
+
+// parseTrak reads the handler and codec of the track, the size of the first video track is the size of the video
+func (info *Info) parseTrak(trak []byte) error {
+	var width, height int
+	var handler, codec string
+	err := eachBox(trak, func(boxType string, data []byte) error {
+		switch boxType {
+		case "tkhd":
+			var err error
+			width, height, err = parseTkhd(data)
+			return err
+		case "mdia":
+			return eachBox(data, func(boxType string, data []byte) error {
+				switch boxType {
+				case "hdlr":
+					if len(data) < 12 {
+						return ErrInvalid
+					}
+					handler = string(data[8:12])
+				case "minf":
+					codec = sampleEntryType(data)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch handler {
+	case "vide":
+		if info.VideoCodec == "" {
+			info.VideoCodec, info.Width, info.Height = codec, width, height
+		}
+	case "soun":
+		if info.AudioCodec == "" {
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
+
+// parseTkhd returns the display size of the track, a track rotated by 90 or 270 degrees has the sides swapped
+func parseTkhd(data []byte) (int, int, error) {
+	offset := 24
+	if len(data) > 0 && data[0] == 1 {
+		offset = 36
+	}
+	// reserved, layer, alternate group, volume and reserved are before the matrix
+	matrix := offset + 16
+	if len(data) < matrix+44 {
+		return 0, 0, ErrInvalid
+	}
+	width := int(binary.BigEndian.Uint32(data[matrix+36:]) >> 16)
+	height := int(binary.BigEndian.Uint32(data[matrix+40:]) >> 16)
+	a, b := int32(binary.BigEndian.Uint32(data[matrix:])), int32(binary.BigEndian.Uint32(data[matrix+4:]))
+	if a == 0 && b != 0 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// sampleEntryType is the type of the first entry of minf/stbl/stsd
+func sampleEntryType(minf []byte) string {
+	var entryType string
+	_ = eachBox(minf, func(boxType string, data []byte) error {
+		if boxType != "stbl" {
+			return nil
+		}
+		return eachBox(data, func(boxType string, data []byte) error {
+			if boxType == "stsd" && len(data) >= 16 {
+				entryType = string(data[12:16])
+			}
+			return nil
+		})
+	})
+	return entryType
+}
+
+// eachBox calls fn with the type and payload of the child boxes
+func eachBox(data []byte, fn func(boxType string, data []byte) error) error {
+	for len(data) > 0 {
+		if len(data) < boxHeaderSize {
+			return ErrInvalid
+		}
+		boxSize, headerSize := uint64(binary.BigEndian.Uint32(data)), uint64(boxHeaderSize)
+		switch boxSize {
+		case 0:
+			boxSize = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return ErrInvalid
+			}
+			boxSize, headerSize = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if boxSize < headerSize || boxSize > uint64(len(data)) {
+			return ErrInvalid
+		}
+		if err := fn(string(data[4:8]), data[headerSize:boxSize]); err != nil {
+			return err
+		}
+		data = data[boxSize:]
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func box(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	data := make([]byte, boxHeaderSize, boxHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(data, uint32(boxHeaderSize+len(payload)))
+	copy(data[4:], boxType)
+	return append(data, payload...)
+}
+
+func uint32s(values ...uint32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(data[4*i:], value)
+	}
+	return data
+}
+
+func track(handler, codec string, width, height uint32, rotated bool) []byte {
+	tkhd := make([]byte, 84)
+	matrix := uint32s(1<<16, 0, 0, 0, 1<<16, 0, 0, 0, 1<<30)
+	if rotated {
+		matrix = uint32s(0, 1<<16, 0, 0xffff0000, 0, 0, 0, 0, 1<<30)
+	}
+	copy(tkhd[40:], matrix)
+	copy(tkhd[76:], uint32s(width<<16, height<<16))
+	hdlr := append(uint32s(0, 0), []byte(handler)...)
+	stsd := append(uint32s(0, 1, 16), []byte(codec)...)
+	return box("trak",
+		box("tkhd", tkhd),
+		box("mdia", box("hdlr", hdlr), box("minf", box("stbl", box("stsd", stsd)))),
+	)
+}
+
+func movie(brand string, tracks ...[]byte) []byte {
+	moov := box("moov", append([][]byte{box("mvhd", uint32s(0, 0, 0, 1000, 2500))}, tracks...)...)
+	ftyp := box("ftyp", []byte(brand), uint32s(0))
+	return bytes.Join([][]byte{ftyp, box("mdat", make([]byte, 32)), moov}, nil)
+}
+
+func TestProbe(t *testing.T) {
+	data := movie("isom", track("soun", "mp4a", 0, 0, false), track("vide", "avc1", 1920, 1080, false))
+	info, err := Probe(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Info{Container: "mp4", Duration: 2500 * time.Millisecond, Width: 1920, Height: 1080, VideoCodec: "avc1", AudioCodec: "mp4a"}
+	if *info != *expected {
+		t.Errorf("info = %+v; expected %+v", info, expected)
+	}
+
+	data = movie("qt  ", track("vide", "hvc1", 1920, 1080, true))
+	info, err = Probe(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Container != "mov" || info.Width != 1080 || info.Height != 1920 || info.VideoCodec != "hvc1" {
+		t.Errorf("rotated quicktime info = %+v", info)
+	}
+	if ContentType(data) != "video/quicktime" {
+		t.Errorf("content type = %q; expected %q", ContentType(data), "video/quicktime")
+	}
+}
+
+func TestProbeFile(t *testing.T) {
+	data, err := ioutil.ReadFile("../../tests/test.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Probe(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Container != "mp4" || info.VideoCodec != "avc1" || info.Width == 0 || info.Height == 0 || info.Duration == 0 {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestProbeInvalid(t *testing.T) {
+	webm := []byte{0x1a, 0x45, 0xdf, 0xa3, 0x9f, 0x42, 0x86, 0x81, 0x01, 0x42, 0xf7}
+	tests := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"webm":         {webm, ErrUnsupported},
+		"no moov":      {box("ftyp", []byte("isom"), uint32s(0)), ErrInvalid},
+		"audio only":   {movie("isom", track("soun", "mp4a", 0, 0, false)), ErrInvalid},
+		"truncated":    {movie("isom", track("vide", "avc1", 640, 480, false))[:60], ErrInvalid},
+		"bad box size": {append(box("ftyp", []byte("isom")), 0, 0, 0, 4, 'm', 'o', 'o', 'v'), ErrInvalid},
+		"overflow size": {append(box("ftyp", []byte("isom")), 0, 0, 0, 1, 'm', 'd', 'a', 't',
+			0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff), ErrInvalid},
+	}
+	for name, test := range tests {
+		if _, err := Probe(bytes.NewReader(test.data), int64(len(test.data))); err != test.err {
+			t.Errorf("%s: err = %v; expected %v", name, err, test.err)
+		}
+	}
+}
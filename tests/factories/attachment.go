@@ -14,6 +14,8 @@ var attachmentFactory = factory.NewFactory(
 	&models.Attachment{},
 ).Attr("ID", func(args factory.Args) (interface{}, error) {
 	return uint64(0), nil
+}).Attr("UID", func(args factory.Args) (interface{}, error) {
+	return uint64(0), nil
 }).Attr("Filename", func(args factory.Args) (interface{}, error) {
 	return "", nil
 }).Attr("FileType", func(args factory.Args) (interface{}, error) {
@@ -31,6 +33,7 @@ func InitAttachments(args ...*models.Attachment) {
 	for _, arg := range args {
 		attachmentFactory.MustCreateWithOption(map[string]interface{}{
 			"ID":        arg.ID,
+			"UID":       arg.UID,
 			"Filename":  arg.Filename,
 			"FileType":  arg.FileType,
 			"CreatedAt": arg.CreatedAt,
@@ -0,0 +1,255 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns/lib/db"
+	jobQueue "github.com/mises-id/sns/lib/queue"
+	"github.com/mises-id/sns/tests"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QueueSuite struct {
+	tests.BaseTestSuite
+	collections []string
+	queue       *jobQueue.Queue
+}
+
+func (suite *QueueSuite) SetupSuite() {
+	suite.BaseTestSuite.SetupSuite()
+	suite.collections = []string{"jobs"}
+}
+
+func (suite *QueueSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+	suite.queue = jobQueue.New(db.DB().Collection("jobs"))
+}
+
+func (suite *QueueSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestQueue(t *testing.T) {
+	suite.Run(t, &QueueSuite{})
+}
+
+func (suite *QueueSuite) findJob(id primitive.ObjectID) *jobQueue.Job {
+	job := &jobQueue.Job{}
+	suite.Nil(db.DB().Collection("jobs").FindOne(context.Background(), bson.M{"_id": id}).Decode(job))
+	return job
+}
+
+func (suite *QueueSuite) TestDequeue() {
+	ctx := context.Background()
+	type payload struct {
+		StatusID string `bson:"status_id"`
+	}
+	enqueued, err := suite.queue.Enqueue(ctx, "test", &payload{StatusID: "1"})
+	suite.Nil(err)
+
+	suite.T().Run("lease the job to one worker", func(t *testing.T) {
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Equal(enqueued.ID, job.ID)
+		suite.Equal(jobQueue.JobRunning, job.Status)
+		suite.Equal("worker-a", job.LockedBy)
+		suite.Equal(1, job.Attempts)
+		decoded := &payload{}
+		suite.Nil(job.Decode(decoded))
+		suite.Equal("1", decoded.StatusID)
+
+		job, err = suite.queue.Dequeue(ctx, "worker-b", "test")
+		suite.Nil(err)
+		suite.Nil(job)
+	})
+
+	suite.T().Run("only the jobs of the names", func(t *testing.T) {
+		_, err := suite.queue.Enqueue(ctx, "other", nil)
+		suite.Nil(err)
+		job, err := suite.queue.Dequeue(ctx, "worker-b", "test")
+		suite.Nil(err)
+		suite.Nil(job)
+	})
+}
+
+func (suite *QueueSuite) TestLeaseExpiry() {
+	ctx := context.Background()
+	suite.queue.LeaseTime = 100 * time.Millisecond
+	enqueued, err := suite.queue.Enqueue(ctx, "test", nil)
+	suite.Nil(err)
+	leased, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+	suite.Nil(err)
+	suite.NotNil(leased)
+
+	suite.T().Run("redeliver the job after the lease expires", func(t *testing.T) {
+		time.Sleep(150 * time.Millisecond)
+		job, err := suite.queue.Dequeue(ctx, "worker-b", "test")
+		suite.Nil(err)
+		suite.Equal(enqueued.ID, job.ID)
+		suite.Equal("worker-b", job.LockedBy)
+		suite.Equal(2, job.Attempts)
+
+		suite.Equal(jobQueue.ErrLeaseLost, suite.queue.Complete(ctx, leased))
+		suite.Nil(suite.queue.Complete(ctx, job))
+		suite.Equal(jobQueue.JobDone, suite.findJob(job.ID).Status)
+	})
+
+	suite.T().Run("extend the lease", func(t *testing.T) {
+		_, err := suite.queue.Enqueue(ctx, "test", nil)
+		suite.Nil(err)
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		time.Sleep(60 * time.Millisecond)
+		suite.Nil(suite.queue.Extend(ctx, job))
+		time.Sleep(60 * time.Millisecond)
+		other, err := suite.queue.Dequeue(ctx, "worker-b", "test")
+		suite.Nil(err)
+		suite.Nil(other)
+		suite.Nil(suite.queue.Complete(ctx, job))
+	})
+
+	suite.T().Run("dead-letter the job whose lease expired on its last attempt", func(t *testing.T) {
+		enqueued, err := suite.queue.Enqueue(ctx, "test", nil, jobQueue.MaxAttempts(1))
+		suite.Nil(err)
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Equal(enqueued.ID, job.ID)
+		time.Sleep(150 * time.Millisecond)
+		job, err = suite.queue.Dequeue(ctx, "worker-b", "test")
+		suite.Nil(err)
+		suite.Nil(job)
+		dead := suite.findJob(enqueued.ID)
+		suite.Equal(jobQueue.JobDead, dead.Status)
+		suite.Equal("lease expired", dead.LastError)
+	})
+}
+
+func (suite *QueueSuite) TestFail() {
+	ctx := context.Background()
+	suite.queue.Backoff = func(attempts int) time.Duration { return time.Hour }
+	enqueued, err := suite.queue.Enqueue(ctx, "test", nil, jobQueue.MaxAttempts(2))
+	suite.Nil(err)
+
+	var job *jobQueue.Job
+	suite.T().Run("retry the failed job with backoff", func(t *testing.T) {
+		job, err = suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Nil(suite.queue.Fail(ctx, job, errors.New("failed")))
+		failed := suite.findJob(enqueued.ID)
+		suite.Equal(jobQueue.JobPending, failed.Status)
+		suite.Equal("failed", failed.LastError)
+		suite.Empty(failed.LockedBy)
+		suite.WithinDuration(time.Now().Add(time.Hour), failed.RunAt, time.Minute)
+		job, err = suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Nil(job)
+
+		_, err = db.DB().Collection("jobs").UpdateOne(ctx, bson.M{"_id": enqueued.ID}, bson.M{"$set": bson.M{"run_at": time.Now()}})
+		suite.Nil(err)
+		job, err = suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Equal(enqueued.ID, job.ID)
+		suite.Equal(2, job.Attempts)
+	})
+
+	suite.T().Run("dead-letter the job after max attempts", func(t *testing.T) {
+		suite.Nil(suite.queue.Fail(ctx, job, errors.New("failed again")))
+		dead := suite.findJob(enqueued.ID)
+		suite.Equal(jobQueue.JobDead, dead.Status)
+		suite.Equal("failed again", dead.LastError)
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Nil(job)
+	})
+
+	suite.T().Run("retry the dead job", func(t *testing.T) {
+		suite.Nil(suite.queue.Retry(ctx, enqueued.ID))
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Equal(enqueued.ID, job.ID)
+		suite.Equal(1, job.Attempts)
+		suite.NotNil(suite.queue.Retry(ctx, enqueued.ID))
+	})
+}
+
+func (suite *QueueSuite) TestRunAt() {
+	ctx := context.Background()
+	_, err := suite.queue.Enqueue(ctx, "test", nil, jobQueue.RunAt(time.Now().Add(time.Hour)))
+	suite.Nil(err)
+	later, err := suite.queue.Enqueue(ctx, "test", nil, jobQueue.RunAt(time.Now().Add(-time.Minute)))
+	suite.Nil(err)
+	earlier, err := suite.queue.Enqueue(ctx, "test", nil, jobQueue.RunAt(time.Now().Add(-time.Hour)))
+	suite.Nil(err)
+
+	for _, expected := range []primitive.ObjectID{earlier.ID, later.ID} {
+		job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+		suite.Nil(err)
+		suite.Equal(expected, job.ID)
+	}
+	job, err := suite.queue.Dequeue(ctx, "worker-a", "test")
+	suite.Nil(err)
+	suite.Nil(job)
+}
+
+func (suite *QueueSuite) TestWorker() {
+	ctx := context.Background()
+
+	suite.T().Run("recover a panicking handler", func(t *testing.T) {
+		worker := jobQueue.NewWorker(suite.queue)
+		worker.Register("panic", func(ctx context.Context, job *jobQueue.Job) error {
+			panic("boom")
+		})
+		enqueued, err := suite.queue.Enqueue(ctx, "panic", nil)
+		suite.Nil(err)
+		count, err := worker.Drain(ctx)
+		suite.Nil(err)
+		suite.Equal(1, count)
+		job := suite.findJob(enqueued.ID)
+		suite.Equal(jobQueue.JobPending, job.Status)
+		suite.Equal("panic: boom", job.LastError)
+	})
+
+	suite.T().Run("wait for the running job on shutdown", func(t *testing.T) {
+		worker := jobQueue.NewWorker(suite.queue)
+		worker.Concurrency, worker.PollInterval = 1, 10*time.Millisecond
+		started, release := make(chan struct{}), make(chan struct{})
+		worker.Register("block", func(ctx context.Context, job *jobQueue.Job) error {
+			close(started)
+			<-release
+			return nil
+		})
+		enqueued, err := suite.queue.Enqueue(ctx, "block", nil)
+		suite.Nil(err)
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		done := make(chan error, 1)
+		go func() {
+			done <- worker.Run(runCtx)
+		}()
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			suite.FailNow("job is not started")
+		}
+		cancel()
+		select {
+		case <-done:
+			suite.Fail("worker stopped before the running job finished")
+		case <-time.After(100 * time.Millisecond):
+		}
+		close(release)
+		select {
+		case err := <-done:
+			suite.Nil(err)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("worker is not stopped")
+		}
+		suite.Equal(jobQueue.JobDone, suite.findJob(enqueued.ID).Status)
+	})
+}
@@ -1,16 +1,30 @@
 package attachment
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/app/models/meta"
 	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/tests/factories"
 	"github.com/mises-id/sns/tests/rest"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AttachmentServerSuite struct {
@@ -21,7 +35,7 @@ type AttachmentServerSuite struct {
 
 func (suite *AttachmentServerSuite) SetupSuite() {
 	suite.RestBaseTestSuite.SetupSuite()
-	suite.collections = []string{"counters", "attachments"}
+	suite.collections = []string{"counters", "attachments", "users", "statuses", "status_revisions", "linkpreviews"}
 	suite.files = []string{path.Join(env.Envs.RootPath, "upload", "attachment")}
 }
 
@@ -58,6 +72,21 @@ func (suite *AttachmentServerSuite) TestUpload() {
 		resp.Value("data").Object().Value("id").Equal(1)
 	})
 
+	suite.T().Run("image variants are stored", func(t *testing.T) {
+		attachment := &models.Attachment{}
+		err := db.ODM(context.Background()).First(attachment, bson.M{"_id": 1}).Error
+		suite.Nil(err)
+		suite.Equal(3, len(attachment.Variants))
+		suite.True(attachment.Width > 0 && attachment.Height > 0)
+		folder := path.Join(env.Envs.RootPath, "upload", "attachment", time.Now().Format("2006/01/02"), "1")
+		for _, variant := range attachment.Variants {
+			_, err = os.Stat(path.Join(folder, variant.Filename))
+			suite.Nil(err)
+		}
+		url := fmt.Sprintf("http://localhost/upload/attachment/%s/1/test_small.jpg", time.Now().Format("2006/01/02"))
+		suite.Equal(url, attachment.VariantUrl("small"))
+	})
+
 	suite.T().Run("upload video success", func(t *testing.T) {
 		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
 			WithFile("file", "../../test.mp4").WithFormField("file_type", "video").
@@ -67,4 +96,275 @@ func (suite *AttachmentServerSuite) TestUpload() {
 		url := fmt.Sprintf("http://localhost/upload/attachment/%s/2/test.mp4", time.Now().Format("2006/01/02"))
 		resp.Value("data").Object().Value("url").Equal(url)
 	})
+	suite.T().Run("serve signed url", func(t *testing.T) {
+		attachment := &models.Attachment{}
+		err := db.ODM(context.Background()).First(attachment, bson.M{"_id": 2}).Error
+		suite.Nil(err)
+		signed, err := attachment.SignedFileUrl(context.Background(), time.Minute)
+		suite.Nil(err)
+		u, err := url.Parse(signed)
+		suite.Nil(err)
+		suite.Expect.GET(u.Path).WithQueryString(u.RawQuery).
+			Expect().Status(http.StatusOK).Header("Content-Type").Equal("video/mp4")
+		suite.Expect.GET(u.Path).WithQuery("expires", u.Query().Get("expires")).WithQuery("signature", "invalid").
+			Expect().Status(http.StatusForbidden)
+	})
+
+	suite.T().Run("delete files", func(t *testing.T) {
+		attachment := &models.Attachment{}
+		err := db.ODM(context.Background()).First(attachment, bson.M{"_id": 1}).Error
+		suite.Nil(err)
+		suite.Nil(attachment.DeleteFiles(context.Background()))
+		folder := path.Join(env.Envs.RootPath, "upload", "attachment", attachment.CreatedAt.Format("2006/01/02"), "1")
+		_, err = os.Stat(path.Join(folder, attachment.Filename))
+		suite.True(os.IsNotExist(err))
+		for _, variant := range attachment.Variants {
+			_, err = os.Stat(path.Join(folder, variant.Filename))
+			suite.True(os.IsNotExist(err))
+		}
+	})
+}
+
+func (suite *AttachmentServerSuite) TestUploadValidation() {
+	suite.T().Run("reject mismatched content", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.jpg").WithFormField("file_type", "video").
+			Expect().Status(http.StatusUnsupportedMediaType).JSON().Object()
+		resp.Value("code").Equal(codes.UnsupportedMediaCode)
+	})
+
+	token := suite.MockLoginUser("1001:123")
+	suite.T().Run("record the uploaded file", func(t *testing.T) {
+		image, err := ioutil.ReadFile("../../test.jpg")
+		suite.Nil(err)
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFileBytes("file", "../my photo.php", image).WithFormField("file_type", "image").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		data := resp.Value("data").Object()
+		data.Value("filename").Equal("my_photo.jpg")
+		data.Value("mime_type").Equal("image/jpeg")
+		attachment := &models.Attachment{}
+		err = db.ODM(context.Background()).First(attachment, bson.M{"_id": data.Value("id").Raw()}).Error
+		suite.Nil(err)
+		suite.Equal(uint64(1001), attachment.UID)
+		suite.True(attachment.Size > 0)
+		suite.Equal(64, len(attachment.Sha256))
+	})
+
+	suite.T().Run("reuse the same content", func(t *testing.T) {
+		ctx := context.Background()
+		old := time.Now().Add(-2 * env.Envs.AttachmentGracePeriod)
+		_, err := db.DB().Collection("attachments").UpdateOne(ctx, bson.M{"_id": 1}, bson.M{"$set": bson.M{"updated_at": old}})
+		suite.Nil(err)
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.jpg").WithFormField("file_type", "image").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("id").Equal(1)
+		var count int64
+		suite.Nil(db.ODM(ctx).Model(&models.Attachment{}).Where(bson.M{}).Count(&count).Error)
+		suite.Equal(int64(1), count)
+		attachment := &models.Attachment{}
+		suite.Nil(db.ODM(ctx).First(attachment, bson.M{"_id": 1}).Error)
+		suite.True(attachment.UpdatedAt.After(old))
+	})
+
+	suite.T().Run("reject upload over quota", func(t *testing.T) {
+		quota := env.Envs.UserStorageQuota
+		env.Envs.UserStorageQuota = 1
+		defer func() { env.Envs.UserStorageQuota = quota }()
+		suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.mp4").WithFormField("file_type", "video").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusForbidden)
+	})
+
+	suite.T().Run("reserve quota of concurrent uploads", func(t *testing.T) {
+		ctx := context.Background()
+		user, err := models.FindUser(ctx, 1001)
+		suite.Nil(err)
+		attachment := &models.Attachment{}
+		suite.Nil(db.ODM(ctx).First(attachment, bson.M{"_id": 1}).Error)
+		suite.Equal(attachment.Size, user.StorageUsed)
+
+		image, err := ioutil.ReadFile("../../test.jpg")
+		suite.Nil(err)
+		quota := env.Envs.UserStorageQuota
+		env.Envs.UserStorageQuota = user.StorageUsed + int64(len(image)) + 10
+		defer func() { env.Envs.UserStorageQuota = quota }()
+		var wg sync.WaitGroup
+		errs := make(chan error, 5)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// a different content for each upload, so none of them is deduplicated
+				data := append(append([]byte{}, image...), byte(i))
+				_, err := models.CreateAttachment(ctx, 1001, enum.ImageFile, "concurrent.jpg", bytes.NewReader(data))
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			} else {
+				suite.True(codes.ErrQuotaExceeded.Equal(err))
+			}
+		}
+		suite.Equal(1, succeeded)
+		user, err = models.FindUser(ctx, 1001)
+		suite.Nil(err)
+		suite.Equal(attachment.Size+int64(len(image))+1, user.StorageUsed)
+	})
+}
+
+func (suite *AttachmentServerSuite) TestUploadVideo() {
+	token := suite.MockLoginUser("1001:123")
+	suite.T().Run("probe video metadata", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.mp4").WithFormField("file_type", "video").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		data := resp.Value("data").Object()
+		data.Value("mime_type").Equal("video/mp4")
+		data.Value("width").Number().Gt(0)
+		data.Value("height").Number().Gt(0)
+		data.Value("duration").Number().Gt(0)
+		data.NotContainsKey("poster_url")
+	})
+
+	video, err := ioutil.ReadFile("../../test.mp4")
+	suite.Nil(err)
+	posterVideo := append(video, 0, 0, 0, 8, 'f', 'r', 'e', 'e')
+	var posterID uint64
+	suite.T().Run("upload a poster with the video", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFileBytes("file", "poster.mp4", posterVideo).WithFormField("file_type", "video").
+			WithFile("poster", "../../test.jpg").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		data := resp.Value("data").Object()
+		data.Value("poster_url").String().Contains("test_large.jpg")
+		attachment := &models.Attachment{}
+		err := db.ODM(context.Background()).First(attachment, bson.M{"_id": data.Value("id").Raw()}).Error
+		suite.Nil(err)
+		suite.NotEqual(uint64(0), attachment.PosterID)
+		posterID = attachment.PosterID
+	})
+
+	suite.T().Run("keep the poster of a duplicated video", func(t *testing.T) {
+		image, err := ioutil.ReadFile("../../test.jpg")
+		suite.Nil(err)
+		resp := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFileBytes("file", "poster.mp4", posterVideo).WithFormField("file_type", "video").
+			WithFileBytes("poster", "other.jpg", append(image, 0)).
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("poster_url").String().Contains("test_large.jpg")
+		attachment := &models.Attachment{}
+		err = db.ODM(context.Background()).First(attachment, bson.M{"_id": resp.Value("data").Object().Value("id").Raw()}).Error
+		suite.Nil(err)
+		suite.Equal(posterID, attachment.PosterID)
+	})
+
+	suite.T().Run("link a poster of another user", func(t *testing.T) {
+		image := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.jpg").WithFormField("file_type", "image").
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("id").Raw()
+		suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFile("file", "../../test.mp4").WithFormField("file_type", "video").
+			WithFormField("poster_id", image).
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusForbidden)
+	})
+
+	suite.T().Run("store webm without metadata", func(t *testing.T) {
+		webm := []byte{0x1a, 0x45, 0xdf, 0xa3, 0x9f, 0x42, 0x86, 0x81, 0x01, 0x42, 0xf7, 0x81, 0x01, 0x42, 0x82, 0x84, 'w', 'e', 'b', 'm'}
+		data := suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFileBytes("file", "test.webm", webm).WithFormField("file_type", "video").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+		data.Value("mime_type").Equal("video/webm")
+		data.Value("filename").String().Contains(".webm")
+	})
+
+	suite.T().Run("reject unsupported container", func(t *testing.T) {
+		avi := []byte{'R', 'I', 'F', 'F', 0x24, 0, 0, 0, 'A', 'V', 'I', ' ', 'L', 'I', 'S', 'T'}
+		suite.Expect.POST("/api/v1/attachment").WithMultipart().
+			WithFileBytes("file", "test.avi", avi).WithFormField("file_type", "video").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnsupportedMediaType)
+	})
+}
+
+func (suite *AttachmentServerSuite) TestSweepOrphanAttachments() {
+	ctx := context.Background()
+	old := time.Now().Add(-2 * env.Envs.AttachmentGracePeriod)
+	attachments := make([]*models.Attachment, 0)
+	for id := uint64(1); id <= 10; id++ {
+		attachments = append(attachments, &models.Attachment{ID: id, Filename: "test.jpg", FileType: enum.ImageFile, CreatedAt: old})
+	}
+	attachments[8].CreatedAt = time.Now()
+	// uploaded again recently
+	attachments[9].UpdatedAt = time.Now()
+	factories.InitAttachments(attachments...)
+	_, err := db.DB().Collection("attachments").UpdateOne(ctx, bson.M{"_id": 4}, bson.M{"$set": bson.M{"poster_id": 5}})
+	suite.Nil(err)
+	_, err = db.DB().Collection("attachments").UpdateOne(ctx, bson.M{"_id": 6}, bson.M{"$set": bson.M{"uid": 1001, "size": 100}})
+	suite.Nil(err)
+	factories.InitUsers(&models.User{UID: 1001, Misesid: "1001", AvatarID: 1})
+	_, err = db.DB().Collection("users").UpdateOne(ctx, bson.M{"_id": 1001}, bson.M{"$set": bson.M{"storage_used": 150}})
+	suite.Nil(err)
+	imageMeta := func(id uint64) json.RawMessage {
+		data, _ := json.Marshal(&meta.ImageMeta{Images: []*meta.MediaItem{{AttachmentID: id}}})
+		return data
+	}
+	videoMeta, _ := json.Marshal(&meta.VideoMeta{Videos: []*meta.MediaItem{{AttachmentID: 4}}})
+	liveStatus, deletedStatus := primitive.NewObjectID(), primitive.NewObjectID()
+	factories.InitStatuses(
+		&models.Status{ID: liveStatus, UID: 1001, StatusType: enum.ImageStatus, Meta: imageMeta(2)},
+		&models.Status{ID: primitive.NewObjectID(), UID: 1001, StatusType: enum.VideoStatus, Meta: videoMeta},
+		&models.Status{ID: deletedStatus, UID: 1001, StatusType: enum.ImageStatus, Meta: imageMeta(6)},
+	)
+	_, err = db.DB().Collection("statuses").UpdateOne(ctx, bson.M{"_id": deletedStatus}, bson.M{"$set": bson.M{"deleted_at": time.Now()}})
+	suite.Nil(err)
+	_, err = db.DB().Collection("status_revisions").InsertOne(ctx, &models.StatusRevision{
+		StatusID: liveStatus, UID: 1001, StatusType: enum.ImageStatus, Meta: imageMeta(7), CreatedAt: old,
+	})
+	suite.Nil(err)
+	_, err = db.DB().Collection("linkpreviews").InsertOne(ctx, &models.LinkPreview{URL: "http://www.test.com", AttachmentID: 3})
+	suite.Nil(err)
+
+	orphanIDs := func(orphans []*models.Attachment) []uint64 {
+		ids := make([]uint64, len(orphans))
+		for i, orphan := range orphans {
+			ids[i] = orphan.ID
+		}
+		return ids
+	}
+	var count int64
+	suite.T().Run("dry run", func(t *testing.T) {
+		orphans, err := models.SweepOrphanAttachments(ctx, env.Envs.AttachmentGracePeriod, true)
+		suite.Nil(err)
+		suite.ElementsMatch([]uint64{6, 8}, orphanIDs(orphans))
+		suite.Nil(db.ODM(ctx).Model(&models.Attachment{}).Where(bson.M{}).Count(&count).Error)
+		suite.Equal(int64(10), count)
+	})
+
+	suite.T().Run("sweep", func(t *testing.T) {
+		orphans, err := models.SweepOrphanAttachments(ctx, env.Envs.AttachmentGracePeriod, false)
+		suite.Nil(err)
+		suite.ElementsMatch([]uint64{6, 8}, orphanIDs(orphans))
+		suite.Nil(db.ODM(ctx).Model(&models.Attachment{}).Where(bson.M{"_id": bson.M{"$in": []uint64{6, 8}}}).Count(&count).Error)
+		suite.Equal(int64(0), count)
+		suite.Nil(db.ODM(ctx).Model(&models.Attachment{}).Where(bson.M{}).Count(&count).Error)
+		suite.Equal(int64(8), count)
+		user, err := models.FindUser(ctx, 1001)
+		suite.Nil(err)
+		suite.Equal(int64(50), user.StorageUsed)
+	})
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
 	"github.com/mises-id/sns/lib/db"
 	"github.com/mises-id/sns/tests/factories"
 	"github.com/mises-id/sns/tests/rest"
@@ -21,7 +22,7 @@ type FollowServerSuite struct {
 
 func (suite *FollowServerSuite) SetupSuite() {
 	suite.RestBaseTestSuite.SetupSuite()
-	suite.collections = []string{"counters", "users", "follows"}
+	suite.collections = []string{"counters", "users", "follows", "timelines", "notifications", "followrequests", "statuses", "userrelations"}
 }
 
 func (suite *FollowServerSuite) TearDownSuite() {
@@ -164,3 +165,145 @@ func (suite *FollowServerSuite) TestListFriendship() {
 		suite.False(f.IsFriend)
 	})
 }
+
+func (suite *FollowServerSuite) TestPrivateAccount() {
+	factories.InitUsers(&models.User{
+		UID:     uint64(1001),
+		Misesid: "1001",
+	}, &models.User{
+		UID:     uint64(1002),
+		Misesid: "1002",
+	}, &models.User{
+		UID:     uint64(1003),
+		Misesid: "1003",
+	})
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	token3 := suite.MockLoginUser("1003:123")
+	resp := suite.Expect.PATCH("/api/v1/user/me").WithJSON(map[string]interface{}{
+		"by":      "privacy",
+		"privacy": map[string]interface{}{"is_private": true},
+	}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+	resp.Value("data").Object().Value("is_private").Equal(true)
+
+	suite.T().Run("follow private user", func(t *testing.T) {
+		for _, token := range []string{token2, token3} {
+			resp := suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+				WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+			resp.Value("data").Object().Value("pending").Equal(true)
+		}
+		_, err := models.GetFollow(context.Background(), 1002, 1001)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		resp := suite.Expect.GET("/api/v1/user/follow_request").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("user").Object().Value("uid").Equal(1003)
+	})
+
+	suite.T().Run("non followers are refused", func(t *testing.T) {
+		suite.Expect.GET("/api/v1/user/1001/status").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+		suite.Expect.GET("/api/v1/user/1001/friendship").Expect().Status(http.StatusForbidden)
+		suite.Expect.GET("/api/v1/user/1001/status").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+	})
+
+	suite.T().Run("approve follow request", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/follow_request/approve").WithJSON(map[string]interface{}{"from_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		_, err := models.GetFollow(context.Background(), 1002, 1001)
+		suite.Nil(err)
+		user := &models.User{}
+		err = db.ODM(context.Background()).First(user, bson.M{"_id": 1001}).Error
+		suite.Nil(err)
+		suite.Equal(int64(1), user.FansCount)
+		suite.Expect.GET("/api/v1/user/1001/status").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.POST("/api/v1/user/follow_request/approve").WithJSON(map[string]interface{}{"from_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusNotFound)
+	})
+
+	suite.T().Run("reject and cancel follow request", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/follow_request/reject").WithJSON(map[string]interface{}{"from_user_id": 1003}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		_, err := models.GetFollowRequest(context.Background(), 1003, 1001)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK)
+		suite.Expect.DELETE("/api/v1/user/follow_request").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK)
+		_, err = models.GetFollowRequest(context.Background(), 1003, 1001)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		user := &models.User{}
+		err = db.ODM(context.Background()).First(user, bson.M{"_id": 1001}).Error
+		suite.Nil(err)
+		suite.Equal(int64(1), user.FansCount)
+	})
+
+	suite.T().Run("refuse to approve a request after a block", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK)
+		// the block is created before its cleanup of the requests
+		_, err := models.CreateUserRelation(context.Background(), 1003, 1001, enum.Block)
+		suite.Nil(err)
+		suite.Expect.POST("/api/v1/user/follow_request/approve").WithJSON(map[string]interface{}{"from_user_id": 1003}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusForbidden)
+		_, err = models.GetFollow(context.Background(), 1003, 1001)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		_, err = models.GetFollowRequest(context.Background(), 1003, 1001)
+		suite.Nil(err)
+	})
+}
+
+func (suite *FollowServerSuite) TestReconcileCounters() {
+	factories.InitUsers(&models.User{
+		UID:     uint64(1001),
+		Misesid: "1001",
+	}, &models.User{
+		UID:     uint64(1002),
+		Misesid: "1002",
+	})
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	findUser := func(uid uint64) *models.User {
+		user := &models.User{}
+		suite.Nil(db.ODM(context.Background()).First(user, bson.M{"_id": uid}).Error)
+		return user
+	}
+
+	suite.T().Run("follow and unfollow are idempotent", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+				WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		}
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Equal(int64(1), findUser(1001).FollowingCount)
+		suite.Equal(int64(1), findUser(1002).FansCount)
+		for i := 0; i < 2; i++ {
+			suite.Expect.DELETE("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+				WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		}
+		suite.Equal(int64(0), findUser(1001).FollowingCount)
+		suite.Equal(int64(0), findUser(1002).FansCount)
+		f, err := models.GetFollow(context.Background(), 1002, 1001)
+		suite.Nil(err)
+		suite.False(f.IsFriend)
+	})
+
+	suite.T().Run("repair drifted counters", func(t *testing.T) {
+		_, err := db.DB().Collection("users").UpdateOne(context.Background(), bson.M{"_id": 1001},
+			bson.M{"$set": bson.M{"fans_count": 5, "following_count": 3}})
+		suite.Nil(err)
+		drifts, err := models.ReconcileUserCounters(context.Background(), true)
+		suite.Nil(err)
+		suite.Equal(2, len(drifts))
+		suite.Equal(int64(5), findUser(1001).FansCount)
+		drifts, err = models.ReconcileUserCounters(context.Background(), false)
+		suite.Nil(err)
+		suite.Equal(2, len(drifts))
+		user := findUser(1001)
+		suite.Equal(int64(1), user.FansCount)
+		suite.Equal(int64(0), user.FollowingCount)
+	})
+}
@@ -0,0 +1,163 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/codes"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/tests/factories"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type NotificationServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+	statuses    []*models.Status
+}
+
+func (suite *NotificationServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "users", "follows", "statuses", "likes", "timelines", "notifications"}
+}
+
+func (suite *NotificationServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *NotificationServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+	factories.InitUsers(&models.User{
+		UID:     uint64(1001),
+		Misesid: "1001",
+	}, &models.User{
+		UID:     uint64(1002),
+		Misesid: "1002",
+	}, &models.User{
+		UID:     uint64(1003),
+		Misesid: "1003",
+	})
+	suite.statuses = factories.InitDefaultStatuses()
+}
+
+func (suite *NotificationServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestNotificationServer(t *testing.T) {
+	suite.Run(t, &NotificationServerSuite{})
+}
+
+func (suite *NotificationServerSuite) TestNotification() {
+	token := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	token3 := suite.MockLoginUser("1003:123")
+	likePath := fmt.Sprintf("/api/v1/status/%s/like", suite.statuses[0].ID.Hex())
+
+	suite.T().Run("group likes", func(t *testing.T) {
+		suite.Expect.POST(likePath).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.POST(likePath).WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK)
+		suite.Expect.POST(likePath).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+
+		resp := suite.Expect.GET("/api/v1/notification").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("code").Equal(codes.SuccessCode)
+		resp.Value("data").Array().Length().Equal(1)
+		notification := resp.Value("data").Array().First().Object()
+		notification.Value("notify_type").Equal("like")
+		notification.Value("actors_count").Equal(2)
+		notification.Value("actors").Array().First().Object().Value("uid").Equal(1003)
+		notification.Value("status").Object().Value("id").Equal(suite.statuses[0].ID.Hex())
+	})
+
+	suite.T().Run("comment and follow notifications", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id": suite.statuses[0].ID.Hex(),
+			"content":   "a comment",
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK)
+
+		resp := suite.Expect.GET("/api/v1/notification").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(3)
+		resp.Value("data").Array().First().Object().Value("notify_type").Equal("follow")
+		resp.Value("data").Array().Element(1).Object().Value("notify_type").Equal("comment")
+		resp.Value("data").Array().Element(1).Object().Value("source").Object().Value("content").Equal("a comment")
+	})
+
+	suite.T().Run("unread count and mark read", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/notification/unread_count").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("count").Equal(3)
+
+		suite.Expect.POST("/api/v1/notification/read").WithJSON(map[string]interface{}{}).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		resp = suite.Expect.GET("/api/v1/notification/unread_count").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("count").Equal(0)
+	})
+}
+
+func (suite *NotificationServerSuite) TestGroupedNotificationActors() {
+	token := suite.MockLoginUser("1001:123")
+	ctx := context.Background()
+	like := func(actorUID uint64) {
+		suite.Nil(models.CreateNotification(ctx, &models.CreateNotificationParams{
+			UID:        1001,
+			ActorUID:   actorUID,
+			NotifyType: enum.NotifyLike,
+			StatusID:   suite.statuses[0].ID,
+		}))
+	}
+
+	suite.T().Run("count each actor once", func(t *testing.T) {
+		for uid := uint64(2001); uid <= 2012; uid++ {
+			like(uid)
+		}
+		// the first actor is no longer one of the latest actors
+		like(2001)
+		like(2012)
+		resp := suite.Expect.GET("/api/v1/notification").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("actors_count").Equal(12)
+	})
+
+	suite.T().Run("new actor of a read group", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/notification/read").WithJSON(map[string]interface{}{}).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		like(2013)
+		resp := suite.Expect.GET("/api/v1/notification/unread_count").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("count").Equal(1)
+		resp = suite.Expect.GET("/api/v1/notification").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("actors_count").Equal(1)
+	})
+
+	suite.T().Run("cap the actor set", func(t *testing.T) {
+		actorUIDSet := make([]uint64, 0, 1000)
+		for uid := uint64(3001); uid <= 4000; uid++ {
+			actorUIDSet = append(actorUIDSet, uid)
+		}
+		filter := bson.M{"uid": 1001, "read_at": nil}
+		_, err := db.DB().Collection("notifications").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"actor_uid_set": actorUIDSet}})
+		suite.Nil(err)
+		like(5001)
+		notification := &models.Notification{}
+		suite.Nil(db.ODM(ctx).First(notification, filter).Error)
+		suite.Len(notification.ActorUIDSet, 1000)
+		suite.Equal(uint64(3002), notification.ActorUIDSet[0])
+		suite.Equal(uint64(5001), notification.ActorUIDSet[999])
+		suite.Equal(uint64(2), notification.ActorsCount)
+	})
+}
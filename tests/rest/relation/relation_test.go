@@ -0,0 +1,173 @@
+package relation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/tests/factories"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RelationServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+}
+
+func (suite *RelationServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "users", "follows", "statuses", "likes", "timelines", "notifications", "userrelations"}
+}
+
+func (suite *RelationServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *RelationServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+	factories.InitUsers(&models.User{
+		UID:     uint64(1001),
+		Misesid: "1001",
+	}, &models.User{
+		UID:     uint64(1002),
+		Misesid: "1002",
+	}, &models.User{
+		UID:     uint64(1003),
+		Misesid: "1003",
+	})
+}
+
+func (suite *RelationServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestRelationServer(t *testing.T) {
+	suite.Run(t, &RelationServerSuite{})
+}
+
+func (suite *RelationServerSuite) createStatus(token string, params map[string]interface{}) string {
+	resp := suite.Expect.POST("/api/v1/status").WithJSON(params).
+		WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+	return resp.Value("data").Object().Value("id").String().Raw()
+}
+
+func (suite *RelationServerSuite) TestBlock() {
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	token3 := suite.MockLoginUser("1003:123")
+	suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+		WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+	suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+		WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+	statusID1 := suite.createStatus(token1, map[string]interface{}{"status_type": "text", "content": "status of 1001"})
+	suite.createStatus(token2, map[string]interface{}{"status_type": "text", "content": "status of 1002"})
+	statusID3 := suite.createStatus(token3, map[string]interface{}{"status_type": "text", "content": "status of 1003"})
+	suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{"status_id": statusID3, "content": "comment of 1002"}).
+		WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+
+	suite.T().Run("block user", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/block").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		_, err := models.GetFollow(context.Background(), 1001, 1002)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		_, err = models.GetFollow(context.Background(), 1002, 1001)
+		suite.Equal(mongo.ErrNoDocuments, err)
+		for _, uid := range []uint64{1001, 1002} {
+			user := &models.User{}
+			err = db.ODM(context.Background()).First(user, bson.M{"_id": uid}).Error
+			suite.Nil(err)
+			suite.Equal(int64(0), user.FansCount)
+			suite.Equal(int64(0), user.FollowingCount)
+		}
+		resp := suite.Expect.GET("/api/v1/user/block").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("user").Object().Value("uid").Equal(1002)
+	})
+
+	suite.T().Run("blocked user can not interact", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+		suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{"status_id": statusID1, "content": "blocked"}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+		suite.Expect.POST("/api/v1/status/"+statusID1+"/like").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text", "content": "forward", "parent_id": statusID1,
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+	})
+
+	suite.T().Run("blocked user is filtered out", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp = suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp = suite.Expect.GET("/api/v1/user/1002/status").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+		resp = suite.Expect.GET("/api/v1/comment").WithQuery("status_id", statusID3).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+		resp = suite.Expect.GET("/api/v1/comment").WithQuery("status_id", statusID3).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+	})
+
+	suite.T().Run("unblock user", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/user/block").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+	})
+}
+
+func (suite *RelationServerSuite) TestMute() {
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	statusID := suite.createStatus(token1, map[string]interface{}{"status_type": "text", "content": "status of 1001"})
+	suite.createStatus(token2, map[string]interface{}{"status_type": "text", "content": "status of 1002"})
+	suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{"status_id": statusID, "content": "comment of 1002"}).
+		WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+
+	suite.Expect.POST("/api/v1/user/mute").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+		WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+	resp := suite.Expect.GET("/api/v1/user/mute").
+		WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+	resp.Value("data").Array().Length().Equal(1)
+
+	suite.T().Run("muted user is hidden from feeds", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp = suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+	})
+
+	suite.T().Run("muted user is still visible elsewhere", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/user/1002/status").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp = suite.Expect.GET("/api/v1/comment").WithQuery("status_id", statusID).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+	})
+
+	suite.T().Run("unmute user", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/user/mute").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		resp := suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+	})
+}
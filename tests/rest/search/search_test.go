@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/tests/factories"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+}
+
+func (suite *SearchServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "users", "follows", "statuses", "likes"}
+}
+
+func (suite *SearchServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *SearchServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+	factories.InitUsers(&models.User{
+		UID:      uint64(1001),
+		Misesid:  "1001",
+		Username: "alice",
+	}, &models.User{
+		UID:      uint64(1002),
+		Misesid:  "1002",
+		Username: "bob",
+	})
+	now := time.Now()
+	statuses := []interface{}{
+		&models.Status{ID: primitive.NewObjectID(), UID: 1001, StatusType: enum.TextStatus, Content: "hello mises world"},
+		&models.Status{ID: primitive.NewObjectID(), UID: 1002, StatusType: enum.LinkStatus, Content: "a link", LinkTitle: "mises documents",
+			Meta: json.RawMessage(`{"title":"mises documents","link":"http://www.test.com/"}`)},
+		&models.Status{ID: primitive.NewObjectID(), UID: 1002, StatusType: enum.TextStatus, Content: "deleted mises status", DeletedAt: &now},
+		&models.Status{ID: primitive.NewObjectID(), UID: 1002, StatusType: enum.TextStatus, Content: "mises comment", FromType: enum.FromComment},
+		&models.Status{ID: primitive.NewObjectID(), UID: 1002, StatusType: enum.TextStatus, Content: "nothing related"},
+	}
+	_, err := db.DB().Collection("statuses").InsertMany(context.Background(), statuses)
+	suite.Nil(err)
+}
+
+func (suite *SearchServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestSearchServer(t *testing.T) {
+	suite.Run(t, &SearchServerSuite{})
+}
+
+func (suite *SearchServerSuite) TestSearch() {
+	suite.T().Run("search statuses", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/search").WithQuery("type", "status").WithQuery("q", "mises").
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("pagination").Object().Value("total_records").Equal(2)
+		resp.Value("data").Array().First().Object().Value("link_meta").Object().Value("title").Equal("mises documents")
+		resp.Value("data").Array().Last().Object().Value("user").Object().Value("username").Equal("alice")
+	})
+	suite.T().Run("search users", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/search").WithQuery("type", "user").WithQuery("q", "bob").
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("uid").Equal(1002)
+	})
+	suite.T().Run("search link title of an old status", func(t *testing.T) {
+		_, err := db.DB().Collection("statuses").InsertOne(context.Background(), &models.Status{
+			ID: primitive.NewObjectID(), UID: 1001, StatusType: enum.LinkStatus, Content: "an old link",
+			Meta: json.RawMessage(`{"title":"golang handbook","link":"http://www.test.com/go"}`),
+		})
+		suite.Nil(err)
+		suite.Expect.GET("/api/v1/search").WithQuery("type", "status").WithQuery("q", "handbook").
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Array().Length().Equal(0)
+		count, err := models.BackfillLinkTitles(context.Background())
+		suite.Nil(err)
+		suite.Equal(1, count)
+		resp := suite.Expect.GET("/api/v1/search").WithQuery("type", "status").WithQuery("q", "handbook").
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("content").Equal("an old link")
+	})
+	suite.T().Run("search with invalid params", func(t *testing.T) {
+		suite.Expect.GET("/api/v1/search").WithQuery("type", "user").
+			Expect().Status(http.StatusBadRequest)
+		suite.Expect.GET("/api/v1/search").WithQuery("type", "tag").WithQuery("q", "mises").
+			Expect().Status(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,241 @@
+package session
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gavv/httpexpect"
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/app/models/enum"
+	sessionSVC "github.com/mises-id/sns/app/services/session"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/tests/factories"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+}
+
+func (suite *SessionServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "users", "sessions"}
+}
+
+func (suite *SessionServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *SessionServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+}
+
+func (suite *SessionServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestSessionServer(t *testing.T) {
+	suite.Run(t, &SessionServerSuite{})
+}
+
+func (suite *SessionServerSuite) signIn(auth, deviceID string) *httpexpect.Object {
+	suite.MockMisesAuth(auth)
+	return suite.Expect.POST("/api/v1/signin").WithHeader("x-device-id", deviceID).WithJSON(map[string]interface{}{
+		"provider": "mises",
+		"user_authz": map[string]interface{}{
+			"auth": auth,
+		},
+	}).Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+}
+
+func (suite *SessionServerSuite) refresh(refreshToken string, status int) *httpexpect.Object {
+	return suite.Expect.POST("/api/v1/token/refresh").WithJSON(map[string]interface{}{
+		"refresh_token": refreshToken,
+	}).Expect().Status(status).JSON().Object()
+}
+
+func (suite *SessionServerSuite) TestRefreshToken() {
+	factories.InitUsers(&models.User{UID: 1001, Misesid: "123", Gender: enum.GenderMale})
+	signIn := suite.signIn("123:123", "device-a")
+	signIn.Value("token_type").Equal("Bearer")
+	signIn.Value("expires_in").Number().InRange(env.Envs.TokenDuration.Seconds()-5, env.Envs.TokenDuration.Seconds())
+	refreshToken := signIn.Value("refresh_token").String().NotEmpty().Raw()
+
+	var token string
+	suite.T().Run("refresh rotates the refresh token", func(t *testing.T) {
+		data := suite.refresh(refreshToken, http.StatusOK).Value("data").Object()
+		data.Value("refresh_token").NotEqual(refreshToken)
+		token = data.Value("token").String().Raw()
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("uid").Equal(1001)
+	})
+
+	suite.T().Run("refresh with an expired access token", func(t *testing.T) {
+		expiredToken, err := sessionSVC.KeySet().Sign(jwt.MapClaims{
+			"uid": 1001, "sid": primitive.NewObjectID().Hex(), "iss": env.Envs.JWTIssuer, "exp": time.Now().Add(-time.Minute).Unix(),
+		})
+		suite.Nil(err)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+expiredToken).
+			Expect().Status(http.StatusForbidden).JSON().Object().Value("code").Equal(403002)
+		resp := suite.Expect.POST("/api/v1/token/refresh").WithHeader("Authorization", "Bearer "+expiredToken).
+			WithJSON(map[string]interface{}{"refresh_token": "unknown"}).
+			Expect().Status(http.StatusUnauthorized).JSON().Object()
+		resp.Value("code").Equal(401000)
+	})
+
+	suite.T().Run("reused refresh token revokes the session", func(t *testing.T) {
+		suite.refresh(refreshToken, http.StatusUnauthorized).Value("code").Equal(401000)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnauthorized).JSON().Object().Value("code").Equal(401000)
+	})
+
+	suite.T().Run("refresh token is required", func(t *testing.T) {
+		suite.refresh("", http.StatusBadRequest).Value("code").Equal(400000)
+	})
+}
+
+func (suite *SessionServerSuite) TestSession() {
+	factories.InitUsers(&models.User{UID: 1001, Misesid: "123", Gender: enum.GenderMale})
+	tokenA := suite.signIn("123:123", "device-a").Value("token").String().Raw()
+	signInB := suite.signIn("123:123", "device-b")
+	tokenB := signInB.Value("token").String().Raw()
+
+	var sessionB string
+	suite.T().Run("list sessions", func(t *testing.T) {
+		sessions := suite.Expect.GET("/api/v1/session").WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Array()
+		sessions.Length().Equal(2)
+		for _, value := range sessions.Iter() {
+			session := value.Object()
+			current := session.Value("device_id").String().Raw() == "device-a"
+			session.Value("current").Equal(current)
+			if !current {
+				sessionB = session.Value("id").String().Raw()
+			}
+		}
+		suite.NotEmpty(sessionB)
+	})
+
+	suite.T().Run("revoke other session", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/session").WithQuery("id", sessionB).WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+tokenB).
+			Expect().Status(http.StatusUnauthorized)
+		suite.refresh(signInB.Value("refresh_token").String().Raw(), http.StatusUnauthorized)
+		suite.Expect.GET("/api/v1/session").WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Array().Length().Equal(1)
+	})
+
+	suite.T().Run("revoke session of other user", func(t *testing.T) {
+		factories.InitUsers(&models.User{UID: 1002, Misesid: "234", Gender: enum.GenderMale})
+		tokenC := suite.signIn("234:234", "device-c").Value("token").String().Raw()
+		sessions := suite.Expect.GET("/api/v1/session").WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Array()
+		sessionA := sessions.First().Object().Value("id").String().Raw()
+		suite.Expect.DELETE("/api/v1/session").WithQuery("id", sessionA).WithHeader("Authorization", "Bearer "+tokenC).
+			Expect().Status(http.StatusNotFound)
+	})
+
+	suite.T().Run("sign out", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/session").WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+tokenA).
+			Expect().Status(http.StatusUnauthorized)
+	})
+}
+
+func (suite *SessionServerSuite) TestAccessToken() {
+	factories.InitUsers(&models.User{UID: 1001, Misesid: "123", Gender: enum.GenderMale})
+	token := suite.signIn("123:123", "device-a").Value("token").String().Raw()
+	sid := suite.Expect.GET("/api/v1/session").WithHeader("Authorization", "Bearer "+token).
+		Expect().Status(http.StatusOK).JSON().Object().Value("data").Array().First().Object().Value("id").String().Raw()
+	exp := time.Now().Add(time.Minute).Unix()
+
+	suite.T().Run("publish jwks", func(t *testing.T) {
+		keys := suite.Expect.GET("/.well-known/jwks.json").Expect().Status(http.StatusOK).JSON().Object().Value("keys").Array()
+		keys.Length().Equal(1)
+		keys.First().Object().Value("kid").Equal(sessionSVC.KeySet().SigningKeyID())
+		keys.First().Object().ContainsKey("kty").NotContainsKey("d")
+	})
+
+	suite.T().Run("reject invalid claims", func(t *testing.T) {
+		invalidClaims := []jwt.MapClaims{
+			{"uid": 1001, "iss": env.Envs.JWTIssuer, "exp": exp},
+			{"uid": "1001", "sid": sid, "iss": env.Envs.JWTIssuer, "exp": exp},
+			{"uid": 1001, "sid": sid, "iss": "other", "exp": exp},
+			{"uid": 1001, "sid": sid, "iss": env.Envs.JWTIssuer},
+			{"uid": 1002, "sid": sid, "iss": env.Envs.JWTIssuer, "exp": exp},
+		}
+		for _, claims := range invalidClaims {
+			invalidToken, err := sessionSVC.KeySet().Sign(claims)
+			suite.Nil(err)
+			suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+invalidToken).
+				Expect().Status(http.StatusUnauthorized).JSON().Object().Value("code").Equal(401000)
+		}
+	})
+
+	suite.T().Run("reject hmac token", func(t *testing.T) {
+		at := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"uid": 1001, "sid": sid, "iss": env.Envs.JWTIssuer, "exp": exp})
+		at.Header["kid"] = sessionSVC.KeySet().SigningKeyID()
+		hmacToken, err := at.SignedString([]byte(env.Envs.JWTSecret))
+		suite.Nil(err)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+hmacToken).
+			Expect().Status(http.StatusUnauthorized)
+	})
+
+	legacyToken := func(exp int64) string {
+		at := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"uid": 1001, "misesid": "123", "username": "", "exp": exp})
+		token, err := at.SignedString([]byte(env.Envs.JWTSecret))
+		suite.Nil(err)
+		return token
+	}
+	cutoff := env.Envs.LegacyTokenCutoff
+	defer func() { env.Envs.LegacyTokenCutoff = cutoff }()
+
+	suite.T().Run("accept legacy token until it expires", func(t *testing.T) {
+		env.Envs.LegacyTokenCutoff = time.Now().Format(time.RFC3339)
+		token := legacyToken(exp)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Object().Value("uid").Equal(1001)
+		sessions := suite.Expect.GET("/api/v1/session").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object().Value("data").Array()
+		sessions.Length().Equal(1)
+		sessions.First().Object().Value("current").Equal(false)
+		suite.Expect.DELETE("/api/v1/session").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusBadRequest)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+legacyToken(time.Now().Add(-time.Minute).Unix())).
+			Expect().Status(http.StatusForbidden).JSON().Object().Value("code").Equal(403002)
+	})
+
+	suite.T().Run("reject legacy token expiring after the window", func(t *testing.T) {
+		env.Envs.LegacyTokenCutoff = time.Now().Format(time.RFC3339)
+		token := legacyToken(time.Now().Add(env.Envs.LegacyTokenDuration + time.Hour).Unix())
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnauthorized)
+	})
+
+	suite.T().Run("reject legacy token after the window", func(t *testing.T) {
+		token := legacyToken(exp)
+		env.Envs.LegacyTokenCutoff = time.Now().Add(-env.Envs.LegacyTokenDuration - time.Minute).Format(time.RFC3339)
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnauthorized)
+		env.Envs.LegacyTokenCutoff = ""
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnauthorized)
+	})
+
+	suite.T().Run("reject legacy token without the secret", func(t *testing.T) {
+		token := legacyToken(exp)
+		secret := env.Envs.JWTSecret
+		defer func() { env.Envs.JWTSecret = secret }()
+		env.Envs.LegacyTokenCutoff, env.Envs.JWTSecret = time.Now().Format(time.RFC3339), ""
+		suite.Expect.GET("/api/v1/user/me").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusUnauthorized)
+	})
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/mises-id/sns/app/jobs"
 	"github.com/mises-id/sns/app/models"
 	"github.com/mises-id/sns/app/models/enum"
+	linkpreviewSVC "github.com/mises-id/sns/app/services/linkpreview"
 	"github.com/mises-id/sns/lib/codes"
 	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/lib/queue"
 	"github.com/mises-id/sns/tests/factories"
 	"github.com/mises-id/sns/tests/rest"
 	"github.com/stretchr/testify/suite"
@@ -25,7 +30,7 @@ type StatusServerSuite struct {
 
 func (suite *StatusServerSuite) SetupSuite() {
 	suite.RestBaseTestSuite.SetupSuite()
-	suite.collections = []string{"counters", "users", "follows", "statuses", "likes"}
+	suite.collections = []string{"counters", "users", "follows", "statuses", "likes", "timelines", "notifications", "attachments", "linkpreviews", "tagbuckets", "status_revisions", "jobs"}
 }
 
 func (suite *StatusServerSuite) TearDownSuite() {
@@ -49,6 +54,22 @@ func (suite *StatusServerSuite) TearDownTest() {
 	suite.Clean(suite.collections...)
 }
 
+func newLinkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/articles/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Test</title>
+<meta property="og:title" content="Test link title">
+<meta property="og:description" content="Test link description">
+<meta property="og:image" content="/cover.jpg">
+</head></html>`))
+	})
+	mux.HandleFunc("/cover.jpg", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../../test.jpg")
+	})
+	return httptest.NewServer(mux)
+}
+
 func TestStatusServer(t *testing.T) {
 	suite.Run(t, &StatusServerSuite{})
 }
@@ -89,16 +110,52 @@ func (suite *StatusServerSuite) TestListStatus() {
 			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
 		resp.Value("data").Array()
 	})
+
+	suite.T().Run("user timeline after follow", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		resp := suite.Expect.GET("/api/v1/timeline/me").WithQuery("limit", 2).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("id").Equal(suite.statuses[7].ID.Hex())
+		resp.Value("pagination").Object().Value("last_id").Equal(suite.statuses[3].ID.Hex())
+
+		resp = suite.Expect.GET("/api/v1/timeline/me").WithQuery("last_id", suite.statuses[3].ID.Hex()).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().Last().Object().Value("id").Equal(suite.statuses[1].ID.Hex())
+		resp.Value("pagination").Object().Value("last_id").Equal("")
+	})
+
+	suite.T().Run("user timeline after unfollow", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		resp := suite.Expect.GET("/api/v1/timeline/me").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+	})
+
+	suite.T().Run("user timeline of follow made before the inbox", func(t *testing.T) {
+		factories.FollowFactory.MustCreateWithOption(map[string]interface{}{"FromUID": uint64(1001), "ToUID": uint64(1002)})
+		suite.Expect.GET("/api/v1/timeline/me").WithHeader("Authorization", "Bearer "+token).Expect().
+			Status(http.StatusOK).JSON().Object().Value("data").Array().Length().Equal(0)
+		for i := 0; i < 2; i++ {
+			count, err := models.BackfillFollowTimelines(context.Background())
+			suite.Nil(err)
+			suite.Equal(1, count)
+		}
+		resp := suite.Expect.GET("/api/v1/timeline/me").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(4)
+		resp.Value("data").Array().First().Object().Value("id").Equal(suite.statuses[7].ID.Hex())
+	})
 }
 
 func (suite *StatusServerSuite) TestCreateStatus() {
 	token := suite.MockLoginUser("1001:123")
-	linkMeta := &map[string]interface{}{
-		"title":         "Test link title",
-		"host":          "www.test.com",
-		"attachment_id": uint64(1),
-		"link":          "http://www.test.com/articles/test/1",
-	}
+	server := newLinkServer()
+	defer server.Close()
+	linkpreviewSVC.MockFetcher(server.Client())
 	suite.T().Run("create a text status", func(t *testing.T) {
 		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
 			"status_type": "text",
@@ -116,15 +173,71 @@ func (suite *StatusServerSuite) TestCreateStatus() {
 		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
 			"status_type": "link",
 			"content":     "post a link status",
-			"meta":        linkMeta,
+			"link_meta": map[string]interface{}{
+				"link":  server.URL + "/articles/1",
+				"title": "Spoofed title",
+			},
 		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
 		resp.Value("code").Equal(codes.SuccessCode)
+		id := resp.Value("data").Object().Value("id").String().Raw()
+		linkMeta := resp.Value("data").Object().Value("link_meta").Object()
+		linkMeta.Value("title").Equal("")
+		linkMeta.Value("host").Equal("127.0.0.1")
 		status := &models.Status{}
 		err := db.ODM(context.Background()).Last(status).Error
 		suite.Nil(err)
 		suite.Equal("post a link status", status.Content)
 		suite.Equal(enum.LinkStatus, status.StatusType)
 		suite.Equal(uint64(1001), status.UID)
+
+		_, err = jobs.NewWorker().Drain(context.Background())
+		suite.Nil(err)
+		linkMeta = suite.Expect.GET("/api/v1/status/" + id).Expect().Status(http.StatusOK).
+			JSON().Object().Value("data").Object().Value("link_meta").Object()
+		linkMeta.Value("title").Equal("Test link title")
+		linkMeta.Value("description").Equal("Test link description")
+		linkMeta.Value("host").Equal("127.0.0.1")
+		linkMeta.Value("attachment_url").String().Contains("preview.jpg")
+		err = db.ODM(context.Background()).Last(status).Error
+		suite.Nil(err)
+		suite.Equal("Test link title", status.LinkTitle)
+	})
+	suite.T().Run("create a link status from cache", func(t *testing.T) {
+		server.Close()
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "link",
+			"link_meta":   map[string]interface{}{"link": server.URL + "/articles/1"},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("link_meta").Object().Value("title").Equal("Test link title")
+	})
+	suite.T().Run("create a link status of an unreachable page", func(t *testing.T) {
+		link := server.URL + "/articles/2"
+		for i := 0; i < 2; i++ {
+			resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+				"status_type": "link",
+				"link_meta":   map[string]interface{}{"link": link},
+			}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+			linkMeta := resp.Value("data").Object().Value("link_meta").Object()
+			linkMeta.Value("title").Equal("")
+			linkMeta.Value("host").Equal("127.0.0.1")
+			_, err := jobs.NewWorker().Drain(context.Background())
+			suite.Nil(err)
+		}
+		preview, err := models.FindLinkPreview(context.Background(), link)
+		suite.Nil(err)
+		suite.True(preview.Failed)
+		count, err := db.DB().Collection("jobs").CountDocuments(context.Background(), bson.M{"name": jobs.StatusUnfurlLink})
+		suite.Nil(err)
+		suite.Equal(int64(2), count)
+	})
+	suite.T().Run("create a link status without link", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "link",
+			"link_meta":   map[string]interface{}{"link": "javascript:alert(1)"},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusBadRequest)
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "link",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusBadRequest)
 	})
 	suite.T().Run("forward a text status", func(t *testing.T) {
 		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
@@ -188,6 +301,47 @@ func (suite *StatusServerSuite) TestDeleteStatus() {
 		resp := suite.Expect.DELETE("/api/v1/status/"+suite.statuses[0].ID.Hex()).
 			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
 		resp.Value("code").Equal(codes.SuccessCode)
+
+		status := &models.Status{}
+		err := db.ODM(context.Background()).First(status, bson.M{"_id": suite.statuses[0].ID}).Error
+		suite.Nil(err)
+		suite.NotNil(status.DeletedAt)
+
+		resp = suite.Expect.GET("/api/v1/status/" + suite.statuses[0].ID.Hex()).
+			Expect().Status(http.StatusNotFound).JSON().Object()
+		resp.Value("code").Equal(codes.NotFoundCode)
+	})
+
+	suite.T().Run("deleted status as tombstone", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/status/" + suite.statuses[4].ID.Hex()).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("origin_status").Object().Value("is_deleted").Equal(true)
+		resp.Value("data").Object().Value("origin_status").Object().Value("content").Equal("")
+		resp.Value("data").Object().Value("parent_status").Object().Value("is_deleted").Equal(true)
+	})
+
+	suite.T().Run("delete forward decrease counter", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"parent_id":   suite.statuses[1].ID.Hex(),
+			"content":     "forward a text status",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		forwardID := resp.Value("data").Object().Value("id").String().Raw()
+		parent := &models.Status{}
+		err := db.ODM(context.Background()).First(parent, bson.M{"_id": suite.statuses[1].ID}).Error
+		suite.Nil(err)
+		suite.Equal(uint64(1), parent.ForwardsCount)
+
+		suite.Expect.DELETE("/api/v1/status/"+forwardID).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		parent = &models.Status{}
+		err = db.ODM(context.Background()).First(parent, bson.M{"_id": suite.statuses[1].ID}).Error
+		suite.Nil(err)
+		suite.Equal(uint64(0), parent.ForwardsCount)
+
+		resp = suite.Expect.DELETE("/api/v1/status/"+forwardID).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusNotFound).JSON().Object()
+		resp.Value("code").Equal(codes.NotFoundCode)
 	})
 }
 
@@ -236,3 +390,433 @@ func (suite *StatusServerSuite) TestUnlikeStatus() {
 		suite.NotNil(likes[0].DeletedAt)
 	})
 }
+
+func (suite *StatusServerSuite) TestListLike() {
+	token := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	likePath := fmt.Sprintf("/api/v1/status/%s/like", suite.statuses[1].ID.Hex())
+	suite.Expect.POST(likePath).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+	suite.Expect.POST(likePath).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+
+	suite.T().Run("list status likes", func(t *testing.T) {
+		resp := suite.Expect.GET(fmt.Sprintf("/api/v1/status/%s/likes", suite.statuses[1].ID.Hex())).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("user").Object().Value("uid").Equal(1002)
+		resp.Value("data").Array().First().Object().Value("user").Object().Value("is_followed").Equal(false)
+		resp.Value("data").Array().Last().Object().Value("user").Object().Value("uid").Equal(1001)
+	})
+
+	suite.T().Run("list user liked statuses", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/user/1001/likes").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("id").Equal(suite.statuses[1].ID.Hex())
+		resp.Value("data").Array().First().Object().Value("is_liked").Equal(true)
+	})
+
+	suite.T().Run("like a comment", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id": suite.statuses[1].ID.Hex(),
+			"content":   "a comment",
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON().Object()
+		commentID := resp.Value("data").Object().Value("id").String().Raw()
+		suite.Expect.POST(fmt.Sprintf("/api/v1/comment/%s/like", commentID)).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+
+		like := &models.Like{}
+		id, _ := primitive.ObjectIDFromHex(commentID)
+		err := db.ODM(context.TODO()).First(like, bson.M{"target_id": id}).Error
+		suite.Nil(err)
+		suite.Equal(enum.LikeComment, like.TargetType)
+
+		resp = suite.Expect.GET("/api/v1/comment").WithQuery("status_id", suite.statuses[1].ID.Hex()).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().First().Object().Value("is_liked").Equal(true)
+		resp.Value("data").Array().First().Object().Value("likes_count").Equal(1)
+
+		suite.Expect.POST(fmt.Sprintf("/api/v1/comment/%s/like", suite.statuses[1].ID.Hex())).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusNotFound)
+	})
+
+	suite.T().Run("comment like stored as status like", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id": suite.statuses[1].ID.Hex(),
+			"content":   "an old comment",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		id, _ := primitive.ObjectIDFromHex(resp.Value("data").Object().Value("id").String().Raw())
+		ctx := context.TODO()
+		_, err := models.CreateLike(ctx, 1002, id, enum.LikeStatus)
+		suite.Nil(err)
+		suite.Nil((&models.Status{ID: id}).IncStatusCounter(ctx, "likes_count"))
+
+		suite.Expect.POST(fmt.Sprintf("/api/v1/comment/%s/like", id.Hex())).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		comment, err := models.FindStatus(ctx, id)
+		suite.Nil(err)
+		suite.Equal(uint64(1), comment.LikesCount)
+
+		count, err := models.RetypeCommentLikes(ctx)
+		suite.Nil(err)
+		suite.Equal(1, count)
+		like, err := models.FindLike(ctx, 1002, id, enum.LikeComment)
+		suite.Nil(err)
+		suite.Equal(enum.LikeComment, like.TargetType)
+		suite.Expect.DELETE(fmt.Sprintf("/api/v1/comment/%s/like", id.Hex())).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+	})
+}
+
+func (suite *StatusServerSuite) TestCreateMediaStatus() {
+	token := suite.MockLoginUser("1001:123")
+	factories.InitAttachments(&models.Attachment{
+		ID: 1, UID: 1001, Filename: "1.jpg", FileType: enum.ImageFile, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, &models.Attachment{
+		ID: 2, UID: 1001, Filename: "2.jpg", FileType: enum.ImageFile, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, &models.Attachment{
+		ID: 3, UID: 1002, Filename: "3.jpg", FileType: enum.ImageFile, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, &models.Attachment{
+		ID: 4, UID: 1001, Filename: "4.mp4", FileType: enum.VideoFile, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	})
+	suite.T().Run("create an image status", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "image",
+			"content":     "post an image status",
+			"image_meta": map[string]interface{}{
+				"images": []map[string]interface{}{
+					{"attachment_id": 2, "width": 800, "height": 600, "alt": "second"},
+					{"attachment_id": 1, "width": 600, "height": 800, "alt": "first"},
+				},
+			},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		images := resp.Value("data").Object().Value("image_meta").Object().Value("images").Array()
+		images.Length().Equal(2)
+		images.First().Object().Value("attachment_id").Equal(2)
+		images.First().Object().Value("alt").Equal("second")
+		images.First().Object().Value("width").Equal(800)
+		images.First().Object().Value("attachment_url").String().Contains("2.jpg")
+		images.Last().Object().Value("attachment_id").Equal(1)
+	})
+	suite.T().Run("create a video status", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "video",
+			"video_meta": map[string]interface{}{
+				"videos": []map[string]interface{}{{"attachment_id": 4}},
+			},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("video_meta").Object().Value("videos").Array().Length().Equal(1)
+	})
+	suite.T().Run("create an image status with invalid attachments", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "image",
+			"image_meta": map[string]interface{}{
+				"images": []map[string]interface{}{{"attachment_id": 4}},
+			},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusBadRequest)
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "image",
+			"image_meta": map[string]interface{}{
+				"images": []map[string]interface{}{{"attachment_id": 3}},
+			},
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusForbidden)
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "image",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusBadRequest)
+	})
+}
+
+func (suite *StatusServerSuite) TestCommentReplies() {
+	token := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	statusID := suite.statuses[1].ID.Hex()
+	var commentID, replyID, reply2ID string
+	suite.T().Run("reply to comments", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id": statusID,
+			"content":   "a comment",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		commentID = resp.Value("data").Object().Value("id").String().Raw()
+
+		resp = suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id":  statusID,
+			"comment_id": commentID,
+			"content":    "a reply",
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON().Object()
+		replyID = resp.Value("data").Object().Value("id").String().Raw()
+		resp.Value("data").Object().Value("reply_to_user").Object().Value("uid").Equal(1001)
+
+		resp = suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"comment_id": replyID,
+			"content":    "a reply of reply",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		reply2ID = resp.Value("data").Object().Value("id").String().Raw()
+		resp.Value("data").Object().Value("reply_to_user").Object().Value("uid").Equal(1002)
+
+		reply := &models.Status{}
+		id, _ := primitive.ObjectIDFromHex(reply2ID)
+		err := db.ODM(context.TODO()).First(reply, bson.M{"_id": id}).Error
+		suite.Nil(err)
+		suite.Equal(commentID, reply.ParentID.Hex())
+		suite.Equal(statusID, reply.RootID.Hex())
+		suite.Equal(uint64(1002), reply.ReplyToUID)
+
+		suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id":  suite.statuses[0].ID.Hex(),
+			"comment_id": commentID,
+			"content":    "a reply of another status",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusBadRequest)
+	})
+	suite.T().Run("list comments with replies", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/comment").WithQuery("status_id", statusID).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		comment := resp.Value("data").Array().First().Object()
+		comment.Value("replies_count").Equal(2)
+		comment.Value("replies").Array().Length().Equal(2)
+		comment.Value("replies").Array().First().Object().Value("id").Equal(reply2ID)
+
+		resp = suite.Expect.GET(fmt.Sprintf("/api/v1/comment/%s/replies", commentID)).WithQuery("limit", 1).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("pagination").Object().Value("last_id").Equal(replyID)
+
+		suite.Expect.GET(fmt.Sprintf("/api/v1/comment/%s/replies", replyID)).Expect().Status(http.StatusNotFound)
+
+		resp = suite.Expect.GET("/api/v1/status/" + statusID).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("comments_count").Equal(3)
+	})
+	suite.T().Run("delete comments of a thread", func(t *testing.T) {
+		suite.Expect.DELETE("/api/v1/status/"+commentID).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		resp := suite.Expect.GET("/api/v1/comment").WithQuery("status_id", statusID).
+			Expect().Status(http.StatusOK).JSON().Object()
+		comment := resp.Value("data").Array().First().Object()
+		comment.Value("is_deleted").Equal(true)
+		comment.Value("replies").Array().Length().Equal(2)
+
+		suite.Expect.DELETE("/api/v1/status/"+reply2ID).
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+		resp = suite.Expect.GET("/api/v1/status/" + statusID).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("comments_count").Equal(1)
+		resp = suite.Expect.GET(fmt.Sprintf("/api/v1/comment/%s/replies", commentID)).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+	})
+}
+
+func (suite *StatusServerSuite) TestMentions() {
+	factories.InitUsers(&models.User{
+		UID:      uint64(1003),
+		Misesid:  "1003",
+		Username: "carol",
+	}, &models.User{
+		UID:      uint64(1004),
+		Misesid:  "1004",
+		Username: "Carol",
+	})
+	token := suite.MockLoginUser("1001:123")
+	token3 := suite.MockLoginUser("1003:123")
+	suite.T().Run("mention users in a status", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"content":     "hi @carol, @nobody and carol@mises.site",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		mentions := resp.Value("data").Object().Value("mentions").Array()
+		mentions.Length().Equal(1)
+		mentions.First().Object().Value("uid").Equal(1003)
+		mentions.First().Object().Value("username").Equal("carol")
+		mentions.First().Object().Value("start").Equal(3)
+		mentions.First().Object().Value("end").Equal(9)
+
+		suite.Expect.POST("/api/v1/comment").WithJSON(map[string]interface{}{
+			"status_id": suite.statuses[1].ID.Hex(),
+			"content":   "@carol look at this",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+	})
+	suite.T().Run("list my mentions", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/user/me/mentions").
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("from_type").Equal("comment")
+
+		resp = suite.Expect.GET("/api/v1/notification").
+			WithHeader("Authorization", "Bearer "+token3).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp.Value("data").Array().First().Object().Value("notify_type").Equal("mention")
+
+		resp = suite.Expect.GET("/api/v1/user/me/mentions").
+			WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+	})
+	suite.T().Run("match usernames case-sensitively", func(t *testing.T) {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"content":     "@Carol and @CAROL",
+		}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+		mentions := resp.Value("data").Object().Value("mentions").Array()
+		mentions.Length().Equal(1)
+		mentions.First().Object().Value("uid").Equal(1004)
+		mentions.First().Object().Value("username").Equal("Carol")
+	})
+}
+
+func (suite *StatusServerSuite) TestVisibility() {
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	ids := make(map[string]string)
+	for _, visibility := range []string{"followers", "friends", "only_me"} {
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"content":     visibility + " status",
+			"visibility":  visibility,
+		}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("visibility").Equal(visibility)
+		ids[visibility] = resp.Value("data").Object().Value("id").String().Raw()
+	}
+	suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+		"status_type": "text",
+		"visibility":  "everyone",
+	}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusBadRequest)
+
+	suite.T().Run("get status by visibility", func(t *testing.T) {
+		for _, id := range ids {
+			suite.Expect.GET("/api/v1/status/" + id).Expect().Status(http.StatusNotFound)
+			suite.Expect.GET("/api/v1/status/"+id).
+				WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		}
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1001}).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/status/"+ids["followers"]).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/status/"+ids["friends"]).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusNotFound)
+		suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/status/"+ids["friends"]).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK)
+		suite.Expect.GET("/api/v1/status/"+ids["only_me"]).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusNotFound)
+	})
+
+	suite.T().Run("list statuses by visibility", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/status/recommend").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().NotContains(ids["followers"], ids["friends"], ids["only_me"])
+		resp = suite.Expect.GET("/api/v1/user/1001/status").Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().NotContains(ids["followers"], ids["friends"], ids["only_me"])
+		resp = suite.Expect.GET("/api/v1/user/1001/status").
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().Contains(ids["followers"], ids["friends"])
+		resp.Path("$.data[*].id").Array().NotContains(ids["only_me"])
+		resp = suite.Expect.GET("/api/v1/user/1001/status").
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().Contains(ids["followers"], ids["friends"], ids["only_me"])
+	})
+
+	suite.T().Run("timeline by visibility", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/timeline/me").WithHeader("Authorization", "Bearer "+token2).
+			Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().Contains(ids["followers"], ids["friends"])
+		resp.Path("$.data[*].id").Array().NotContains(ids["only_me"])
+		suite.Expect.DELETE("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+			WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+		// the status for friends is filtered before the page is cut
+		resp = suite.Expect.GET("/api/v1/timeline/me").WithQuery("limit", 1).
+			WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON()
+		resp.Path("$.data[*].id").Array().Equal([]string{ids["followers"]})
+	})
+
+	suite.T().Run("forward does not widen visibility", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"parent_id":   ids["followers"],
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+		resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+			"status_type": "text",
+			"parent_id":   ids["friends"],
+			"visibility":  "public",
+		}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("visibility").Equal("friends")
+	})
+}
+
+func (suite *StatusServerSuite) TestEditStatus() {
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+		"status_type": "text",
+		"content":     "hello #old",
+	}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+	resp.Value("data").Object().Value("edited_at").Null()
+	id := resp.Value("data").Object().Value("id").String().Raw()
+
+	suite.T().Run("edit status", func(t *testing.T) {
+		resp := suite.Expect.PATCH("/api/v1/status/"+id).WithJSON(map[string]interface{}{
+			"content": "hello #new",
+		}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Object().Value("content").Equal("hello #new")
+		resp.Value("data").Object().Value("edited_at").NotNull()
+
+		resp = suite.Expect.GET("/api/v1/tag/new/status").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp = suite.Expect.GET("/api/v1/tag/old/status").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+	})
+
+	suite.T().Run("list revisions", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/status/" + id + "/revisions").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(1)
+		resp.Value("data").Array().First().Object().Value("content").Equal("hello #old")
+	})
+
+	suite.T().Run("conflicting edit keeps no revision", func(t *testing.T) {
+		statusID, _ := primitive.ObjectIDFromHex(id)
+		ctx := context.Background()
+		status, err := models.FindStatus(ctx, statusID)
+		suite.Nil(err)
+		stale, err := models.FindStatus(ctx, statusID)
+		suite.Nil(err)
+		suite.Nil(models.UpdateStatus(ctx, status, &models.UpdateStatusParams{Content: "hello #new again"}))
+		err = models.UpdateStatus(ctx, stale, &models.UpdateStatusParams{Content: "stale"})
+		suite.True(codes.ErrConflict.Equal(err))
+		count, err := db.DB().Collection("status_revisions").CountDocuments(ctx, bson.M{"status_id": statusID})
+		suite.Nil(err)
+		suite.Equal(int64(2), count)
+	})
+
+	suite.T().Run("edit status of other user", func(t *testing.T) {
+		suite.Expect.PATCH("/api/v1/status/"+id).WithJSON(map[string]interface{}{
+			"content": "hacked",
+		}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusForbidden)
+	})
+
+	suite.T().Run("edit status after the edit window", func(t *testing.T) {
+		suite.Expect.PATCH("/api/v1/status/"+suite.statuses[0].ID.Hex()).WithJSON(map[string]interface{}{
+			"content": "too late",
+		}).WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusForbidden)
+	})
+}
+
+func (suite *StatusServerSuite) TestFanOutJob() {
+	token1 := suite.MockLoginUser("1001:123")
+	token2 := suite.MockLoginUser("1002:123")
+	suite.Expect.POST("/api/v1/user/follow").WithJSON(map[string]interface{}{"to_user_id": 1002}).
+		WithHeader("Authorization", "Bearer "+token1).Expect().Status(http.StatusOK)
+	resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+		"status_type": "text",
+		"content":     "fan out later",
+	}).WithHeader("Authorization", "Bearer "+token2).Expect().Status(http.StatusOK).JSON().Object()
+	statusID := resp.Value("data").Object().Value("id").String().Raw()
+
+	suite.Expect.GET("/api/v1/timeline/me").WithHeader("Authorization", "Bearer "+token1).Expect().
+		Status(http.StatusOK).JSON().Path("$.data[*].id").Array().NotContains(statusID)
+	count, err := jobs.NewWorker().Drain(context.Background())
+	suite.Nil(err)
+	suite.Equal(1, count)
+	suite.Expect.GET("/api/v1/timeline/me").WithHeader("Authorization", "Bearer "+token1).Expect().
+		Status(http.StatusOK).JSON().Path("$.data[*].id").Array().Contains(statusID)
+	job := &queue.Job{}
+	suite.Nil(db.DB().Collection("jobs").FindOne(context.Background(), bson.M{}).Decode(job))
+	suite.Equal(queue.JobDone, job.Status)
+	suite.Equal(1, job.Attempts)
+}
@@ -0,0 +1,82 @@
+package tag
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mises-id/sns/app/models"
+	"github.com/mises-id/sns/tests/factories"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+)
+
+type TagServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+}
+
+func (suite *TagServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "users", "follows", "statuses", "likes", "timelines", "notifications", "tagbuckets"}
+}
+
+func (suite *TagServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *TagServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+	factories.InitUsers(&models.User{
+		UID:     uint64(1001),
+		Misesid: "1001",
+	})
+}
+
+func (suite *TagServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+}
+
+func TestTagServer(t *testing.T) {
+	suite.Run(t, &TagServerSuite{})
+}
+
+func (suite *TagServerSuite) createStatus(token, content string) string {
+	resp := suite.Expect.POST("/api/v1/status").WithJSON(map[string]interface{}{
+		"status_type": "text",
+		"content":     content,
+	}).WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK).JSON().Object()
+	return resp.Value("data").Object().Value("id").String().Raw()
+}
+
+func (suite *TagServerSuite) TestTag() {
+	token := suite.MockLoginUser("1001:123")
+	suite.createStatus(token, "#Mises is live, read https://mises.site/#news")
+	suite.createStatus(token, "#mises #区块链")
+	deletedID := suite.createStatus(token, "#区块链 to be deleted")
+	suite.createStatus(token, "#web3 #区块链")
+	suite.Expect.DELETE("/api/v1/status/"+deletedID).
+		WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusOK)
+
+	suite.T().Run("list tag statuses", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/tag/MISES/status").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp = suite.Expect.GET("/api/v1/tag/区块链/status").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(2)
+		resp = suite.Expect.GET("/api/v1/tag/news/status").Expect().Status(http.StatusOK).JSON().Object()
+		resp.Value("data").Array().Length().Equal(0)
+		suite.Expect.GET("/api/v1/tag/123/status").Expect().Status(http.StatusBadRequest)
+	})
+	suite.T().Run("list trending tags", func(t *testing.T) {
+		resp := suite.Expect.GET("/api/v1/tag/trending").WithQuery("window", "1h").
+			Expect().Status(http.StatusOK).JSON().Object()
+		tags := resp.Value("data").Array()
+		tags.Length().Equal(3)
+		tags.Element(0).Object().Value("tag").Equal("mises")
+		tags.Element(0).Object().Value("count").Equal(2)
+		tags.Element(1).Object().Value("tag").Equal("区块链")
+		tags.Element(1).Object().Value("count").Equal(2)
+		tags.Element(2).Object().Value("tag").Equal("web3")
+		suite.Expect.GET("/api/v1/tag/trending").WithQuery("window", "1y").Expect().Status(http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,221 @@
+package upload
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mises-id/sns/app/models"
+	uploadSVC "github.com/mises-id/sns/app/services/upload"
+	"github.com/mises-id/sns/config/env"
+	"github.com/mises-id/sns/lib/db"
+	"github.com/mises-id/sns/tests/rest"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UploadServerSuite struct {
+	rest.RestBaseTestSuite
+	collections []string
+	files       []string
+}
+
+func (suite *UploadServerSuite) SetupSuite() {
+	suite.RestBaseTestSuite.SetupSuite()
+	suite.collections = []string{"counters", "attachments", "upload_sessions", "users"}
+	suite.files = []string{
+		path.Join(env.Envs.RootPath, "upload", "attachment"),
+		path.Join(env.Envs.RootPath, "upload", "tmp"),
+	}
+}
+
+func (suite *UploadServerSuite) TearDownSuite() {
+	suite.RestBaseTestSuite.TearDownSuite()
+}
+
+func (suite *UploadServerSuite) SetupTest() {
+	suite.Clean(suite.collections...)
+	suite.Acquire(suite.collections...)
+}
+
+func (suite *UploadServerSuite) TearDownTest() {
+	suite.Clean(suite.collections...)
+	for _, file := range suite.files {
+		_ = os.RemoveAll(file)
+	}
+}
+
+func TestUploadServer(t *testing.T) {
+	suite.Run(t, &UploadServerSuite{})
+}
+
+func uploadMetadata(filename, fileType string) string {
+	return "filename " + base64.StdEncoding.EncodeToString([]byte(filename)) +
+		",filetype " + base64.StdEncoding.EncodeToString([]byte(fileType))
+}
+
+func (suite *UploadServerSuite) createSession(token string, length int) string {
+	resp := suite.Expect.POST("/api/v1/uploads").
+		WithHeader("Authorization", "Bearer "+token).
+		WithHeader("Tus-Resumable", "1.0.0").
+		WithHeader("Upload-Length", strconv.Itoa(length)).
+		WithHeader("Upload-Metadata", uploadMetadata("test.mp4", "video/mp4")).
+		Expect().Status(http.StatusCreated)
+	resp.Header("Tus-Resumable").Equal("1.0.0")
+	resp.Header("Upload-Offset").Equal("0")
+	return resp.Header("Location").Raw()
+}
+
+func (suite *UploadServerSuite) TestResumableUpload() {
+	video, err := ioutil.ReadFile("../../test.mp4")
+	suite.Nil(err)
+	token := suite.MockLoginUser("1001:123")
+	location := suite.createSession(token, len(video))
+	half := len(video) / 2
+
+	suite.T().Run("require tus version", func(t *testing.T) {
+		suite.Expect.HEAD(location).WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusPreconditionFailed).Header("Tus-Version").Equal("1.0.0")
+	})
+
+	suite.T().Run("upload the first chunk", func(t *testing.T) {
+		suite.Expect.PATCH(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			WithHeader("Upload-Offset", "0").
+			WithHeader("Content-Type", "application/offset+octet-stream").
+			WithBytes(video[:half]).
+			Expect().Status(http.StatusNoContent).Header("Upload-Offset").Equal(strconv.Itoa(half))
+	})
+
+	suite.T().Run("query the offset", func(t *testing.T) {
+		resp := suite.Expect.HEAD(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			Expect().Status(http.StatusOK)
+		resp.Header("Upload-Offset").Equal(strconv.Itoa(half))
+		resp.Header("Upload-Length").Equal(strconv.Itoa(len(video)))
+	})
+
+	suite.T().Run("reject a mismatched offset", func(t *testing.T) {
+		suite.Expect.PATCH(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			WithHeader("Upload-Offset", "0").
+			WithHeader("Content-Type", "application/offset+octet-stream").
+			WithBytes(video[:half]).
+			Expect().Status(http.StatusConflict)
+	})
+
+	suite.T().Run("complete an incomplete upload", func(t *testing.T) {
+		suite.Expect.POST(location+"/complete").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusConflict)
+	})
+
+	suite.T().Run("upload the last chunk", func(t *testing.T) {
+		suite.Expect.PATCH(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			WithHeader("Upload-Offset", strconv.Itoa(half)).
+			WithHeader("Content-Type", "application/offset+octet-stream").
+			WithBytes(video[half:]).
+			Expect().Status(http.StatusNoContent).Header("Upload-Offset").Equal(strconv.Itoa(len(video)))
+	})
+
+	suite.T().Run("get the attachment", func(t *testing.T) {
+		resp := suite.Expect.POST(location+"/complete").WithHeader("Authorization", "Bearer "+token).
+			Expect().Status(http.StatusOK).JSON().Object()
+		data := resp.Value("data").Object()
+		data.Value("filename").Equal("test.mp4")
+		data.Value("file_type").Equal("video")
+		data.Value("size").Equal(len(video))
+		session := &models.UploadSession{}
+		id, _ := primitive.ObjectIDFromHex(path.Base(location))
+		suite.Nil(db.ODM(context.Background()).First(session, bson.M{"_id": id}).Error)
+		suite.Equal(0, len(session.Chunks))
+		files, _ := ioutil.ReadDir(path.Join(env.Envs.RootPath, "upload", "tmp", "uploads", id.Hex()))
+		suite.Equal(0, len(files))
+	})
+
+	suite.T().Run("session of another user", func(t *testing.T) {
+		token2 := suite.MockLoginUser("1002:123")
+		suite.Expect.HEAD(location).
+			WithHeader("Authorization", "Bearer "+token2).
+			WithHeader("Tus-Resumable", "1.0.0").
+			Expect().Status(http.StatusNotFound)
+	})
+}
+
+func (suite *UploadServerSuite) TestRejectUpload() {
+	token := suite.MockLoginUser("1001:123")
+
+	suite.T().Run("reject an oversized upload", func(t *testing.T) {
+		suite.Expect.POST("/api/v1/uploads").
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			WithHeader("Upload-Length", strconv.FormatInt(env.Envs.VideoMaxSize+1, 10)).
+			WithHeader("Upload-Metadata", uploadMetadata("test.mp4", "video/mp4")).
+			Expect().Status(http.StatusRequestEntityTooLarge)
+	})
+
+	suite.T().Run("delete the session of an invalid file", func(t *testing.T) {
+		image, err := ioutil.ReadFile("../../test.jpg")
+		suite.Nil(err)
+		location := suite.createSession(token, len(image))
+		suite.Expect.PATCH(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			WithHeader("Upload-Offset", "0").
+			WithHeader("Content-Type", "application/offset+octet-stream").
+			WithBytes(image).
+			Expect().Status(http.StatusUnsupportedMediaType)
+		suite.Expect.HEAD(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			Expect().Status(http.StatusNotFound)
+	})
+
+	suite.T().Run("terminate the session", func(t *testing.T) {
+		location := suite.createSession(token, 10)
+		suite.Expect.DELETE(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			Expect().Status(http.StatusNoContent)
+		suite.Expect.HEAD(location).
+			WithHeader("Authorization", "Bearer "+token).
+			WithHeader("Tus-Resumable", "1.0.0").
+			Expect().Status(http.StatusNotFound)
+	})
+}
+
+func (suite *UploadServerSuite) TestCleanExpiredSessions() {
+	token := suite.MockLoginUser("1001:123")
+	location := suite.createSession(token, 10)
+	suite.Expect.PATCH(location).
+		WithHeader("Authorization", "Bearer "+token).
+		WithHeader("Tus-Resumable", "1.0.0").
+		WithHeader("Upload-Offset", "0").
+		WithHeader("Content-Type", "application/offset+octet-stream").
+		WithBytes([]byte("01234")).
+		Expect().Status(http.StatusNoContent)
+	id, _ := primitive.ObjectIDFromHex(path.Base(location))
+	_, err := db.DB().Collection("upload_sessions").UpdateOne(context.Background(), bson.M{"_id": id},
+		bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Minute)}})
+	suite.Nil(err)
+
+	count, err := uploadSVC.CleanExpiredSessions(context.Background())
+	suite.Nil(err)
+	suite.Equal(1, count)
+	files, _ := ioutil.ReadDir(path.Join(env.Envs.RootPath, "upload", "tmp", "uploads", id.Hex()))
+	suite.Equal(0, len(files))
+	var sessions int64
+	suite.Nil(db.ODM(context.Background()).Model(&models.UploadSession{}).Where(bson.M{}).Count(&sessions).Error)
+	suite.Equal(int64(0), sessions)
+}